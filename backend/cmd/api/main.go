@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
 	"time"
@@ -10,7 +11,12 @@ import (
 	"jnv/backend/internal/config"
 	"jnv/backend/internal/db"
 	"jnv/backend/internal/http"
+	"jnv/backend/internal/http/handlers"
+	"jnv/backend/internal/idcard"
+	"jnv/backend/internal/jobs"
 	"jnv/backend/internal/notify"
+	"jnv/backend/internal/reminders"
+	"jnv/backend/internal/storage"
 	"jnv/backend/internal/store"
 )
 
@@ -56,12 +62,76 @@ func main() {
 		log.Fatalf("unsupported AUTH_MODE: %s", cfg.AuthMode)
 	}
 
+	var files storage.Bucket
+	switch cfg.StorageBackend {
+	case "local":
+		signingKey := requiredKey(cfg, "STORAGE_SIGNING_KEY", cfg.StorageSigningKey, "signed file URLs will not survive a restart")
+		localFiles, storageErr := storage.NewLocal(cfg.StorageLocalDir, cfg.PublicBaseURL, signingKey)
+		if storageErr != nil {
+			log.Fatalf("failed to initialize local storage: %v", storageErr)
+		}
+		files = localFiles
+	case "s3":
+		s3Files, storageErr := storage.NewS3(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		})
+		if storageErr != nil {
+			log.Fatalf("failed to initialize s3 storage: %v", storageErr)
+		}
+		files = s3Files
+	case "none":
+		log.Printf("warning: STORAGE_BACKEND=none; uploads are not archived and are imported inline")
+	default:
+		log.Fatalf("unsupported STORAGE_BACKEND: %s", cfg.StorageBackend)
+	}
+
+	cardKey := requiredKey(cfg, "ID_CARD_SIGNING_KEY", cfg.IDCardSigningKey, "printed ID cards will not verify after a restart")
+
+	if cfg.ParentLinkEscalateDays > 0 && cfg.ParentLinkEscalateDays <= cfg.ParentLinkRemindDays {
+		log.Printf("warning: PARENT_LINK_ESCALATE_DAYS should be later than PARENT_LINK_REMIND_DAYS")
+	}
+	go reminders.ParentLinkSLA{
+		Store:         store,
+		Notifier:      notifier,
+		RemindAfter:   time.Duration(cfg.ParentLinkRemindDays) * 24 * time.Hour,
+		EscalateAfter: time.Duration(cfg.ParentLinkEscalateDays) * 24 * time.Hour,
+		Interval:      time.Hour,
+	}.Run(context.Background())
+
+	if cfg.InfirmarySummaryHour >= 0 {
+		go reminders.InfirmarySummary{
+			Store:    store,
+			Notifier: notifier,
+			Hour:     cfg.InfirmarySummaryHour,
+		}.Run(context.Background())
+	}
+
+	// Without storage no job is queued here, and a worker could not read
+	// the files of jobs queued by other instances. Without workers, uploads
+	// are imported inline rather than queued for nobody to claim.
+	queueImports := cfg.ImportWorkers > 0 && files != nil
+	if queueImports {
+		go jobs.Runner{
+			Store:   store,
+			Funcs:   handlers.ImportJobs(store, notifier, files),
+			Workers: cfg.ImportWorkers,
+		}.Run(context.Background())
+	}
+
 	server := &http.Server{
 		Addr: cfg.HTTPAddr,
 		Handler: httpapi.API{
 			Store:         store,
 			AuthProvider:  authProvider,
 			Notifier:      notifier,
+			Files:         files,
+			QueueImports:  queueImports,
+			CardSigner:    idcard.NewSigner(cardKey),
+			PublicBaseURL: cfg.PublicBaseURL,
 			CORSAllowList: cfg.CORSAllowedOrigins,
 		}.Router(),
 		ReadTimeout:  5 * time.Second,
@@ -74,3 +144,21 @@ func main() {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+// requiredKey returns a signing key from the environment. Keys must be set
+// outside dev mode, since anything signed with a random key stops verifying
+// once the process restarts; in dev mode a random key is used with a warning.
+func requiredKey(cfg config.Config, name, value, consequence string) []byte {
+	if value != "" {
+		return []byte(value)
+	}
+	if cfg.AuthMode != "dev" {
+		log.Fatalf("%s is required unless AUTH_MODE=dev", name)
+	}
+	log.Printf("warning: %s not set; %s", name, consequence)
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("failed to generate %s: %v", name, err)
+	}
+	return key
+}
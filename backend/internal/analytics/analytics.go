@@ -0,0 +1,311 @@
+// Package analytics computes class standings, averages and trends from the
+// scores of a class over a set of exams.
+package analytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/models"
+)
+
+type marks struct {
+	score float32
+	max   float32
+}
+
+func (m marks) percent() float64 {
+	if m.max <= 0 {
+		return 0
+	}
+	return float64(m.score) / float64(m.max) * 100
+}
+
+type standing struct {
+	studentID  string
+	percentage float64
+	rank       int
+}
+
+// Set holds a class's scores over some exams. Percentages are marks summed
+// across every exam and subject in the set, so a paper with no score lowers
+// the maximum rather than counting as zero. A paper marked absent does
+// count as zero in the student's totals; medical leave and exemptions are
+// left out like a missing score. Subject figures only take papers with
+// marks, so absentees do not drag down a subject's average or lowest mark.
+type Set struct {
+	exams     []models.Exam
+	overall   map[string]*marks            // by student
+	byExam    map[string]map[string]*marks // by student, then exam
+	bySubject map[string]map[string]*marks // by subject key, then student
+	subjectOf map[string]string            // subject key to display name
+	perExam   map[string]map[string]map[string]*marks
+	standings []standing
+}
+
+// New indexes scores of the given exams; scores of other exams are ignored.
+func New(exams []models.Exam, scores []models.Score) *Set {
+	set := &Set{
+		exams:     append([]models.Exam(nil), exams...),
+		overall:   map[string]*marks{},
+		byExam:    map[string]map[string]*marks{},
+		bySubject: map[string]map[string]*marks{},
+		subjectOf: map[string]string{},
+		perExam:   map[string]map[string]map[string]*marks{},
+	}
+	sort.SliceStable(set.exams, func(i, j int) bool { return set.exams[i].Date.Before(set.exams[j].Date) })
+	inSet := make(map[string]bool, len(exams))
+	for _, exam := range exams {
+		inSet[exam.ID] = true
+	}
+	for _, score := range scores {
+		if !inSet[score.ExamID] {
+			continue
+		}
+		key := strings.ToLower(score.Subject)
+		if _, ok := set.subjectOf[key]; !ok {
+			set.subjectOf[key] = score.Subject
+		}
+		if !grading.CountsInTotal(score.Status) {
+			continue
+		}
+		add(set.overall, score.StudentID, score)
+		add(nested(set.byExam, score.StudentID), score.ExamID, score)
+		if !grading.HasMarks(score.Status) {
+			continue
+		}
+		add(nested(set.bySubject, key), score.StudentID, score)
+		student := set.perExam[score.StudentID]
+		if student == nil {
+			student = map[string]map[string]*marks{}
+			set.perExam[score.StudentID] = student
+		}
+		add(nested(student, key), score.ExamID, score)
+	}
+	set.standings = rank(set.overall)
+	return set
+}
+
+// Exams returns the number of exams in the set.
+func (set *Set) Exams() int {
+	return len(set.exams)
+}
+
+// Student returns a student's percentage, rank, percentile and trends.
+func (set *Set) Student(studentID string, bands []models.GradeBand) models.StudentAnalytics {
+	out := models.StudentAnalytics{
+		StudentID: studentID,
+		Exams:     len(set.exams),
+		ClassSize: len(set.standings),
+		Trend:     []models.TrendPoint{},
+		Subjects:  []models.SubjectTrend{},
+	}
+	total, ok := set.overall[studentID]
+	if !ok || total.max <= 0 {
+		return out
+	}
+	out.Percentage = ptr(round(total.percent()))
+	out.Grade = grading.Grade(bands, total.score, total.max)
+	below, equal := 0, 0
+	for _, st := range set.standings {
+		if st.studentID == studentID {
+			out.Rank = st.rank
+		}
+		switch p := round(st.percentage); {
+		case p < *out.Percentage:
+			below++
+		case p == *out.Percentage:
+			equal++
+		}
+	}
+	out.Percentile = ptr(round((float64(below) + 0.5*float64(equal)) / float64(len(set.standings)) * 100))
+
+	out.Trend = set.trend(set.byExam[studentID])
+	out.Change = change(out.Trend)
+	keys := make([]string, 0, len(set.perExam[studentID]))
+	for key := range set.perExam[studentID] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		points := set.trend(set.perExam[studentID][key])
+		out.Subjects = append(out.Subjects, models.SubjectTrend{
+			Subject:    set.subjectOf[key],
+			Percentage: round(set.bySubject[key][studentID].percent()),
+			Points:     points,
+			Change:     change(points),
+		})
+	}
+	return out
+}
+
+// SubjectRanks returns each student's rank in one subject across the set,
+// ranked the same way as overall standings. Students without marks in the
+// subject are left out.
+func (set *Set) SubjectRanks(subject string) map[string]int {
+	ranks := map[string]int{}
+	for _, st := range rank(set.bySubject[strings.ToLower(subject)]) {
+		ranks[st.studentID] = st.rank
+	}
+	return ranks
+}
+
+// Class returns class and subject averages with the top students overall
+// and in each subject. students supplies names for the toppers.
+func (set *Set) Class(students []models.Student, top int) models.ClassAnalytics {
+	names := make(map[string]*models.Student, len(students))
+	for i := range students {
+		names[students[i].ID] = &students[i]
+	}
+	topper := func(studentID string, rank int, percentage float64) models.Topper {
+		t := models.Topper{StudentID: studentID, Rank: rank, Percentage: round(percentage)}
+		if student := names[studentID]; student != nil {
+			t.Name, t.RollNumber = student.FullName, student.RollNumber
+		}
+		return t
+	}
+
+	out := models.ClassAnalytics{
+		Exams:    len(set.exams),
+		Students: len(set.standings),
+		Toppers:  []models.Topper{},
+		Subjects: []models.SubjectStats{},
+		Trend:    []models.TrendPoint{},
+	}
+	if len(set.standings) > 0 {
+		sum := 0.0
+		for _, st := range set.standings {
+			sum += st.percentage
+		}
+		out.Average = ptr(round(sum / float64(len(set.standings))))
+	}
+	for _, st := range set.standings {
+		if st.rank > top {
+			break
+		}
+		out.Toppers = append(out.Toppers, topper(st.studentID, st.rank, st.percentage))
+	}
+
+	keys := make([]string, 0, len(set.bySubject))
+	for key := range set.bySubject {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ranked := rank(set.bySubject[key])
+		if len(ranked) == 0 {
+			continue
+		}
+		stats := models.SubjectStats{
+			Subject:  set.subjectOf[key],
+			Students: len(ranked),
+			Highest:  round(ranked[0].percentage),
+			Lowest:   round(ranked[len(ranked)-1].percentage),
+			Toppers:  []models.Topper{},
+		}
+		sum := 0.0
+		for _, st := range ranked {
+			sum += st.percentage
+			if st.rank == 1 {
+				stats.Toppers = append(stats.Toppers, topper(st.studentID, st.rank, st.percentage))
+			}
+		}
+		stats.Average = round(sum / float64(len(ranked)))
+		out.Subjects = append(out.Subjects, stats)
+	}
+
+	for _, exam := range set.exams {
+		sum, n := 0.0, 0
+		for _, exams := range set.byExam {
+			if m := exams[exam.ID]; m != nil && m.max > 0 {
+				sum += m.percent()
+				n++
+			}
+		}
+		if n > 0 {
+			out.Trend = append(out.Trend, models.TrendPoint{
+				ExamID: exam.ID, Title: exam.Title, Date: exam.Date, Percentage: round(sum / float64(n)),
+			})
+		}
+	}
+	return out
+}
+
+// trend lists the percentage in each exam of the set that has marks.
+func (set *Set) trend(byExam map[string]*marks) []models.TrendPoint {
+	points := []models.TrendPoint{}
+	for _, exam := range set.exams {
+		m := byExam[exam.ID]
+		if m == nil || m.max <= 0 {
+			continue
+		}
+		points = append(points, models.TrendPoint{
+			ExamID: exam.ID, Title: exam.Title, Date: exam.Date, Percentage: round(m.percent()),
+		})
+	}
+	return points
+}
+
+// rank orders students by percentage, highest first, with standard
+// competition ranking (1, 2, 2, 4) for ties.
+func rank(totals map[string]*marks) []standing {
+	out := make([]standing, 0, len(totals))
+	for studentID, m := range totals {
+		if m.max <= 0 {
+			continue
+		}
+		out = append(out, standing{studentID: studentID, percentage: m.percent()})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		pi, pj := round(out[i].percentage), round(out[j].percentage)
+		if pi != pj {
+			return pi > pj
+		}
+		return out[i].studentID < out[j].studentID
+	})
+	for i := range out {
+		if i > 0 && round(out[i].percentage) == round(out[i-1].percentage) {
+			out[i].rank = out[i-1].rank
+		} else {
+			out[i].rank = i + 1
+		}
+	}
+	return out
+}
+
+func change(points []models.TrendPoint) *float64 {
+	if len(points) < 2 {
+		return nil
+	}
+	return ptr(round(points[len(points)-1].Percentage - points[len(points)-2].Percentage))
+}
+
+func add(totals map[string]*marks, key string, score models.Score) {
+	m := totals[key]
+	if m == nil {
+		m = &marks{}
+		totals[key] = m
+	}
+	m.score += score.Score
+	m.max += score.MaxScore
+}
+
+func nested[T any](m map[string]map[string]T, key string) map[string]T {
+	inner := m[key]
+	if inner == nil {
+		inner = map[string]T{}
+		m[key] = inner
+	}
+	return inner
+}
+
+// round keeps two decimals, which is also the precision ties are judged at.
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func ptr(value float64) *float64 {
+	return &value
+}
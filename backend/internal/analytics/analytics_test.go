@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+
+	"jnv/backend/internal/models"
+)
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name   string
+		totals map[string]*marks
+		want   []standing
+	}{
+		{
+			name: "ties share a rank and skip the next",
+			totals: map[string]*marks{
+				"d": {score: 70, max: 100},
+				"b": {score: 40, max: 50},
+				"a": {score: 90, max: 100},
+				"c": {score: 80, max: 100},
+			},
+			want: []standing{
+				{studentID: "a", percentage: 90, rank: 1},
+				{studentID: "b", percentage: 80, rank: 2},
+				{studentID: "c", percentage: 80, rank: 2},
+				{studentID: "d", percentage: 70, rank: 4},
+			},
+		},
+		{
+			name: "float noise ties at two decimals",
+			totals: map[string]*marks{
+				"a": {score: 27.3, max: 30},
+				"b": {score: 91, max: 100},
+			},
+			want: []standing{
+				{studentID: "a", percentage: marks{score: 27.3, max: 30}.percent(), rank: 1},
+				{studentID: "b", percentage: 91, rank: 1},
+			},
+		},
+		{
+			name: "no maximum is left out",
+			totals: map[string]*marks{
+				"a": {score: 0, max: 0},
+				"b": {score: 10, max: 20},
+			},
+			want: []standing{{studentID: "b", percentage: 50, rank: 1}},
+		},
+		{
+			name:   "empty",
+			totals: map[string]*marks{"a": {score: 5, max: 0}},
+			want:   []standing{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rank(tt.totals); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rank = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStudentPercentile(t *testing.T) {
+	exams := []models.Exam{{ID: "e1", Title: "Periodic Test 1"}}
+	scores := []models.Score{
+		{ExamID: "e1", StudentID: "a", Subject: "English", Score: 90, MaxScore: 100},
+		{ExamID: "e1", StudentID: "b", Subject: "English", Score: 80, MaxScore: 100},
+		{ExamID: "e1", StudentID: "c", Subject: "English", Score: 80, MaxScore: 100},
+		{ExamID: "e1", StudentID: "d", Subject: "English", Score: 70, MaxScore: 100},
+	}
+	set := New(exams, scores)
+	tests := []struct {
+		studentID  string
+		rank       int
+		percentile float64
+	}{
+		{"a", 1, 87.5},
+		{"b", 2, 50},
+		{"c", 2, 50},
+		{"d", 4, 12.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.studentID, func(t *testing.T) {
+			got := set.Student(tt.studentID, nil)
+			if got.Rank != tt.rank || got.Percentile == nil || *got.Percentile != tt.percentile {
+				t.Errorf("Student(%s) rank %d, percentile %v; want %d, %v", tt.studentID, got.Rank, got.Percentile, tt.rank, tt.percentile)
+			}
+			if got.ClassSize != 4 {
+				t.Errorf("ClassSize = %d, want 4", got.ClassSize)
+			}
+		})
+	}
+
+	if got := set.Student("missing", nil); got.Percentile != nil || got.Rank != 0 {
+		t.Errorf("Student(missing) = rank %d, percentile %v; want no standing", got.Rank, got.Percentile)
+	}
+}
+
+func TestClassSkipsSubjectsWithoutMaximum(t *testing.T) {
+	exams := []models.Exam{{ID: "e1", Title: "Periodic Test 1"}}
+	scores := []models.Score{
+		{ExamID: "e1", StudentID: "a", Subject: "English", Score: 18, MaxScore: 20},
+		{ExamID: "e1", StudentID: "a", Subject: "Art", Score: 0, MaxScore: 0},
+		{ExamID: "e1", StudentID: "b", Subject: "Art", Score: 0, MaxScore: 0},
+	}
+	got := New(exams, scores).Class(nil, 3)
+	if len(got.Subjects) != 1 || got.Subjects[0].Subject != "English" {
+		t.Fatalf("Subjects = %+v, want English only", got.Subjects)
+	}
+	if got.Students != 1 {
+		t.Errorf("Students = %d, want 1", got.Students)
+	}
+}
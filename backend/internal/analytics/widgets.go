@@ -0,0 +1,90 @@
+package analytics
+
+import (
+	"strconv"
+
+	"jnv/backend/internal/models"
+)
+
+// Metrics a dashboard widget can bind to.
+const (
+	MetricPercentage = "percentage"
+	MetricRank       = "rank"
+	MetricPercentile = "percentile"
+	MetricGrade      = "grade"
+	MetricTrend      = "trend"
+)
+
+func ValidMetric(metric string) bool {
+	switch metric {
+	case MetricPercentage, MetricRank, MetricPercentile, MetricGrade, MetricTrend:
+		return true
+	}
+	return false
+}
+
+// Personalise fills in the value of each widget bound to a metric from the
+// student's analytics; widgets without a metric are returned unchanged. A
+// metric with no data yet shows "-".
+func Personalise(widgets []models.DashboardWidget, a models.StudentAnalytics) []models.DashboardWidget {
+	out := make([]models.DashboardWidget, len(widgets))
+	for i, widget := range widgets {
+		out[i] = widget
+		if widget.Metric == "" {
+			continue
+		}
+		value, hint := metricValue(widget.Metric, a)
+		out[i].Value = value
+		if out[i].Hint == "" {
+			out[i].Hint = hint
+		}
+	}
+	return out
+}
+
+func metricValue(metric string, a models.StudentAnalytics) (string, string) {
+	const none = "-"
+	switch metric {
+	case MetricPercentage:
+		if a.Percentage == nil {
+			return none, ""
+		}
+		return formatPercent(*a.Percentage), "Across " + plural(a.Exams, "exam")
+	case MetricRank:
+		if a.Rank == 0 {
+			return none, ""
+		}
+		return "#" + strconv.Itoa(a.Rank), "of " + strconv.Itoa(a.ClassSize) + " in class " + a.Class
+	case MetricPercentile:
+		if a.Percentile == nil {
+			return none, ""
+		}
+		return strconv.FormatFloat(*a.Percentile, 'f', 0, 64), "Percentile in class"
+	case MetricGrade:
+		if a.Grade == "" {
+			return none, ""
+		}
+		return a.Grade, "Overall grade"
+	case MetricTrend:
+		if a.Change == nil {
+			return none, ""
+		}
+		value := formatPercent(*a.Change)
+		if *a.Change >= 0 {
+			value = "+" + value
+		}
+		return value, "Since the previous exam"
+	}
+	return none, ""
+}
+
+func formatPercent(value float64) string {
+	return strconv.FormatFloat(value, 'f', 1, 64) + "%"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
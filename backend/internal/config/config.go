@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +17,20 @@ type Config struct {
 	FirebaseProjectID       string
 	FirebaseCredentialsFile string
 	CORSAllowedOrigins      []string
+	PublicBaseURL           string
+	StorageBackend          string
+	StorageLocalDir         string
+	StorageSigningKey       string
+	IDCardSigningKey        string
+	S3Endpoint              string
+	S3Region                string
+	S3Bucket                string
+	S3AccessKeyID           string
+	S3SecretAccessKey       string
+	ParentLinkRemindDays    int
+	ParentLinkEscalateDays  int
+	ImportWorkers           int
+	InfirmarySummaryHour    int
 }
 
 func Load() Config {
@@ -29,6 +44,20 @@ func Load() Config {
 		FirebaseProjectID:       getEnv("FIREBASE_PROJECT_ID", ""),
 		FirebaseCredentialsFile: getEnv("FIREBASE_CREDENTIALS_FILE", ""),
 		CORSAllowedOrigins:      parseCSV(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:5173,http://localhost:3000")),
+		PublicBaseURL:           getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+		StorageBackend:          getEnv("STORAGE_BACKEND", "local"),
+		StorageLocalDir:         getEnv("STORAGE_LOCAL_DIR", "./data/uploads"),
+		StorageSigningKey:       getEnv("STORAGE_SIGNING_KEY", ""),
+		IDCardSigningKey:        getEnv("ID_CARD_SIGNING_KEY", ""),
+		S3Endpoint:              getEnv("S3_ENDPOINT", ""),
+		S3Region:                getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:                getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:           getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:       getEnv("S3_SECRET_ACCESS_KEY", ""),
+		ParentLinkRemindDays:    getEnvInt("PARENT_LINK_REMIND_DAYS", 3),
+		ParentLinkEscalateDays:  getEnvInt("PARENT_LINK_ESCALATE_DAYS", 7),
+		ImportWorkers:           getEnvInt("IMPORT_WORKERS", 2),
+		InfirmarySummaryHour:    getEnvInt("INFIRMARY_SUMMARY_HOUR", 18),
 	}
 }
 
@@ -39,6 +68,14 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return val
+}
+
 func loadDotEnv() {
 	path := ".env"
 	content, err := os.ReadFile(path)
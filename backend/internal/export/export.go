@@ -0,0 +1,302 @@
+// Package export lays out exam results as tables for staff to download as
+// CSV or XLSX. The row and matrix layouts use the column names score uploads
+// read, so an exported sheet can be corrected and uploaded again; the
+// summary columns are skipped on upload. Scores without marks are written as
+// their status code, AB, ML or EX, which uploads read back.
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"jnv/backend/internal/analytics"
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/xlsx"
+)
+
+// Layouts of an exam's scores.
+const (
+	// LayoutRows has one row per student and subject.
+	LayoutRows = "rows"
+	// LayoutMatrix has one row per student and one column per subject.
+	LayoutMatrix = "matrix"
+)
+
+// idColumns identify the student at the start of every table.
+var idColumns = []string{"roll", "admission_no", "student_name"}
+
+// Table is one sheet of results.
+type Table struct {
+	Name   string
+	Header []string
+	Rows   [][]xlsx.Cell
+}
+
+// WriteCSV writes the header and rows as CSV.
+func (t Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return err
+	}
+	record := make([]string, 0, len(t.Header))
+	for _, row := range t.Rows {
+		record = record[:0]
+		for _, cell := range row {
+			record = append(record, cell.String())
+		}
+		// Every record has as many fields as the header, which CSV readers
+		// expect.
+		for len(record) < len(t.Header) {
+			record = append(record, "")
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// AddTo writes the table as a sheet with a styled header, keeping the header
+// and the student columns in view.
+func (t Table) AddTo(f *xlsx.File) {
+	sheet := f.AddSheet(t.Name)
+	header := make([]xlsx.Cell, len(t.Header))
+	for i, name := range t.Header {
+		header[i] = xlsx.Header(name)
+	}
+	sheet.AddRow(header...)
+	for _, row := range t.Rows {
+		sheet.AddRow(row...)
+	}
+	sheet.Freeze(1, len(idColumns))
+}
+
+// Exam lays out one exam's scores. Students are those given plus anyone
+// else with a score in the exam; percentages, grades and ranks are over the
+// subjects each student has marks in, as in analytics.
+func Exam(exam models.Exam, students []models.Student, scores []models.Score, bands []models.GradeBand, layout string) Table {
+	set := analytics.New([]models.Exam{exam}, scores)
+	subjects := examSubjects(exam, scores)
+	marks := map[string]map[string]models.Score{}
+	for _, score := range scores {
+		if score.ExamID != exam.ID {
+			continue
+		}
+		bySubject := marks[score.StudentID]
+		if bySubject == nil {
+			bySubject = map[string]models.Score{}
+			marks[score.StudentID] = bySubject
+		}
+		bySubject[strings.ToLower(score.Subject)] = score
+	}
+
+	table := Table{Name: exam.Title}
+	if layout == LayoutRows {
+		table.Header = append(append([]string{}, idColumns...),
+			"subject", "score", "max_score", "percentage", "grade", "subject_rank")
+		ranks := make(map[string]map[string]int, len(subjects))
+		for _, subject := range subjects {
+			ranks[subject] = set.SubjectRanks(subject)
+		}
+		for _, student := range students {
+			for _, subject := range subjects {
+				score, ok := marks[student.ID][strings.ToLower(subject)]
+				if !ok {
+					continue
+				}
+				row := studentCells(student)
+				percentage := xlsx.Cell{}
+				if grading.HasMarks(score.Status) {
+					percentage = percentCell(score.Score, score.MaxScore)
+				}
+				row = append(row,
+					xlsx.Text(score.Subject),
+					scoreCell(score),
+					xlsx.Number(round(float64(score.MaxScore))),
+					percentage,
+					xlsx.Text(score.Grade),
+					rankCell(ranks[subject][student.ID]),
+				)
+				table.Rows = append(table.Rows, row)
+			}
+		}
+		return table
+	}
+
+	table.Header = append([]string{}, idColumns...)
+	for _, subject := range subjects {
+		table.Header = append(table.Header, subjectHeader(exam, subject, scores))
+	}
+	table.Header = append(table.Header, "total", "max_total", "percentage", "grade", "rank")
+	for _, student := range students {
+		row := studentCells(student)
+		var total, max float32
+		for _, subject := range subjects {
+			score, ok := marks[student.ID][strings.ToLower(subject)]
+			if !ok {
+				row = append(row, xlsx.Cell{})
+				continue
+			}
+			if grading.CountsInTotal(score.Status) {
+				total += score.Score
+				max += score.MaxScore
+			}
+			row = append(row, scoreCell(score))
+		}
+		result := set.Student(student.ID, bands)
+		if max > 0 {
+			totalCell := xlsx.Number(round(float64(total)))
+			totalCell.Style = xlsx.StyleBold
+			row = append(row, totalCell, xlsx.Number(round(float64(max))), percentCell(total, max), xlsx.Text(result.Grade), rankCell(result.Rank))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// ClassSummary lists each student's percentage in every exam, then their
+// overall percentage, grade and rank across all of them.
+func ClassSummary(exams []models.Exam, students []models.Student, scores []models.Score, bands []models.GradeBand) Table {
+	set := analytics.New(exams, scores)
+	table := Table{Name: "Summary", Header: append([]string{}, idColumns...)}
+	ordered := append([]models.Exam(nil), exams...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Date.Before(ordered[j].Date) })
+	for _, exam := range ordered {
+		table.Header = append(table.Header, exam.Title)
+	}
+	table.Header = append(table.Header, "percentage", "grade", "rank")
+
+	for _, student := range students {
+		result := set.Student(student.ID, bands)
+		byExam := map[string]float64{}
+		for _, point := range result.Trend {
+			byExam[point.ExamID] = point.Percentage
+		}
+		row := studentCells(student)
+		for _, exam := range ordered {
+			if percentage, ok := byExam[exam.ID]; ok {
+				row = append(row, xlsx.Decimal(percentage))
+			} else {
+				row = append(row, xlsx.Cell{})
+			}
+		}
+		if result.Percentage != nil {
+			overall := xlsx.Decimal(*result.Percentage)
+			overall.Style = xlsx.StyleBold
+			row = append(row, overall, xlsx.Text(result.Grade), rankCell(result.Rank))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// Students merges the class roster with anyone else who has scores, ordered
+// by roll number and then name, so students who have since moved class
+// still appear in old exams.
+func Students(roster, others []models.Student) []models.Student {
+	seen := map[string]bool{}
+	var students []models.Student
+	for _, list := range [][]models.Student{roster, others} {
+		for _, student := range list {
+			if !seen[student.ID] {
+				seen[student.ID] = true
+				students = append(students, student)
+			}
+		}
+	}
+	sort.SliceStable(students, func(i, j int) bool {
+		if students[i].RollNumber != students[j].RollNumber {
+			return students[i].RollNumber < students[j].RollNumber
+		}
+		return students[i].FullName < students[j].FullName
+	})
+	return students
+}
+
+// examSubjects lists the exam's declared subjects in their order, then any
+// other subject with scores by name.
+func examSubjects(exam models.Exam, scores []models.Score) []string {
+	seen := map[string]bool{}
+	var subjects []string
+	for _, subject := range exam.Subjects {
+		if key := strings.ToLower(subject.Name); !seen[key] {
+			seen[key] = true
+			subjects = append(subjects, subject.Name)
+		}
+	}
+	var extra []string
+	for _, score := range scores {
+		if key := strings.ToLower(score.Subject); score.ExamID == exam.ID && !seen[key] {
+			seen[key] = true
+			extra = append(extra, score.Subject)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return strings.ToLower(extra[i]) < strings.ToLower(extra[j]) })
+	return append(subjects, extra...)
+}
+
+// subjectHeader names a matrix column "Subject (max)" when the subject has
+// one maximum in the exam, declared or shared by all its scores, so the
+// sheet uploads back with the same maximum.
+func subjectHeader(exam models.Exam, subject string, scores []models.Score) string {
+	for _, declared := range exam.Subjects {
+		if strings.EqualFold(declared.Name, subject) {
+			return subject + " (" + formatMarks(declared.MaxScore) + ")"
+		}
+	}
+	var max float32
+	for _, score := range scores {
+		if score.ExamID != exam.ID || !strings.EqualFold(score.Subject, subject) {
+			continue
+		}
+		if max != 0 && score.MaxScore != max {
+			return subject
+		}
+		max = score.MaxScore
+	}
+	if max <= 0 {
+		return subject
+	}
+	return subject + " (" + formatMarks(max) + ")"
+}
+
+// scoreCell is the marks of a score, or its status code when it has none.
+func scoreCell(score models.Score) xlsx.Cell {
+	if !grading.HasMarks(score.Status) {
+		return xlsx.Text(grading.StatusCode(score.Status))
+	}
+	return xlsx.Number(round(float64(score.Score)))
+}
+
+func formatMarks(value float32) string {
+	return strconv.FormatFloat(round(float64(value)), 'f', -1, 64)
+}
+
+func studentCells(student models.Student) []xlsx.Cell {
+	return []xlsx.Cell{xlsx.Number(float64(student.RollNumber)), xlsx.Text(student.AdmissionNo), xlsx.Text(student.FullName)}
+}
+
+func percentCell(score, max float32) xlsx.Cell {
+	if max <= 0 {
+		return xlsx.Cell{}
+	}
+	return xlsx.Decimal(round(float64(score) / float64(max) * 100))
+}
+
+func rankCell(rank int) xlsx.Cell {
+	if rank <= 0 {
+		return xlsx.Cell{}
+	}
+	return xlsx.Number(float64(rank))
+}
+
+// round drops the float32 noise of stored marks, keeping two decimals.
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/models"
+)
+
+func TestExamCSV(t *testing.T) {
+	exam := models.Exam{ID: "e1", Title: "Periodic Test 1"}
+	students := []models.Student{
+		{ID: "s1", FullName: "Asha Kumari", RollNumber: 1, AdmissionNo: "A1"},
+		{ID: "s2", FullName: "Ravi Singh", RollNumber: 2},
+		{ID: "s3", FullName: "Meena Das", RollNumber: 3},
+	}
+	scores := []models.Score{
+		{ExamID: "e1", StudentID: "s1", Subject: "English", Score: 72, MaxScore: 80, Status: models.ScorePresent},
+		{ExamID: "e1", StudentID: "s1", Subject: "Mathematics", Score: 18, MaxScore: 20, Status: models.ScorePresent},
+		{ExamID: "e1", StudentID: "s2", Subject: "English", MaxScore: 80, Status: models.ScoreAbsent},
+		{ExamID: "e1", StudentID: "s2", Subject: "Mathematics", Score: 15, MaxScore: 20, Status: models.ScorePresent},
+		{ExamID: "e2", StudentID: "s3", Subject: "English", Score: 50, MaxScore: 80, Status: models.ScorePresent},
+	}
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{LayoutMatrix, "" +
+			"roll,admission_no,student_name,English (80),Mathematics (20),total,max_total,percentage,grade,rank\n" +
+			"1,A1,Asha Kumari,72,18,90,100,90.00,A2,1\n" +
+			"2,,Ravi Singh,AB,15,15,100,15.00,E,2\n" +
+			"3,,Meena Das,,,,,,,\n"},
+		{LayoutRows, "" +
+			"roll,admission_no,student_name,subject,score,max_score,percentage,grade,subject_rank\n" +
+			"1,A1,Asha Kumari,English,72,80,90.00,,1\n" +
+			"1,A1,Asha Kumari,Mathematics,18,20,90.00,,1\n" +
+			"2,,Ravi Singh,English,AB,80,,,\n" +
+			"2,,Ravi Singh,Mathematics,15,20,75.00,,2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Exam(exam, students, scores, grading.CBSE8, tt.layout).WriteCSV(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("CSV =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package grading turns marks into grades using percentage bands.
+package grading
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"jnv/backend/internal/models"
+)
+
+// CBSE8 is the CBSE 8-point scale.
+var CBSE8 = []models.GradeBand{
+	{Grade: "A1", MinPercent: 91},
+	{Grade: "A2", MinPercent: 81},
+	{Grade: "B1", MinPercent: 71},
+	{Grade: "B2", MinPercent: 61},
+	{Grade: "C1", MinPercent: 51},
+	{Grade: "C2", MinPercent: 41},
+	{Grade: "D", MinPercent: 33},
+	{Grade: "E", MinPercent: 0},
+}
+
+// Presets are the ready-made schemes a school can pick by name.
+var Presets = map[string][]models.GradeBand{
+	"cbse_8_point": CBSE8,
+}
+
+// Normalize trims grade names and orders bands from the highest threshold
+// down, then checks that they cover every percentage from 0 to 100 without
+// repeating a grade or a threshold.
+func Normalize(bands []models.GradeBand) ([]models.GradeBand, error) {
+	if len(bands) == 0 {
+		return nil, fmt.Errorf("at least one band is required")
+	}
+	if len(bands) > 20 {
+		return nil, fmt.Errorf("at most 20 bands are allowed")
+	}
+	out := make([]models.GradeBand, len(bands))
+	seen := map[string]bool{}
+	for i, band := range bands {
+		band.Grade = strings.TrimSpace(band.Grade)
+		if band.Grade == "" || len(band.Grade) > 10 {
+			return nil, fmt.Errorf("band %d: grade must be 1 to 10 characters", i+1)
+		}
+		if band.MinPercent < 0 || band.MinPercent > 100 {
+			return nil, fmt.Errorf("band %s: min_percent must be between 0 and 100", band.Grade)
+		}
+		key := strings.ToUpper(band.Grade)
+		if seen[key] {
+			return nil, fmt.Errorf("grade %s appears twice", band.Grade)
+		}
+		seen[key] = true
+		out[i] = band
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].MinPercent > out[j].MinPercent })
+	for i := 1; i < len(out); i++ {
+		if out[i].MinPercent == out[i-1].MinPercent {
+			return nil, fmt.Errorf("grades %s and %s share min_percent %g", out[i-1].Grade, out[i].Grade, out[i].MinPercent)
+		}
+	}
+	if out[len(out)-1].MinPercent != 0 {
+		return nil, fmt.Errorf("the lowest band must start at 0")
+	}
+	return out, nil
+}
+
+// Grade returns the grade for score out of max. Bands must be normalized.
+// The percentage is rounded to two decimals first, so float32 marks such as
+// 27.3/30 reach the 91% threshold instead of stopping at 90.99999%.
+func Grade(bands []models.GradeBand, score, max float32) string {
+	if max <= 0 || len(bands) == 0 {
+		return ""
+	}
+	percent := math.Round(float64(score)/float64(max)*10000) / 100
+	for _, band := range bands {
+		if percent >= band.MinPercent {
+			return band.Grade
+		}
+	}
+	return bands[len(bands)-1].Grade
+}
+
+// Resolve returns the grade to store for a score. An empty supplied grade
+// is computed; a supplied one must be the grade the marks earn, compared
+// case-insensitively, and is returned in the scheme's spelling.
+func Resolve(bands []models.GradeBand, score, max float32, supplied string) (string, error) {
+	computed := Grade(bands, score, max)
+	supplied = strings.TrimSpace(supplied)
+	if supplied == "" || strings.EqualFold(supplied, computed) {
+		return computed, nil
+	}
+	for _, band := range bands {
+		if strings.EqualFold(supplied, band.Grade) {
+			return "", fmt.Errorf("grade %s does not match %g/%g (expected %s)", supplied, score, max, computed)
+		}
+	}
+	return "", fmt.Errorf("grade %s is not in the grading scheme", supplied)
+}
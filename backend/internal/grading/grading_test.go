@@ -0,0 +1,114 @@
+package grading
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"jnv/backend/internal/models"
+)
+
+func TestGradeCBSE8(t *testing.T) {
+	tests := []struct {
+		score, max float32
+		want       string
+	}{
+		{100, 100, "A1"},
+		{91, 100, "A1"},
+		{90.9, 100, "A2"},
+		{27.3, 30, "A1"},
+		{72.8, 80, "A1"},
+		{81, 100, "A2"},
+		{80.99, 100, "B1"},
+		{71, 100, "B1"},
+		{61, 100, "B2"},
+		{51, 100, "C1"},
+		{41, 100, "C2"},
+		{33, 100, "D"},
+		{9.9, 30, "D"},
+		{32.99, 100, "E"},
+		{0, 100, "E"},
+		{10, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%g/%g", tt.score, tt.max), func(t *testing.T) {
+			if got := Grade(CBSE8, tt.score, tt.max); got != tt.want {
+				t.Errorf("Grade(%g, %g) = %q, want %q", tt.score, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		bands []models.GradeBand
+		want  []models.GradeBand
+		err   string
+	}{
+		{
+			name:  "sorted and trimmed",
+			bands: []models.GradeBand{{Grade: " C ", MinPercent: 0}, {Grade: "A", MinPercent: 80}, {Grade: "B", MinPercent: 50}},
+			want:  []models.GradeBand{{Grade: "A", MinPercent: 80}, {Grade: "B", MinPercent: 50}, {Grade: "C", MinPercent: 0}},
+		},
+		{name: "cbse", bands: CBSE8, want: CBSE8},
+		{name: "empty", bands: nil, err: "at least one band"},
+		{name: "blank grade", bands: []models.GradeBand{{Grade: " ", MinPercent: 0}}, err: "grade must be 1 to 10 characters"},
+		{name: "above 100", bands: []models.GradeBand{{Grade: "A", MinPercent: 101}, {Grade: "B", MinPercent: 0}}, err: "between 0 and 100"},
+		{name: "below 0", bands: []models.GradeBand{{Grade: "A", MinPercent: -1}}, err: "between 0 and 100"},
+		{name: "repeated grade", bands: []models.GradeBand{{Grade: "A", MinPercent: 50}, {Grade: "a", MinPercent: 0}}, err: "appears twice"},
+		{name: "shared threshold", bands: []models.GradeBand{{Grade: "A", MinPercent: 50}, {Grade: "B", MinPercent: 50}, {Grade: "C", MinPercent: 0}}, err: "share min_percent 50"},
+		{name: "gap at 0", bands: []models.GradeBand{{Grade: "A", MinPercent: 50}, {Grade: "B", MinPercent: 10}}, err: "must start at 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.bands)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Normalize error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Normalize = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		score, max float32
+		supplied   string
+		want       string
+		err        string
+	}{
+		{"computed", 27.3, 30, "", "A1", ""},
+		{"matching", 27.3, 30, "A1", "A1", ""},
+		{"case-insensitive", 27.3, 30, " a1 ", "A1", ""},
+		{"wrong band", 27.3, 30, "A2", "", "does not match 27.3/30 (expected A1)"},
+		{"not in scheme", 50, 100, "F", "", "not in the grading scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(CBSE8, tt.score, tt.max, tt.supplied)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Resolve error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package grading
+
+import (
+	"strings"
+
+	"jnv/backend/internal/models"
+)
+
+// statusCodes are the codes sheets and report cards use for scores without
+// marks.
+var statusCodes = map[string]string{
+	"AB": models.ScoreAbsent,
+	"ML": models.ScoreMedicalLeave,
+	"EX": models.ScoreExempt,
+}
+
+// ParseStatus returns the status a code such as AB stands for, compared
+// case-insensitively, and false when value is not a code.
+func ParseStatus(value string) (string, bool) {
+	status, ok := statusCodes[strings.ToUpper(strings.TrimSpace(value))]
+	return status, ok
+}
+
+// StatusCode returns the code a score without marks is shown as, or "" for
+// a present score.
+func StatusCode(status string) string {
+	for code, s := range statusCodes {
+		if s == status {
+			return code
+		}
+	}
+	return ""
+}
+
+// HasMarks reports whether a score with this status carries marks. Scores
+// stored before statuses existed have none set and count as present.
+func HasMarks(status string) bool {
+	return status == "" || status == models.ScorePresent
+}
+
+// CountsInTotal reports whether a score adds to a student's totals. An
+// absent paper counts as zero out of its maximum; medical leave and
+// exemptions are left out, so they lower neither the marks nor the maximum.
+func CountsInTotal(status string) bool {
+	return HasMarks(status) || status == models.ScoreAbsent
+}
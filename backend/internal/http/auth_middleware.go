@@ -94,6 +94,8 @@ func toRole(value string) models.Role {
 		return models.RoleStaff
 	case string(models.RoleTeacher):
 		return models.RoleTeacher
+	case string(models.RoleNurse):
+		return models.RoleNurse
 	case string(models.RoleParent):
 		return models.RoleParent
 	default:
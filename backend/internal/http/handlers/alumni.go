@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/storage"
+	"jnv/backend/internal/store"
+)
+
+type AlumniHandler struct {
+	Store *store.Store
+}
+
+type graduateRequest struct {
+	Class          string   `json:"class"`
+	GraduationYear int      `json:"graduation_year"`
+	StudentIDs     []string `json:"student_ids"`
+}
+
+type alumniContactRequest struct {
+	Phone        string `json:"phone"`
+	Email        string `json:"email"`
+	City         string `json:"city"`
+	Occupation   string `json:"occupation"`
+	ShareContact bool   `json:"share_contact"`
+}
+
+const maxAlumniResults = 1000
+
+func (h AlumniHandler) Graduate(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+
+	var req graduateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	classLabel := strings.TrimSpace(req.Class)
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "class is required")
+		return
+	}
+	currentYear := time.Now().Year()
+	if req.GraduationYear == 0 {
+		req.GraduationYear = currentYear
+	}
+	if req.GraduationYear < 1985 || req.GraduationYear > currentYear+1 {
+		writeError(w, http.StatusBadRequest, "invalid graduation_year")
+		return
+	}
+
+	count, err := h.Store.GraduateStudents(r.Context(), user.SchoolID, classLabel, req.GraduationYear, req.StudentIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to archive students")
+		return
+	}
+	auditLog(r.Context(), "students.graduated", user, map[string]interface{}{
+		"class":           classLabel,
+		"graduation_year": req.GraduationYear,
+		"count":           count,
+	})
+	writeJSON(w, http.StatusOK, map[string]int{"graduated": count})
+}
+
+func (h AlumniHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+
+	year := 0
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid year")
+			return
+		}
+		year = parsed
+	}
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	items, err := h.Store.ListAlumni(r.Context(), user.SchoolID, year, search, maxAlumniResults)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list alumni")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h AlumniHandler) GetContact(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := h.authorizeContact(w, r, user)
+	if !ok {
+		return
+	}
+	contact, err := h.Store.GetAlumniContact(r.Context(), student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load contact")
+		return
+	}
+	if contact == nil {
+		contact = &models.AlumniContact{StudentID: student.ID}
+	}
+	writeJSON(w, http.StatusOK, contact)
+}
+
+// UpdateContact lets a former student (through the family account that was
+// linked to them, or a phone on their guardian list) keep their contact
+// details current. Details appear in the directory only with share_contact.
+func (h AlumniHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := h.authorizeContact(w, r, user)
+	if !ok {
+		return
+	}
+
+	var req alumniContactRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	phone, err := normalizePhone(req.Phone, "phone")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email != "" && !strings.Contains(email, "@") {
+		writeError(w, http.StatusBadRequest, "invalid email")
+		return
+	}
+
+	contact, err := h.Store.UpsertAlumniContact(r.Context(), models.AlumniContact{
+		StudentID:    student.ID,
+		Phone:        phone,
+		Email:        email,
+		City:         strings.TrimSpace(req.City),
+		Occupation:   strings.TrimSpace(req.Occupation),
+		ShareContact: req.ShareContact,
+	}, user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save contact")
+		return
+	}
+	auditLog(r.Context(), "alumni.contact.updated", user, map[string]interface{}{
+		"student_id":    student.ID,
+		"share_contact": contact.ShareContact,
+	})
+	writeJSON(w, http.StatusOK, contact)
+}
+
+// ExportScores returns an alumnus's complete score history as CSV for
+// certificate verification.
+func (h AlumniHandler) ExportScores(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	records, err := h.Store.ListScoreRecordsByStudent(r.Context(), student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load scores")
+		return
+	}
+
+	auditLog(r.Context(), "alumni.scores.exported", user, map[string]interface{}{
+		"student_id": student.ID,
+		"count":      len(records),
+	})
+	w.Header().Set("Content-Type", storage.ContentTypeCSV)
+	w.Header().Set("Content-Disposition", `attachment; filename="scores-`+sanitizeFileName(student.FullName)+`.csv"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	_ = out.Write([]string{"student_name", "admission_year", "graduation_year", "exam_date", "exam", "term", "class", "subject", "score", "max_score", "grade"})
+	graduationYear := ""
+	if student.GraduationYear != nil {
+		graduationYear = strconv.Itoa(*student.GraduationYear)
+	}
+	for _, record := range records {
+		score := strconv.FormatFloat(float64(record.Score.Score), 'f', -1, 32)
+		if !grading.HasMarks(record.Status) {
+			score = grading.StatusCode(record.Status)
+		}
+		_ = out.Write([]string{
+			student.FullName,
+			strconv.Itoa(student.AdmissionYear),
+			graduationYear,
+			record.ExamDate.Format("2006-01-02"),
+			record.ExamTitle,
+			record.ExamTerm,
+			record.ExamClass,
+			record.Subject,
+			score,
+			strconv.FormatFloat(float64(record.MaxScore), 'f', -1, 32),
+			record.Grade,
+		})
+	}
+	out.Flush()
+}
+
+func (h AlumniHandler) authorizeContact(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Student, bool) {
+	studentID := r.PathValue("id")
+	if studentID == "" {
+		writeError(w, http.StatusBadRequest, "missing student id")
+		return nil, false
+	}
+	student, err := h.Store.GetStudent(r.Context(), studentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load student")
+		return nil, false
+	}
+	if student == nil {
+		writeError(w, http.StatusNotFound, "student not found")
+		return nil, false
+	}
+	if student.Status != models.StudentStatusAlumni {
+		writeError(w, http.StatusConflict, "student has not graduated")
+		return nil, false
+	}
+
+	if hasRole(user, models.RoleAdmin) && student.SchoolID == user.SchoolID {
+		return student, true
+	}
+	linked, err := h.Store.IsParentLinkedToStudent(r.Context(), user.ID, student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to validate access")
+		return nil, false
+	}
+	if linked {
+		return student, true
+	}
+	if key := verifiedPhoneKey(user); key != "" && hasRole(user, models.RoleParent) && student.SchoolID == user.SchoolID {
+		guardian, err := h.Store.FindGuardianByPhone(r.Context(), student.ID, key)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to validate access")
+			return nil, false
+		}
+		if guardian != nil {
+			return student, true
+		}
+	}
+	writeError(w, http.StatusForbidden, "not linked to this student")
+	return nil, false
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"jnv/backend/internal/analytics"
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// analyticsToppers is how many students the class toppers list holds,
+// more when there are ties at the end.
+const analyticsToppers = 5
+
+type AnalyticsHandler struct {
+	Store *store.Store
+}
+
+// Student returns a student's percentage, class rank, percentile and
+// subject trends across the class's exams, or those of ?term=. Parents only
+// see published exams.
+func (h AnalyticsHandler) Student(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := authorizeStudentView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	term := strings.TrimSpace(r.URL.Query().Get("term"))
+	result, err := studentAnalytics(r.Context(), h.Store, student, term, hasRole(user, models.RoleParent))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to compute analytics")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Class returns class and subject averages, toppers and the class trend
+// across exams.
+func (h AnalyticsHandler) Class(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	classLabel := strings.TrimSpace(r.PathValue("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "missing class")
+		return
+	}
+	term := strings.TrimSpace(r.URL.Query().Get("term"))
+	set, _, err := loadAnalyticsSet(r.Context(), h.Store, user.SchoolID, classLabel, term, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to compute analytics")
+		return
+	}
+	students, err := h.Store.ListStudentsBySchool(r.Context(), user.SchoolID, classLabel, maxClassRoster)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list students")
+		return
+	}
+	result := set.Class(students, analyticsToppers)
+	result.Class, result.Term = classLabel, term
+	writeJSON(w, http.StatusOK, result)
+}
+
+func studentAnalytics(ctx context.Context, s *store.Store, student *models.Student, term string, publishedOnly bool) (models.StudentAnalytics, error) {
+	set, bands, err := loadAnalyticsSet(ctx, s, student.SchoolID, student.ClassLabel, term, publishedOnly)
+	if err != nil {
+		return models.StudentAnalytics{}, err
+	}
+	result := set.Student(student.ID, bands)
+	result.Class, result.Term = student.ClassLabel, term
+	return result, nil
+}
+
+// loadAnalyticsSet reads the class's exams, optionally of one term, with
+// every score in them and the class's grade bands.
+func loadAnalyticsSet(ctx context.Context, s *store.Store, schoolID, class, term string, publishedOnly bool) (*analytics.Set, []models.GradeBand, error) {
+	all, err := s.ListExams(ctx, schoolID, store.ExamFilter{Class: class, Term: term})
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		exams   []models.Exam
+		examIDs []string
+	)
+	for _, exam := range all {
+		if exam.Status == models.ExamDraft {
+			continue
+		}
+		if publishedOnly && exam.Status != models.ExamResultsPublished && exam.Status != models.ExamLocked {
+			continue
+		}
+		exams = append(exams, exam)
+		examIDs = append(examIDs, exam.ID)
+	}
+	var scores []models.Score
+	if len(examIDs) > 0 {
+		if scores, err = s.ListScoresForExams(ctx, examIDs, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	var bands []models.GradeBand
+	scheme, err := s.GradingSchemeForClass(ctx, schoolID, class)
+	if err != nil {
+		return nil, nil, err
+	}
+	if scheme != nil {
+		bands = scheme.Bands
+	}
+	return analytics.New(exams, scores), bands, nil
+}
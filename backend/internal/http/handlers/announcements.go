@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/google/uuid"
+
 	"jnv/backend/internal/httpctx"
 	"jnv/backend/internal/models"
 	"jnv/backend/internal/notify"
@@ -143,3 +145,27 @@ func (h AnnouncementHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
+
+// MarkRead records that the signed-in user has read an announcement, so it
+// drops out of their unread count.
+func (h AnnouncementHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "announcement not found")
+		return
+	}
+	if err := h.Store.MarkAnnouncementRead(r.Context(), id, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "announcement not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to mark read")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "read"})
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
+	"jnv/backend/internal/analytics"
 	"jnv/backend/internal/httpctx"
 	"jnv/backend/internal/models"
 	"jnv/backend/internal/store"
@@ -30,9 +32,58 @@ func (h AppConfigHandler) Get(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to load config")
 		return
 	}
+	if hasMetricWidgets(config.DashboardWidgets) {
+		student, ok := h.widgetStudent(w, r, user)
+		if !ok {
+			return
+		}
+		if student != nil {
+			result, err := studentAnalytics(r.Context(), h.Store, student, "", hasRole(user, models.RoleParent))
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to compute analytics")
+				return
+			}
+			config.DashboardWidgets = analytics.Personalise(config.DashboardWidgets, result)
+		}
+	}
 	writeJSON(w, http.StatusOK, config)
 }
 
+// widgetStudent picks the student metric widgets are computed for:
+// ?student_id= when given, otherwise a parent's current child. Staff without
+// ?student_id= get the widgets as configured, so they can edit them.
+func (h AppConfigHandler) widgetStudent(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Student, bool) {
+	if studentID := r.URL.Query().Get("student_id"); studentID != "" {
+		return authorizeStudentView(w, r, h.Store, user, studentID)
+	}
+	if !hasRole(user, models.RoleParent) {
+		return nil, true
+	}
+	link, err := h.Store.LatestParentLinkByParent(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load parent link")
+		return nil, false
+	}
+	if link == nil || link.Status != models.ParentLinkApproved {
+		return nil, true
+	}
+	student, err := h.Store.GetStudent(r.Context(), link.StudentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load student")
+		return nil, false
+	}
+	return student, true
+}
+
+func hasMetricWidgets(widgets []models.DashboardWidget) bool {
+	for _, widget := range widgets {
+		if widget.Metric != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (h AppConfigHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 	user := httpctx.UserFromContext(r.Context())
 	if user == nil {
@@ -54,6 +105,12 @@ func (h AppConfigHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 	if req.DashboardWidgets == nil {
 		req.DashboardWidgets = []models.DashboardWidget{}
 	}
+	for i, widget := range req.DashboardWidgets {
+		if widget.Metric != "" && !analytics.ValidMetric(widget.Metric) {
+			writeError(w, http.StatusBadRequest, "dashboard_widgets["+strconv.Itoa(i)+"]: unknown metric "+widget.Metric)
+			return
+		}
+	}
 	if err := h.Store.UpsertAppConfig(r.Context(), models.AppConfig{
 		SchoolID:            user.SchoolID,
 		FeatureFlags:        req.FeatureFlags,
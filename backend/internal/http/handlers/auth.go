@@ -89,6 +89,8 @@ func claimsRoleOrParent(value string) models.Role {
 		return models.RoleStaff
 	case string(models.RoleTeacher):
 		return models.RoleTeacher
+	case string(models.RoleNurse):
+		return models.RoleNurse
 	default:
 		return models.RoleParent
 	}
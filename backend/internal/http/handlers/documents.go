@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/notify"
+	"jnv/backend/internal/storage"
+	"jnv/backend/internal/store"
+)
+
+type DocumentsHandler struct {
+	Store    *store.Store
+	Files    storage.Bucket
+	Notifier notify.Sender
+}
+
+type rejectDocumentRequest struct {
+	Reason string `json:"reason"`
+}
+
+type documentRequirementsRequest struct {
+	Class    string   `json:"class"`
+	DocTypes []string `json:"doc_types"`
+}
+
+var documentTypes = map[string]bool{
+	"photo":                true,
+	"birth_certificate":    true,
+	"caste_certificate":    true,
+	"income_certificate":   true,
+	"medical_form":         true,
+	"transfer_certificate": true,
+	"aadhaar":              true,
+	"other":                true,
+}
+
+var documentUploadPolicy = storage.Policy{
+	MaxBytes:     5 << 20,
+	AllowedTypes: []string{storage.ContentTypePDF, storage.ContentTypeJPEG, storage.ContentTypePNG},
+}
+
+var photoUploadPolicy = storage.Policy{
+	MaxBytes:     2 << 20,
+	AllowedTypes: []string{storage.ContentTypeJPEG, storage.ContentTypePNG},
+}
+
+func (h DocumentsHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := authorizeStudentView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	items, err := h.Store.ListStudentDocuments(r.Context(), student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list documents")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h DocumentsHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleParent) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := authorizeStudentView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if h.Files == nil {
+		writeError(w, http.StatusServiceUnavailable, "file storage not configured")
+		return
+	}
+	if err := r.ParseMultipartForm(documentUploadPolicy.MaxBytes + (1 << 20)); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid multipart form")
+		return
+	}
+	docType := strings.ToLower(strings.TrimSpace(r.FormValue("doc_type")))
+	if !documentTypes[docType] {
+		writeError(w, http.StatusBadRequest, "unsupported doc_type")
+		return
+	}
+	var expiresOn *time.Time
+	if raw := strings.TrimSpace(r.FormValue("expires_on")); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "expires_on must be YYYY-MM-DD")
+			return
+		}
+		expiresOn = &parsed
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	policy := documentUploadPolicy
+	if docType == "photo" {
+		policy = photoUploadPolicy
+	}
+	data, contentType, status, err := readUpload(file, header, policy)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+
+	docID := uuid.NewString()
+	key := "documents/" + student.SchoolID + "/" + student.ID + "/" + docID + strings.ToLower(filepath.Ext(header.Filename))
+	obj, err := h.Files.Put(r.Context(), key, bytes.NewReader(data), contentType)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store document")
+		return
+	}
+
+	doc, err := h.Store.CreateStudentDocument(r.Context(), models.StudentDocument{
+		ID:          docID,
+		SchoolID:    student.SchoolID,
+		StudentID:   student.ID,
+		DocType:     docType,
+		Status:      "submitted",
+		StorageKey:  obj.Key,
+		FileName:    filepath.Base(header.Filename),
+		ContentType: contentType,
+		SizeBytes:   obj.Size,
+		SHA256:      obj.SHA256,
+		UploadedBy:  user.ID,
+		ExpiresOn:   expiresOn,
+	})
+	if err != nil {
+		_ = h.Files.Delete(r.Context(), obj.Key)
+		writeError(w, http.StatusInternalServerError, "failed to save document")
+		return
+	}
+	auditLog(r.Context(), "student_document.uploaded", user, map[string]interface{}{
+		"student_id":  student.ID,
+		"document_id": doc.ID,
+		"doc_type":    doc.DocType,
+		"upload_key":  obj.Key,
+	})
+	writeJSON(w, http.StatusCreated, doc)
+}
+
+func (h DocumentsHandler) Download(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := authorizeStudentView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	doc, ok := h.loadDocument(w, r, student)
+	if !ok {
+		return
+	}
+	if h.Files == nil {
+		writeError(w, http.StatusServiceUnavailable, "file storage not configured")
+		return
+	}
+	body, _, err := h.Files.Get(r.Context(), doc.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "document file missing")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to read document")
+		return
+	}
+	defer body.Close()
+
+	auditLog(r.Context(), "student_document.downloaded", user, map[string]interface{}{
+		"student_id":  student.ID,
+		"document_id": doc.ID,
+	})
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(doc.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+strings.ReplaceAll(doc.FileName, `"`, "")+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
+
+func (h DocumentsHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "verified")
+}
+
+func (h DocumentsHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, "rejected")
+}
+
+func (h DocumentsHandler) review(w http.ResponseWriter, r *http.Request, status string) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	doc, ok := h.loadDocument(w, r, student)
+	if !ok {
+		return
+	}
+
+	reason := ""
+	if status == "rejected" {
+		var req rejectDocumentRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request")
+			return
+		}
+		reason = strings.TrimSpace(req.Reason)
+		if reason == "" {
+			writeError(w, http.StatusBadRequest, "reason is required")
+			return
+		}
+	}
+
+	if err := h.Store.SetStudentDocumentStatus(r.Context(), doc.ID, student.ID, status, user.ID, reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "document not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update document")
+		return
+	}
+
+	title := "Document verified"
+	body := student.FullName + "'s " + strings.ReplaceAll(doc.DocType, "_", " ") + " has been verified."
+	if status == "rejected" {
+		title = "Document needs attention"
+		body = student.FullName + "'s " + strings.ReplaceAll(doc.DocType, "_", " ") + " was rejected: " + reason
+	}
+	_ = h.Notifier.SendToStudentGuardians(r.Context(), student.ID, title, body, map[string]string{
+		"type":        "student_document",
+		"student_id":  student.ID,
+		"document_id": doc.ID,
+		"status":      status,
+	})
+	auditLog(r.Context(), "student_document."+status, user, map[string]interface{}{
+		"student_id":  student.ID,
+		"document_id": doc.ID,
+		"doc_type":    doc.DocType,
+		"reason":      reason,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+func (h DocumentsHandler) Requirements(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	classLabel := strings.TrimSpace(r.URL.Query().Get("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "class is required")
+		return
+	}
+	items, err := h.Store.ListDocumentRequirements(r.Context(), user.SchoolID, classLabel)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load requirements")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"class": classLabel, "doc_types": items})
+}
+
+func (h DocumentsHandler) SetRequirements(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req documentRequirementsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	req.Class = strings.TrimSpace(req.Class)
+	if req.Class == "" {
+		writeError(w, http.StatusBadRequest, "class is required")
+		return
+	}
+	docTypes := []string{}
+	for _, raw := range req.DocTypes {
+		docType := strings.ToLower(strings.TrimSpace(raw))
+		if !documentTypes[docType] {
+			writeError(w, http.StatusBadRequest, "unsupported doc_type: "+raw)
+			return
+		}
+		docTypes = append(docTypes, docType)
+	}
+	if err := h.Store.ReplaceDocumentRequirements(r.Context(), user.SchoolID, req.Class, docTypes); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save requirements")
+		return
+	}
+	auditLog(r.Context(), "document_requirements.updated", user, map[string]interface{}{
+		"class":     req.Class,
+		"doc_types": docTypes,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"class": req.Class, "doc_types": docTypes})
+}
+
+func (h DocumentsHandler) Checklist(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	classLabel := strings.TrimSpace(r.URL.Query().Get("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "class is required")
+		return
+	}
+	items, err := h.Store.DocumentChecklist(r.Context(), user.SchoolID, classLabel, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to build checklist")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h DocumentsHandler) loadDocument(w http.ResponseWriter, r *http.Request, student *models.Student) (*models.StudentDocument, bool) {
+	documentID := r.PathValue("docID")
+	if documentID == "" {
+		writeError(w, http.StatusBadRequest, "missing document id")
+		return nil, false
+	}
+	doc, err := h.Store.GetStudentDocument(r.Context(), documentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load document")
+		return nil, false
+	}
+	if doc == nil || doc.StudentID != student.ID {
+		writeError(w, http.StatusNotFound, "document not found")
+		return nil, false
+	}
+	return doc, true
+}
@@ -1,23 +1,40 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
 	"jnv/backend/internal/httpctx"
 	"jnv/backend/internal/models"
+	"jnv/backend/internal/notify"
 	"jnv/backend/internal/store"
 )
 
 type ExamHandler struct {
-	Store *store.Store
+	Store    *store.Store
+	Notifier notify.Sender
 }
 
 type createExamRequest struct {
-	Class    string `json:"class"`
-	Title    string `json:"title"`
-	Term     string `json:"term"`
-	Date     string `json:"date"`
+	Class    string               `json:"class"`
+	Title    string               `json:"title"`
+	Term     string               `json:"term"`
+	Date     string               `json:"date"`
+	Subjects []examSubjectRequest `json:"subjects"`
+}
+
+// examSubjectRequest names a catalogue subject, by name or alias, and the
+// marks it is out of in the exam.
+type examSubjectRequest struct {
+	Subject  string  `json:"subject"`
+	MaxScore float32 `json:"max_score"`
 }
 
 func (h ExamHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -35,27 +52,336 @@ func (h ExamHandler) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusForbidden, "insufficient permissions")
 		return
 	}
-	if req.Date == "" {
-		writeError(w, http.StatusBadRequest, "date required")
+	exam, err := examFromRequest(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	exam.SchoolID = user.SchoolID
+	if len(req.Subjects) > 0 {
+		if exam.Subjects, err = h.examSubjects(r.Context(), user.SchoolID, exam.Class, req.Subjects); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
+	created, err := h.Store.CreateExam(r.Context(), exam)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create exam")
+		return
+	}
+	auditLog(r.Context(), "exam.created", user, map[string]interface{}{
+		"exam_id":  created.ID,
+		"class":    created.Class,
+		"title":    created.Title,
+		"subjects": len(created.Subjects),
+	})
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func examFromRequest(req createExamRequest) (models.Exam, error) {
+	exam := models.Exam{
+		Class: strings.TrimSpace(req.Class),
+		Title: strings.TrimSpace(req.Title),
+		Term:  strings.TrimSpace(req.Term),
+	}
+	if exam.Class == "" || exam.Title == "" {
+		return exam, errors.New("class and title required")
+	}
+	if req.Date == "" {
+		return exam, errors.New("date required")
+	}
 	examDate, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "invalid date")
+		return exam, errors.New("invalid date")
+	}
+	exam.Date = examDate
+	return exam, nil
+}
+
+// List returns the school's exams, filtered by ?class=, ?term= and ?status=.
+func (h ExamHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	query := r.URL.Query()
+	filter := store.ExamFilter{
+		Class:  strings.TrimSpace(query.Get("class")),
+		Term:   strings.TrimSpace(query.Get("term")),
+		Status: strings.TrimSpace(query.Get("status")),
+	}
+	if filter.Status != "" && !validExamStatus(filter.Status) {
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	items, err := h.Store.ListExams(r.Context(), user.SchoolID, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list exams")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h ExamHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
 		return
 	}
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, exam)
+}
 
-	exam, err := h.Store.CreateExam(r.Context(), models.Exam{
-		SchoolID: user.SchoolID,
-		Class:    req.Class,
-		Title:    req.Title,
-		Term:     req.Term,
-		Date:     examDate,
+// Update edits the exam's details. The class cannot change once scores have
+// been entered, and locked exams cannot be edited at all.
+func (h ExamHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	var req createExamRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	updated, err := examFromRequest(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if exam.Status == models.ExamLocked {
+		writeError(w, http.StatusConflict, "exam is locked")
+		return
+	}
+	if updated.Class != exam.Class && exam.ScoreCount > 0 {
+		writeError(w, http.StatusConflict, "class cannot change once scores are entered")
+		return
+	}
+	switch {
+	case req.Subjects != nil:
+		if updated.Subjects, err = h.examSubjects(r.Context(), user.SchoolID, updated.Class, req.Subjects); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	case updated.Class != exam.Class && len(exam.Subjects) > 0:
+		writeError(w, http.StatusBadRequest, "subjects required when the class changes")
+		return
+	}
+	exam.Class, exam.Title, exam.Term, exam.Date = updated.Class, updated.Title, updated.Term, updated.Date
+	save := *exam
+	save.Subjects = updated.Subjects
+	if err := h.Store.UpdateExam(r.Context(), save); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusConflict, "exam is locked")
+			return
+		}
+		if errors.Is(err, store.ErrSubjectHasScores) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update exam")
+		return
+	}
+	if save.Subjects != nil {
+		exam.Subjects = save.Subjects
+	}
+	auditLog(r.Context(), "exam.updated", user, map[string]interface{}{
+		"exam_id":  exam.ID,
+		"class":    exam.Class,
+		"title":    exam.Title,
+		"term":     exam.Term,
+		"date":     exam.Date.Format("2006-01-02"),
+		"subjects": len(exam.Subjects),
 	})
+	writeJSON(w, http.StatusOK, exam)
+}
+
+// examSubjects matches the subjects an exam declares to the class catalogue.
+func (h ExamHandler) examSubjects(ctx context.Context, schoolID, class string, items []examSubjectRequest) ([]models.ExamSubject, error) {
+	catalogue, err := h.Store.ListSubjects(ctx, schoolID, class)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create exam")
+		return nil, errors.New("failed to load subjects")
+	}
+	subjects := newSubjectCatalogue(catalogue)
+	out := make([]models.ExamSubject, 0, len(items))
+	seen := map[string]bool{}
+	for i, item := range items {
+		prefix := "subjects[" + strconv.Itoa(i) + "]: "
+		subject := subjects.match(item.Subject)
+		if subject == nil {
+			return nil, errors.New(prefix + subjects.unknown(strings.TrimSpace(item.Subject)))
+		}
+		if seen[subject.ID] {
+			return nil, errors.New(prefix + subject.Name + " is listed twice")
+		}
+		seen[subject.ID] = true
+		if item.MaxScore <= 0 {
+			return nil, errors.New(prefix + "max_score must be positive")
+		}
+		out = append(out, models.ExamSubject{SubjectID: subject.ID, Name: subject.Name, MaxScore: item.MaxScore})
+	}
+	return out, nil
+}
+
+// Delete removes an exam and its scores. Exams whose results were published
+// must be unpublished first, and locked exams cannot be deleted.
+func (h ExamHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-	writeJSON(w, http.StatusCreated, exam)
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if err := h.Store.DeleteExam(r.Context(), exam.ID, user.SchoolID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusConflict, "exam is "+exam.Status)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete exam")
+		return
+	}
+	auditLog(r.Context(), "exam.deleted", user, map[string]interface{}{
+		"exam_id": exam.ID,
+		"class":   exam.Class,
+		"title":   exam.Title,
+		"scores":  exam.ScoreCount,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// Publish makes the exam's scores visible to parents and tells them.
+func (h ExamHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, []string{models.ExamScoresEntered}, models.ExamResultsPublished)
+}
+
+// Unpublish hides published results again so they can be corrected.
+func (h ExamHandler) Unpublish(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, []string{models.ExamResultsPublished}, models.ExamScoresEntered)
+}
+
+// Lock freezes a published exam; no scores can be added or changed after.
+func (h ExamHandler) Lock(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, []string{models.ExamResultsPublished}, models.ExamLocked)
+}
+
+func (h ExamHandler) transition(w http.ResponseWriter, r *http.Request, from []string, status string) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if err := h.Store.SetExamStatus(r.Context(), exam.ID, user.SchoolID, from, status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusConflict, "exam is "+exam.Status)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update exam")
+		return
+	}
+
+	if status == models.ExamResultsPublished {
+		_ = h.Notifier.SendToClassGuardians(r.Context(), user.SchoolID, exam.Class, "Results published",
+			exam.Title+" results for class "+exam.Class+" are now available.", map[string]string{
+				"type":    "exam_results",
+				"exam_id": exam.ID,
+				"class":   exam.Class,
+			})
+	}
+	auditLog(r.Context(), "exam."+status, user, map[string]interface{}{
+		"exam_id": exam.ID,
+		"from":    exam.Status,
+		"to":      status,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+// examScoresEntered records the first scores of a draft exam and tells the
+// school's admins the results are ready to review and publish.
+func examScoresEntered(ctx context.Context, s *store.Store, notifier notify.Sender, user *models.User, exam *models.Exam) {
+	entered, err := s.MarkExamScoresEntered(ctx, exam.ID)
+	if err != nil || !entered {
+		return
+	}
+	auditLog(ctx, "exam."+models.ExamScoresEntered, user, map[string]interface{}{
+		"exam_id": exam.ID,
+		"from":    models.ExamDraft,
+		"to":      models.ExamScoresEntered,
+	})
+	adminIDs, err := s.ListUserIDsByRole(ctx, exam.SchoolID, models.RoleAdmin)
+	if err != nil {
+		return
+	}
+	for _, adminID := range adminIDs {
+		_ = notifier.SendToUser(ctx, adminID, "Scores ready to publish",
+			exam.Title+" scores for class "+exam.Class+" have been entered.", map[string]string{
+				"type":    "exam_scores_entered",
+				"exam_id": exam.ID,
+			})
+	}
+}
+
+func loadSchoolExam(w http.ResponseWriter, r *http.Request, s *store.Store, user *models.User, examID string) (*models.Exam, bool) {
+	if examID == "" {
+		writeError(w, http.StatusBadRequest, "missing exam id")
+		return nil, false
+	}
+	if _, err := uuid.Parse(examID); err != nil {
+		writeError(w, http.StatusNotFound, "exam not found")
+		return nil, false
+	}
+	exam, err := s.GetExam(r.Context(), examID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load exam")
+		return nil, false
+	}
+	if exam == nil || exam.SchoolID != user.SchoolID {
+		writeError(w, http.StatusNotFound, "exam not found")
+		return nil, false
+	}
+	return exam, true
+}
+
+func validExamStatus(status string) bool {
+	switch status {
+	case models.ExamDraft, models.ExamScoresEntered, models.ExamResultsPublished, models.ExamLocked:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+type FamiliesHandler struct {
+	Store *store.Store
+}
+
+type familyMembersRequest struct {
+	StudentIDs []string `json:"student_ids"`
+}
+
+type dismissFamilyRequest struct {
+	PhoneKey string `json:"phone_key"`
+}
+
+const (
+	maxFamilySize    = 10
+	maxFamilyResults = 500
+)
+
+// Suggestions lists groups of active students who share a parent phone and
+// are not yet confirmed as one family.
+func (h FamiliesHandler) Suggestions(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	items, err := h.Store.ListFamilySuggestions(r.Context(), user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list suggestions")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h FamiliesHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req dismissFamilyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	key := phoneKey(req.PhoneKey)
+	if len(key) != 10 {
+		writeError(w, http.StatusBadRequest, "phone_key must be a 10-digit phone number")
+		return
+	}
+	if err := h.Store.DismissFamilySuggestion(r.Context(), user.SchoolID, key, user.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to dismiss suggestion")
+		return
+	}
+	auditLog(r.Context(), "family.suggestion_dismissed", user, map[string]interface{}{
+		"phone_key": key,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "dismissed"})
+}
+
+// Create confirms a family. Students sharing one parent phone are keyed by
+// it, so later siblings with that phone are suggested for the same family;
+// admins may also group siblings registered under different phones.
+func (h FamiliesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req familyMembersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	if len(req.StudentIDs) < 2 {
+		writeError(w, http.StatusBadRequest, "a family needs at least two students")
+		return
+	}
+	students, status, err := h.loadNewMembers(r, user, req.StudentIDs)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	if len(students) < 2 {
+		writeError(w, http.StatusBadRequest, "a family needs at least two students")
+		return
+	}
+
+	key := phoneKey(students[0].ParentPhone)
+	for _, student := range students[1:] {
+		if phoneKey(student.ParentPhone) != key {
+			key = ""
+			break
+		}
+	}
+	if len(key) == 10 {
+		existing, err := h.Store.FindFamilyByPhone(r.Context(), user.SchoolID, key)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to check families")
+			return
+		}
+		if existing != "" {
+			writeError(w, http.StatusConflict, "a family with this parent phone already exists; add the students to it")
+			return
+		}
+	} else {
+		key = ""
+	}
+
+	familyID, err := h.Store.CreateFamily(r.Context(), user.SchoolID, key, user.ID, studentIDsOf(students))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create family")
+		return
+	}
+	family, err := h.Store.GetFamily(r.Context(), familyID)
+	if err != nil || family == nil {
+		writeError(w, http.StatusInternalServerError, "failed to load family")
+		return
+	}
+	auditLog(r.Context(), "family.confirmed", user, map[string]interface{}{
+		"family_id":    familyID,
+		"student_ids":  studentIDsOf(students),
+		"shared_phone": key != "",
+	})
+	writeJSON(w, http.StatusCreated, family)
+}
+
+func (h FamiliesHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	items, err := h.Store.ListFamilies(r.Context(), user.SchoolID, maxFamilyResults)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list families")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Get is the family-level view for staff: every sibling together with the
+// parent accounts linked to any of them.
+func (h FamiliesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	family, ok := h.loadFamily(w, r, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	h.writeFamilyDetail(w, r, family)
+}
+
+func (h FamiliesHandler) ForStudent(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	ids, err := h.Store.FamilyIDsByStudent(r.Context(), []string{student.ID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load family")
+		return
+	}
+	if ids[student.ID] == "" {
+		writeError(w, http.StatusNotFound, "student is not in a family")
+		return
+	}
+	family, ok := h.loadFamily(w, r, user, ids[student.ID])
+	if !ok {
+		return
+	}
+	h.writeFamilyDetail(w, r, family)
+}
+
+func (h FamiliesHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	family, ok := h.loadFamily(w, r, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	var req familyMembersRequest
+	if err := decodeJSON(r, &req); err != nil || len(req.StudentIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "student_ids is required")
+		return
+	}
+	if len(family.Members)+len(req.StudentIDs) > maxFamilySize {
+		writeError(w, http.StatusBadRequest, "family is too large")
+		return
+	}
+	students, status, err := h.loadNewMembers(r, user, req.StudentIDs)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	if err := h.Store.AddFamilyMembers(r.Context(), family.ID, user.ID, studentIDsOf(students)); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to add students")
+		return
+	}
+	auditLog(r.Context(), "family.members_added", user, map[string]interface{}{
+		"family_id":   family.ID,
+		"student_ids": studentIDsOf(students),
+	})
+	family, ok = h.loadFamily(w, r, user, family.ID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, family)
+}
+
+func (h FamiliesHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	family, ok := h.loadFamily(w, r, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	studentID := r.PathValue("studentID")
+	dissolved, err := h.Store.RemoveFamilyMember(r.Context(), family.ID, studentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "student is not in this family")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to remove student")
+		return
+	}
+	auditLog(r.Context(), "family.member_removed", user, map[string]interface{}{
+		"family_id":  family.ID,
+		"student_id": studentID,
+		"dissolved":  dissolved,
+	})
+	status := "removed"
+	if dissolved {
+		status = "dissolved"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+func (h FamiliesHandler) loadFamily(w http.ResponseWriter, r *http.Request, user *models.User, familyID string) (*models.Family, bool) {
+	if _, err := uuid.Parse(familyID); err != nil {
+		writeError(w, http.StatusNotFound, "family not found")
+		return nil, false
+	}
+	family, err := h.Store.GetFamily(r.Context(), familyID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load family")
+		return nil, false
+	}
+	if family == nil || family.SchoolID != user.SchoolID {
+		writeError(w, http.StatusNotFound, "family not found")
+		return nil, false
+	}
+	return family, true
+}
+
+func (h FamiliesHandler) writeFamilyDetail(w http.ResponseWriter, r *http.Request, family *models.Family) {
+	parents, err := h.Store.ListFamilyParents(r.Context(), family.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load linked parents")
+		return
+	}
+	family.Parents = parents
+	writeJSON(w, http.StatusOK, family)
+}
+
+// loadNewMembers resolves students to add to a family. Each must be an
+// active student of the admin's school and not already in a family.
+func (h FamiliesHandler) loadNewMembers(r *http.Request, user *models.User, studentIDs []string) ([]models.Student, int, error) {
+	if len(studentIDs) > maxFamilySize {
+		return nil, http.StatusBadRequest, &validationError{message: "family is too large"}
+	}
+	seen := map[string]bool{}
+	students := make([]models.Student, 0, len(studentIDs))
+	for _, raw := range studentIDs {
+		id := strings.TrimSpace(raw)
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, http.StatusBadRequest, &validationError{message: "student_ids must be valid UUIDs"}
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		student, err := h.Store.GetStudent(r.Context(), id)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.New("failed to load student")
+		}
+		if student == nil || student.SchoolID != user.SchoolID || student.Status != models.StudentStatusActive {
+			return nil, http.StatusBadRequest, &validationError{message: "student " + id + " is not an active student of this school"}
+		}
+		students = append(students, *student)
+	}
+
+	existing, err := h.Store.FamilyIDsByStudent(r.Context(), studentIDsOf(students))
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("failed to check families")
+	}
+	for _, student := range students {
+		if existing[student.ID] != "" {
+			return nil, http.StatusConflict, &validationError{message: student.FullName + " is already in a family"}
+		}
+	}
+	return students, http.StatusOK, nil
+}
+
+func studentIDsOf(students []models.Student) []string {
+	ids := make([]string, 0, len(students))
+	for _, student := range students {
+		ids = append(ids, student.ID)
+	}
+	return ids
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/storage"
+)
+
+type FilesHandler struct {
+	Files storage.Bucket
+}
+
+type signFileRequest struct {
+	Key string `json:"key"`
+}
+
+const signedURLExpiry = 15 * time.Minute
+
+var spreadsheetUploadPolicy = storage.Policy{
+	MaxBytes:     10 << 20,
+	AllowedTypes: []string{storage.ContentTypeCSV, storage.ContentTypeXLSX},
+}
+
+// readUpload reads a multipart file fully and checks it against the policy.
+// The returned status is the HTTP status to use when err is not nil.
+func readUpload(file multipart.File, header *multipart.FileHeader, policy storage.Policy) ([]byte, string, int, error) {
+	data, err := io.ReadAll(io.LimitReader(file, policy.MaxBytes+1))
+	if err != nil {
+		return nil, "", http.StatusBadRequest, errors.New("failed to read file")
+	}
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	contentType := storage.DetectContentType(header.Filename, head)
+	if err := policy.Check(contentType, int64(len(data))); err != nil {
+		if errors.Is(err, storage.ErrTooLarge) {
+			return nil, "", http.StatusRequestEntityTooLarge, errors.New("file exceeds " + strconv.FormatInt(policy.MaxBytes>>20, 10) + " MB limit")
+		}
+		return nil, "", http.StatusUnsupportedMediaType, errors.New("file content does not match an allowed type")
+	}
+	return data, contentType, 0, nil
+}
+
+// archiveUpload keeps the original uploaded file so that audit entries can
+// point at exactly what was imported. It is a no-op without a bucket.
+func archiveUpload(ctx context.Context, files storage.Bucket, user *models.User, kind, filename, contentType string, data []byte) (*storage.Object, error) {
+	if files == nil {
+		return nil, nil
+	}
+	key := storage.UploadKey(user.SchoolID, kind, uuid.NewString(), filename, time.Now())
+	return files.Put(ctx, key, bytes.NewReader(data), contentType)
+}
+
+func uploadAuditFields(obj *storage.Object, filename string) map[string]interface{} {
+	fields := map[string]interface{}{
+		"file_name": filename,
+	}
+	if obj != nil {
+		fields["upload_key"] = obj.Key
+		fields["upload_sha256"] = obj.SHA256
+		fields["upload_size"] = obj.Size
+	}
+	return fields
+}
+
+func (h FilesHandler) Download(w http.ResponseWriter, r *http.Request) {
+	verifier, ok := h.Files.(storage.SignedURLVerifier)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	key, err := storage.CleanKey(r.PathValue("key"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid key")
+		return
+	}
+	if err := verifier.VerifySignedURL(key, r.URL.Query()); err != nil {
+		writeError(w, http.StatusForbidden, "invalid or expired link")
+		return
+	}
+	body, obj, err := h.Files.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "file not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to read file")
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", obj.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
+
+func (h FilesHandler) Sign(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	if h.Files == nil {
+		writeError(w, http.StatusServiceUnavailable, "file storage not configured")
+		return
+	}
+	var req signFileRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	key, err := storage.CleanKey(req.Key)
+	if err != nil || user.SchoolID == "" || !strings.HasPrefix(key, "uploads/"+user.SchoolID+"/") {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	url, err := h.Files.SignedURL(r.Context(), key, signedURLExpiry)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to sign url")
+		return
+	}
+	auditLog(r.Context(), "file.url_signed", user, map[string]interface{}{
+		"key": key,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"url":        url,
+		"expires_at": time.Now().Add(signedURLExpiry).UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+const regradeReason = "grading scheme changed"
+
+type GradingSchemesHandler struct {
+	Store *store.Store
+}
+
+type gradingSchemeRequest struct {
+	Class  string             `json:"class"`
+	Name   string             `json:"name"`
+	Preset string             `json:"preset"`
+	Bands  []models.GradeBand `json:"bands"`
+}
+
+type gradingSchemeResponse struct {
+	Scheme   *models.GradingScheme `json:"scheme"`
+	Regraded int                   `json:"regraded"`
+}
+
+type gradingPreset struct {
+	Key   string             `json:"key"`
+	Bands []models.GradeBand `json:"bands"`
+}
+
+// List returns the school's schemes; the one with an empty class is the
+// school default used by classes without their own.
+func (h GradingSchemesHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	items, err := h.Store.ListGradingSchemes(r.Context(), user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list grading schemes")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h GradingSchemesHandler) Presets(w http.ResponseWriter, r *http.Request) {
+	if httpctx.UserFromContext(r.Context()) == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	items := make([]gradingPreset, 0, len(grading.Presets))
+	for key, bands := range grading.Presets {
+		items = append(items, gradingPreset{Key: key, Bands: bands})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Upsert sets the scheme of a class, or the school default when class is
+// empty, from a preset or custom bands. Grades of existing scores the scheme
+// covers are recomputed, except in locked exams.
+func (h GradingSchemesHandler) Upsert(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req gradingSchemeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	scheme := models.GradingScheme{
+		SchoolID:  user.SchoolID,
+		Class:     strings.TrimSpace(req.Class),
+		Name:      strings.TrimSpace(req.Name),
+		Bands:     req.Bands,
+		UpdatedBy: user.ID,
+	}
+	if preset := strings.TrimSpace(req.Preset); preset != "" {
+		bands, ok := grading.Presets[preset]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "unknown preset")
+			return
+		}
+		if len(req.Bands) > 0 {
+			writeError(w, http.StatusBadRequest, "give either preset or bands")
+			return
+		}
+		scheme.Bands = bands
+		if scheme.Name == "" {
+			scheme.Name = preset
+		}
+	}
+	if scheme.Name == "" {
+		writeError(w, http.StatusBadRequest, "name required")
+		return
+	}
+	bands, err := grading.Normalize(scheme.Bands)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	scheme.Bands = bands
+
+	saved, err := h.Store.SaveGradingScheme(r.Context(), scheme)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save grading scheme")
+		return
+	}
+	auditLog(r.Context(), "grading_scheme.updated", user, map[string]interface{}{
+		"scheme_id": saved.ID,
+		"class":     saved.Class,
+		"name":      saved.Name,
+		"bands":     saved.Bands,
+	})
+	regraded, ok := h.regrade(w, r, user, saved.Class, saved.Bands)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, gradingSchemeResponse{Scheme: saved, Regraded: regraded})
+}
+
+// Delete removes a scheme. Scores of a class that loses its own scheme are
+// regraded with the school default if there is one; otherwise grades are
+// left as they are.
+func (h GradingSchemesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "grading scheme not found")
+		return
+	}
+	scheme, err := h.Store.GetGradingScheme(r.Context(), id, user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load grading scheme")
+		return
+	}
+	if scheme == nil {
+		writeError(w, http.StatusNotFound, "grading scheme not found")
+		return
+	}
+	if err := h.Store.DeleteGradingScheme(r.Context(), scheme.ID, user.SchoolID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "grading scheme not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete grading scheme")
+		return
+	}
+	auditLog(r.Context(), "grading_scheme.deleted", user, map[string]interface{}{
+		"scheme_id": scheme.ID,
+		"class":     scheme.Class,
+		"name":      scheme.Name,
+	})
+
+	regraded := 0
+	if scheme.Class != "" {
+		fallback, err := h.Store.GradingSchemeForClass(r.Context(), user.SchoolID, scheme.Class)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load grading scheme")
+			return
+		}
+		if fallback != nil {
+			var ok bool
+			if regraded, ok = h.regrade(w, r, user, scheme.Class, fallback.Bands); !ok {
+				return
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "deleted", "regraded": regraded})
+}
+
+func (h GradingSchemesHandler) regrade(w http.ResponseWriter, r *http.Request, user *models.User, class string, bands []models.GradeBand) (int, bool) {
+	regraded, err := h.Store.RegradeScores(r.Context(), user.SchoolID, class, bands, store.ScoreWrite{
+		ChangedBy: user.ID,
+		Reason:    regradeReason,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to regrade scores")
+		return 0, false
+	}
+	if regraded > 0 {
+		auditLog(r.Context(), "scores.regraded", user, map[string]interface{}{
+			"class":  class,
+			"scores": regraded,
+		})
+	}
+	return regraded, true
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+type GuardiansHandler struct {
+	Store *store.Store
+}
+
+type guardianRequest struct {
+	FullName     string `json:"full_name"`
+	Relationship string `json:"relationship"`
+	Phone        string `json:"phone"`
+	Email        string `json:"email"`
+	IsPrimary    bool   `json:"is_primary"`
+	CanPickUp    bool   `json:"can_pick_up"`
+}
+
+var guardianRelationships = map[string]bool{
+	"parent":      true,
+	"father":      true,
+	"mother":      true,
+	"guardian":    true,
+	"grandparent": true,
+	"sibling":     true,
+	"relative":    true,
+	"other":       true,
+}
+
+// guardianUploadSlots are the column prefixes recognised by the student
+// upload, e.g. father_name, mother_phone, guardian_can_pick_up.
+var guardianUploadSlots = []string{"father", "mother", "guardian"}
+
+func (req guardianRequest) toGuardian() (models.Guardian, error) {
+	relationship := strings.ToLower(strings.TrimSpace(req.Relationship))
+	if !guardianRelationships[relationship] {
+		return models.Guardian{}, &validationError{message: "relationship must be one of parent, father, mother, guardian, grandparent, sibling, relative, other"}
+	}
+	phone, err := normalizePhone(req.Phone, "phone")
+	if err != nil {
+		return models.Guardian{}, err
+	}
+	fullName := strings.TrimSpace(req.FullName)
+	if fullName == "" && phone == "" {
+		return models.Guardian{}, &validationError{message: "full_name or phone is required"}
+	}
+	return models.Guardian{
+		FullName:     fullName,
+		Relationship: relationship,
+		Phone:        phone,
+		Email:        strings.ToLower(strings.TrimSpace(req.Email)),
+		IsPrimary:    req.IsPrimary,
+		CanPickUp:    req.CanPickUp,
+	}, nil
+}
+
+// assignPrimaryGuardian makes sure exactly one guardian is the primary
+// contact and returns the phone to keep in students.parent_phone. A bare
+// parent_phone with no guardians becomes a primary "parent" guardian.
+func assignPrimaryGuardian(guardians []models.Guardian, parentPhone string) ([]models.Guardian, string) {
+	if len(guardians) == 0 {
+		if parentPhone == "" {
+			return nil, ""
+		}
+		return []models.Guardian{{
+			Relationship: "parent",
+			Phone:        parentPhone,
+			IsPrimary:    true,
+			CanPickUp:    true,
+		}}, parentPhone
+	}
+
+	primary := -1
+	for i := range guardians {
+		if guardians[i].IsPrimary && primary < 0 {
+			primary = i
+		}
+		guardians[i].IsPrimary = false
+	}
+	if primary < 0 && parentPhone != "" {
+		for i := range guardians {
+			if guardians[i].Phone != "" && phoneKey(guardians[i].Phone) == phoneKey(parentPhone) {
+				primary = i
+				break
+			}
+		}
+		if primary < 0 {
+			guardians = append(guardians, models.Guardian{
+				Relationship: "parent",
+				Phone:        parentPhone,
+				CanPickUp:    true,
+			})
+			primary = len(guardians) - 1
+		}
+	}
+	if primary < 0 {
+		primary = 0
+		for i := range guardians {
+			if guardians[i].Phone != "" {
+				primary = i
+				break
+			}
+		}
+	}
+	guardians[primary].IsPrimary = true
+	if guardians[primary].Phone != "" {
+		parentPhone = guardians[primary].Phone
+	}
+	return guardians, parentPhone
+}
+
+func isGuardianColumn(key string) bool {
+	if key == "guardian_relationship" || key == "primary_guardian" {
+		return true
+	}
+	for _, slot := range guardianUploadSlots {
+		switch key {
+		case slot + "_name", slot + "_phone", slot + "_email", slot + "_can_pick_up":
+			return true
+		}
+	}
+	return false
+}
+
+func guardiansFromRow(row []string, idx map[string]int) ([]models.Guardian, error) {
+	primarySlot := strings.ToLower(getStudentCell(row, idx, "primary_guardian"))
+	var guardians []models.Guardian
+	for _, slot := range guardianUploadSlots {
+		name := getStudentCell(row, idx, slot+"_name")
+		phoneRaw := getStudentCell(row, idx, slot+"_phone")
+		email := getStudentCell(row, idx, slot+"_email")
+		if name == "" && phoneRaw == "" && email == "" {
+			continue
+		}
+		phone, err := normalizePhone(phoneRaw, slot+"_phone")
+		if err != nil {
+			return nil, err
+		}
+		relationship := slot
+		if slot == "guardian" {
+			if custom := strings.ToLower(getStudentCell(row, idx, "guardian_relationship")); custom != "" {
+				if !guardianRelationships[custom] {
+					return nil, &validationError{message: "invalid guardian_relationship"}
+				}
+				relationship = custom
+			}
+		}
+		guardians = append(guardians, models.Guardian{
+			FullName:     name,
+			Relationship: relationship,
+			Phone:        phone,
+			Email:        strings.ToLower(email),
+			IsPrimary:    primarySlot == slot,
+			CanPickUp:    parseBoolCell(getStudentCell(row, idx, slot+"_can_pick_up")),
+		})
+	}
+	return guardians, nil
+}
+
+func parseBoolCell(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "y", "yes", "true":
+		return true
+	default:
+		return false
+	}
+}
+
+func (h GuardiansHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := authorizeStudentView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	items, err := h.Store.ListGuardiansByStudent(r.Context(), student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list guardians")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h GuardiansHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	var req guardianRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	guardian, err := req.toGuardian()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	guardian.StudentID = student.ID
+
+	existing, err := h.Store.ListGuardiansByStudent(r.Context(), student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load guardians")
+		return
+	}
+	if len(existing) == 0 {
+		guardian.IsPrimary = true
+	}
+
+	created, err := h.Store.CreateGuardian(r.Context(), guardian)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create guardian")
+		return
+	}
+	auditLog(r.Context(), "guardian.created", user, map[string]interface{}{
+		"student_id":   student.ID,
+		"guardian_id":  created.ID,
+		"relationship": created.Relationship,
+		"is_primary":   created.IsPrimary,
+	})
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h GuardiansHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	guardianID := r.PathValue("guardianID")
+	if guardianID == "" {
+		writeError(w, http.StatusBadRequest, "missing guardian id")
+		return
+	}
+
+	var req guardianRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	guardian, err := req.toGuardian()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	current, err := h.Store.GetGuardian(r.Context(), guardianID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load guardian")
+		return
+	}
+	if current == nil || current.StudentID != student.ID {
+		writeError(w, http.StatusNotFound, "guardian not found")
+		return
+	}
+	if current.IsPrimary && !guardian.IsPrimary {
+		writeError(w, http.StatusBadRequest, "mark another guardian as primary instead")
+		return
+	}
+	guardian.ID = current.ID
+	guardian.StudentID = student.ID
+	guardian.CreatedAt = current.CreatedAt
+
+	updated, err := h.Store.UpdateGuardian(r.Context(), guardian)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "guardian not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update guardian")
+		return
+	}
+	auditLog(r.Context(), "guardian.updated", user, map[string]interface{}{
+		"student_id":   student.ID,
+		"guardian_id":  updated.ID,
+		"relationship": updated.Relationship,
+		"is_primary":   updated.IsPrimary,
+	})
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h GuardiansHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	guardianID := r.PathValue("guardianID")
+	if guardianID == "" {
+		writeError(w, http.StatusBadRequest, "missing guardian id")
+		return
+	}
+
+	if err := h.Store.DeleteGuardian(r.Context(), guardianID, student.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "guardian not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete guardian")
+		return
+	}
+	auditLog(r.Context(), "guardian.deleted", user, map[string]interface{}{
+		"student_id":  student.ID,
+		"guardian_id": guardianID,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/idcard"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/pdf"
+	"jnv/backend/internal/storage"
+	"jnv/backend/internal/store"
+)
+
+type IDCardsHandler struct {
+	Store         *store.Store
+	Files         storage.Bucket
+	Signer        *idcard.Signer
+	PublicBaseURL string
+}
+
+type idCardVerification struct {
+	Valid       bool   `json:"valid"`
+	Reason      string `json:"reason,omitempty"`
+	StudentName string `json:"student_name"`
+	ClassLabel  string `json:"class_label"`
+	House       string `json:"house"`
+	SchoolName  string `json:"school_name"`
+	ValidUntil  string `json:"valid_until"`
+}
+
+// maxClassCards bounds a class print run, which is built inside one request.
+// A JNV class is at most a few sections of 40.
+const maxClassCards = 200
+
+// photoFetchers is how many photos a class print reads from storage at once.
+const photoFetchers = 8
+
+func (h IDCardsHandler) Student(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	validUntil, ok := parseValidUntil(w, r)
+	if !ok {
+		return
+	}
+	school, err := h.Store.GetSchool(r.Context(), user.SchoolID)
+	if err != nil || school == nil {
+		writeError(w, http.StatusInternalServerError, "failed to load school")
+		return
+	}
+
+	doc := pdf.New()
+	card, err := h.buildCard(r.Context(), doc, school, *student, validUntil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to prepare id card")
+		return
+	}
+	if err := idcard.RenderSingle(doc, card); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render id card")
+		return
+	}
+
+	auditLog(r.Context(), "id_card.generated", user, map[string]interface{}{
+		"student_id":  student.ID,
+		"valid_until": validUntil.Format("2006-01-02"),
+	})
+	writePDF(w, doc, "id-card-"+student.ID+".pdf")
+}
+
+func (h IDCardsHandler) Class(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	classLabel := strings.TrimSpace(r.PathValue("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "missing class")
+		return
+	}
+	validUntil, ok := parseValidUntil(w, r)
+	if !ok {
+		return
+	}
+	school, err := h.Store.GetSchool(r.Context(), user.SchoolID)
+	if err != nil || school == nil {
+		writeError(w, http.StatusInternalServerError, "failed to load school")
+		return
+	}
+	students, err := h.Store.ListStudentsBySchool(r.Context(), user.SchoolID, classLabel, maxClassCards+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list students")
+		return
+	}
+	if len(students) == 0 {
+		writeError(w, http.StatusNotFound, "no students in class")
+		return
+	}
+	if len(students) > maxClassCards {
+		writeError(w, http.StatusBadRequest, "class has more than "+strconv.Itoa(maxClassCards)+" students")
+		return
+	}
+
+	doc := pdf.New()
+	cards, err := h.buildClassCards(r.Context(), doc, school, students, validUntil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to prepare id cards")
+		return
+	}
+	if err := idcard.RenderSheet(doc, cards); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render id cards")
+		return
+	}
+
+	auditLog(r.Context(), "id_card.batch_generated", user, map[string]interface{}{
+		"class":       classLabel,
+		"count":       len(cards),
+		"valid_until": validUntil.Format("2006-01-02"),
+	})
+	writePDF(w, doc, "id-cards-"+sanitizeFileName(classLabel)+".pdf")
+}
+
+// Verify is public: anyone scanning a card learns whether it was issued by
+// the school and is still valid, and sees only what is printed on the card.
+// Cards of students who have left, as alumni or archived, are inactive.
+func (h IDCardsHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	studentID, validUntil, err := h.Signer.Verify(r.PathValue("token"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "card not recognised")
+		return
+	}
+	student, err := h.Store.GetStudent(r.Context(), studentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to verify card")
+		return
+	}
+	if student == nil {
+		writeError(w, http.StatusNotFound, "card not recognised")
+		return
+	}
+	school, err := h.Store.GetSchool(r.Context(), student.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to verify card")
+		return
+	}
+
+	resp := idCardVerification{
+		Valid:       true,
+		StudentName: student.FullName,
+		ClassLabel:  student.ClassLabel,
+		House:       student.House,
+		ValidUntil:  validUntil.Format("2006-01-02"),
+	}
+	if school != nil {
+		resp.SchoolName = school.Name
+	}
+	switch {
+	case student.Status != models.StudentStatusActive:
+		resp.Valid = false
+		resp.Reason = "inactive"
+	case time.Now().After(validUntil.AddDate(0, 0, 1)):
+		resp.Valid = false
+		resp.Reason = "expired"
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h IDCardsHandler) buildCard(ctx context.Context, doc *pdf.Document, school *models.School, student models.Student, validUntil time.Time) (idcard.Card, error) {
+	guardianPhone := student.ParentPhone
+	guardians, err := h.Store.ListGuardiansByStudent(ctx, student.ID)
+	if err != nil {
+		return idcard.Card{}, err
+	}
+	for _, guardian := range guardians {
+		if guardian.IsPrimary && guardian.Phone != "" {
+			guardianPhone = guardian.Phone
+			break
+		}
+	}
+	var photo *pdf.Image
+	if h.Files != nil {
+		latest, err := h.Store.LatestStudentDocument(ctx, student.ID, "photo")
+		if err != nil {
+			return idcard.Card{}, err
+		}
+		if latest != nil {
+			data, err := h.readPhoto(ctx, *latest)
+			if err != nil {
+				return idcard.Card{}, err
+			}
+			photo = embedPhoto(doc, latest.ContentType, data)
+		}
+	}
+	return h.card(school, student, validUntil, guardianPhone, photo)
+}
+
+// buildClassCards builds the cards of a class with one query for the
+// guardians and one for the photo documents, reading the photos from
+// storage photoFetchers at a time.
+func (h IDCardsHandler) buildClassCards(ctx context.Context, doc *pdf.Document, school *models.School, students []models.Student, validUntil time.Time) ([]idcard.Card, error) {
+	ids := make([]string, len(students))
+	for i, student := range students {
+		ids[i] = student.ID
+	}
+	phones, err := h.Store.PrimaryGuardianPhones(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	photos := make([][]byte, len(students))
+	var photoDocs map[string]models.StudentDocument
+	if h.Files != nil {
+		photoDocs, err = h.Store.LatestStudentDocuments(ctx, ids, "photo")
+		if err != nil {
+			return nil, err
+		}
+		errs := make([]error, len(students))
+		slots := make(chan struct{}, photoFetchers)
+		var wg sync.WaitGroup
+		for i, student := range students {
+			latest, ok := photoDocs[student.ID]
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			slots <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				photos[i], errs[i] = h.readPhoto(ctx, latest)
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+	}
+
+	cards := make([]idcard.Card, 0, len(students))
+	for i, student := range students {
+		guardianPhone := student.ParentPhone
+		if phone, ok := phones[student.ID]; ok {
+			guardianPhone = phone
+		}
+		var photo *pdf.Image
+		if photos[i] != nil {
+			photo = embedPhoto(doc, photoDocs[student.ID].ContentType, photos[i])
+		}
+		card, err := h.card(school, student, validUntil, guardianPhone, photo)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+func (h IDCardsHandler) card(school *models.School, student models.Student, validUntil time.Time, guardianPhone string, photo *pdf.Image) (idcard.Card, error) {
+	token, err := h.Signer.Sign(student.ID, validUntil)
+	if err != nil {
+		return idcard.Card{}, err
+	}
+	return idcard.Card{
+		SchoolName:    school.Name,
+		StudentName:   student.FullName,
+		ClassLabel:    student.ClassLabel,
+		RollNumber:    student.RollNumber,
+		House:         student.House,
+		DateOfBirth:   student.DateOfBirth,
+		GuardianPhone: guardianPhone,
+		Photo:         photo,
+		VerifyURL:     strings.TrimRight(h.PublicBaseURL, "/") + "/api/v1/verify/" + token,
+		ValidUntil:    validUntil,
+	}, nil
+}
+
+// readPhoto reads a photo document from storage. A photo missing from
+// storage reads as nil and leaves the placeholder on the card rather than
+// failing the whole print run.
+func (h IDCardsHandler) readPhoto(ctx context.Context, photo models.StudentDocument) ([]byte, error) {
+	body, _, err := h.Files.Get(ctx, photo.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(io.LimitReader(body, photoUploadPolicy.MaxBytes+1))
+}
+
+// embedPhoto adds a photo to the document. Unreadable images are left out,
+// like missing ones.
+func embedPhoto(doc *pdf.Document, contentType string, data []byte) *pdf.Image {
+	if data == nil {
+		return nil
+	}
+	switch contentType {
+	case storage.ContentTypeJPEG:
+		img, err := doc.AddJPEG(data)
+		if err != nil {
+			return nil
+		}
+		return img
+	case storage.ContentTypePNG:
+		decoded, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		return doc.AddImage(decoded)
+	}
+	return nil
+}
+
+func parseValidUntil(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	raw := strings.TrimSpace(r.URL.Query().Get("valid_until"))
+	if raw == "" {
+		return idcard.DefaultValidUntil(time.Now()), true
+	}
+	validUntil, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "valid_until must be YYYY-MM-DD")
+		return time.Time{}, false
+	}
+	if validUntil.Before(time.Now().Truncate(24 * time.Hour)) {
+		writeError(w, http.StatusBadRequest, "valid_until is in the past")
+		return time.Time{}, false
+	}
+	return validUntil, true
+}
+
+func writePDF(w http.ResponseWriter, doc *pdf.Document, filename string) {
+	data, err := doc.Bytes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render pdf")
+		return
+	}
+	w.Header().Set("Content-Type", storage.ContentTypePDF)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+func sanitizeFileName(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
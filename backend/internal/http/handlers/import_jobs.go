@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// ImportJobsHandler lets uploaders follow and cancel their queued imports.
+// Staff see their own jobs; admins see every job of their school.
+type ImportJobsHandler struct {
+	Store *store.Store
+}
+
+func (h ImportJobsHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+	createdBy := user.ID
+	if hasRole(user, models.RoleAdmin) {
+		createdBy = ""
+	}
+	items, err := h.Store.ListImportJobs(r.Context(), user.SchoolID, createdBy, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list jobs")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h ImportJobsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	job, ok := h.loadJob(w, r, user)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// Cancel stops a job. A queued job is cancelled at once; a running one
+// stops at its worker's next heartbeat, and its import is rolled back.
+func (h ImportJobsHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	job, ok := h.loadJob(w, r, user)
+	if !ok {
+		return
+	}
+	cancelled, err := h.Store.CancelImportJob(r.Context(), job.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to cancel job")
+		return
+	}
+	if cancelled == nil {
+		writeError(w, http.StatusConflict, "job has already finished")
+		return
+	}
+	auditLog(r.Context(), "import_job.cancel_requested", user, map[string]interface{}{
+		"job_id": job.ID,
+		"kind":   job.Kind,
+		"status": cancelled.Status,
+	})
+	writeJSON(w, http.StatusOK, cancelled)
+}
+
+func (h ImportJobsHandler) loadJob(w http.ResponseWriter, r *http.Request, user *models.User) (*models.ImportJob, bool) {
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return nil, false
+	}
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return nil, false
+	}
+	job, err := h.Store.GetImportJob(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load job")
+		return nil, false
+	}
+	if job == nil || job.SchoolID != user.SchoolID || (!hasRole(user, models.RoleAdmin) && job.CreatedBy != user.ID) {
+		writeError(w, http.StatusNotFound, "job not found")
+		return nil, false
+	}
+	return job, true
+}
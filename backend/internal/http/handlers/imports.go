@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"jnv/backend/internal/jobs"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/notify"
+	"jnv/backend/internal/storage"
+	"jnv/backend/internal/store"
+	"jnv/backend/internal/xlsx"
+)
+
+// Import job kinds. Each kind is registered in ImportJobs.
+const (
+	ImportStudents = "students"
+	ImportScores   = "scores"
+)
+
+// importError stops a whole sheet, e.g. one that cannot be parsed. The
+// message is shown to the uploader, in the response or on the job.
+type importError struct {
+	status  int
+	message string
+}
+
+func (e *importError) Error() string {
+	return e.message
+}
+
+func writeImportError(w http.ResponseWriter, err error) {
+	var importErr *importError
+	if errors.As(err, &importErr) {
+		writeError(w, importErr.status, importErr.message)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "import failed; nothing was saved")
+}
+
+func noProgress(done, total int) {}
+
+// checkSheetType rejects files the importers cannot read before they are
+// queued.
+func checkSheetType(filename string) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".xlsx":
+		return nil
+	}
+	return &importError{http.StatusBadRequest, "supported file types: .csv, .xlsx"}
+}
+
+// sheetRows is one worksheet of an upload, header row first. A CSV file is a
+// single sheet without a name.
+type sheetRows struct {
+	name string
+	rows [][]string
+}
+
+// sheetSelection says which worksheets of a workbook to import. Without a
+// name or perClass, the prefer sheet is used if there is one, and otherwise
+// the first visible sheet.
+type sheetSelection struct {
+	name     string
+	perClass bool
+	prefer   string
+}
+
+// Upload form fields and job params that select sheets.
+const (
+	sheetParam         = "sheet"
+	sheetPerClassParam = "sheet_per_class"
+)
+
+// sheetParams copies the sheet selection fields of an upload form into job
+// params.
+func sheetParams(r *http.Request, params map[string]string) map[string]string {
+	if params == nil {
+		params = map[string]string{}
+	}
+	if name := strings.TrimSpace(r.FormValue(sheetParam)); name != "" {
+		params[sheetParam] = name
+	}
+	if perClass, _ := strconv.ParseBool(r.FormValue(sheetPerClassParam)); perClass {
+		params[sheetPerClassParam] = "true"
+	}
+	return params
+}
+
+func sheetSelectionFromParams(params map[string]string) sheetSelection {
+	perClass, _ := strconv.ParseBool(params[sheetPerClassParam])
+	return sheetSelection{name: params[sheetParam], perClass: perClass}
+}
+
+// readSheets parses an uploaded csv or xlsx file into the selected sheets.
+// Empty sheets are skipped when importing one sheet per class.
+func readSheets(filename string, data []byte, selection sheetSelection) ([]sheetRows, error) {
+	if err := checkSheetType(filename); err != nil {
+		return nil, err
+	}
+	empty := &importError{http.StatusBadRequest, "file is empty"}
+	if strings.ToLower(filepath.Ext(filename)) == ".csv" {
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, &importError{http.StatusBadRequest, "failed to parse csv file"}
+		}
+		if len(rows) == 0 {
+			return nil, empty
+		}
+		return []sheetRows{{rows: rows}}, nil
+	}
+
+	wb, err := xlsx.Open(data)
+	if err != nil {
+		return nil, &importError{http.StatusBadRequest, "failed to parse xlsx file"}
+	}
+	var picked []xlsx.Sheet
+	switch {
+	case selection.perClass:
+		picked = wb.Visible()
+	case selection.name != "":
+		sheet, err := wb.Sheet(selection.name)
+		if err != nil {
+			names := make([]string, 0, len(wb.Sheets))
+			for _, sheet := range wb.Visible() {
+				names = append(names, sheet.Name)
+			}
+			return nil, &importError{http.StatusBadRequest, fmt.Sprintf("sheet %q not found; the workbook has: %s",
+				selection.name, strings.Join(names, ", "))}
+		}
+		picked = []xlsx.Sheet{sheet}
+	default:
+		sheet, err := wb.Sheet(selection.prefer)
+		if selection.prefer == "" || err != nil {
+			sheet = wb.Sheets[0]
+			if visible := wb.Visible(); len(visible) > 0 {
+				sheet = visible[0]
+			}
+		}
+		picked = []xlsx.Sheet{sheet}
+	}
+
+	var sheets []sheetRows
+	for _, sheet := range picked {
+		rows, err := wb.Rows(sheet)
+		if err != nil {
+			return nil, &importError{http.StatusBadRequest, "failed to parse xlsx file"}
+		}
+		if len(rows) == 0 {
+			if selection.perClass {
+				continue
+			}
+			return nil, empty
+		}
+		sheets = append(sheets, sheetRows{name: strings.TrimSpace(sheet.Name), rows: rows})
+	}
+	if len(sheets) == 0 {
+		return nil, empty
+	}
+	return sheets, nil
+}
+
+// rowLabel names a row in error messages. Rows of a multi-sheet upload carry
+// their sheet's name.
+func rowLabel(sheet string, number int) string {
+	if sheet == "" {
+		return "row " + strconv.Itoa(number)
+	}
+	return fmt.Sprintf("%s row %d", sheet, number)
+}
+
+// queueImport records a job for an archived upload and answers 202 with it;
+// the client follows progress at GET /api/v1/jobs/{id}.
+func queueImport(ctx context.Context, w http.ResponseWriter, s *store.Store, user *models.User, kind string, archived *storage.Object, filename string, params map[string]string) {
+	job, err := s.CreateImportJob(ctx, models.ImportJob{
+		SchoolID:  user.SchoolID,
+		Kind:      kind,
+		Params:    params,
+		FileName:  filename,
+		UploadKey: archived.Key,
+		CreatedBy: user.ID,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to queue import")
+		return
+	}
+	fields := uploadAuditFields(archived, filename)
+	fields["job_id"] = job.ID
+	fields["kind"] = kind
+	auditLog(ctx, "import_job.queued", user, fields)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// ImportJobs returns the functions that run queued uploads, keyed by job
+// kind. Workers load the uploader and the archived file, then run the same
+// import the upload endpoint runs inline when there is no bucket.
+func ImportJobs(s *store.Store, notifier notify.Sender, files storage.Bucket) map[string]jobs.Func {
+	students := StudentsHandler{Store: s, Files: files}
+	scores := ScoresHandler{Store: s, Notifier: notifier, Files: files}
+	return map[string]jobs.Func{
+		ImportStudents: importJobFunc(func(ctx context.Context, job models.ImportJob, progress jobs.Progress) (jobs.Outcome, error) {
+			user, archived, data, err := loadImportJob(ctx, s, files, job)
+			if err != nil {
+				return jobs.Outcome{}, err
+			}
+			resp, err := students.importSheet(ctx, user, job.FileName, sheetSelectionFromParams(job.Params), archived, data, progress)
+			if err != nil {
+				return jobs.Outcome{}, err
+			}
+			return jobs.Outcome{Result: resp, Rejected: len(resp.Errors) > 0}, nil
+		}),
+		ImportScores: importJobFunc(func(ctx context.Context, job models.ImportJob, progress jobs.Progress) (jobs.Outcome, error) {
+			user, archived, data, err := loadImportJob(ctx, s, files, job)
+			if err != nil {
+				return jobs.Outcome{}, err
+			}
+			exam, err := s.GetExam(ctx, job.Params["exam_id"])
+			if err != nil {
+				return jobs.Outcome{}, err
+			}
+			if exam == nil || exam.SchoolID != job.SchoolID {
+				return jobs.Outcome{}, &importError{http.StatusNotFound, "exam not found"}
+			}
+			write, err := scoreWriteOptions(job.Params["mode"], job.Params["reason"], user)
+			if err != nil {
+				return jobs.Outcome{}, &importError{http.StatusBadRequest, err.Error()}
+			}
+			resp, err := scores.importSheet(ctx, user, exam, write, job.FileName, sheetSelectionFromParams(job.Params), archived, data, progress)
+			if err != nil {
+				return jobs.Outcome{}, err
+			}
+			return jobs.Outcome{Result: resp, Rejected: len(resp.Errors) > 0}, nil
+		}),
+	}
+}
+
+// importJobFunc keeps internal errors off the job: they are logged, and the
+// uploader sees a generic message.
+func importJobFunc(fn jobs.Func) jobs.Func {
+	return func(ctx context.Context, job models.ImportJob, progress jobs.Progress) (jobs.Outcome, error) {
+		outcome, err := fn(ctx, job, progress)
+		var importErr *importError
+		if err != nil && !errors.As(err, &importErr) {
+			log.Printf("import job %s: %v", job.ID, err)
+			err = errors.New("import failed; nothing was saved")
+		}
+		return outcome, err
+	}
+}
+
+func loadImportJob(ctx context.Context, s *store.Store, files storage.Bucket, job models.ImportJob) (*models.User, *storage.Object, []byte, error) {
+	user, err := s.GetUser(ctx, job.CreatedBy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if user == nil || user.SchoolID != job.SchoolID {
+		return nil, nil, nil, &importError{http.StatusForbidden, "uploader no longer belongs to this school"}
+	}
+	if files == nil {
+		return nil, nil, nil, errors.New("file storage is not configured")
+	}
+	body, archived, err := files.Get(ctx, job.UploadKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, nil, &importError{http.StatusGone, "uploaded file is no longer available"}
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return user, archived, data, nil
+}
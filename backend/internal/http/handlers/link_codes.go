@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/linkcode"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/pdf"
+	"jnv/backend/internal/store"
+)
+
+type LinkCodesHandler struct {
+	Store *store.Store
+}
+
+type linkCodeResponse struct {
+	StudentID  string    `json:"student_id"`
+	FullName   string    `json:"full_name"`
+	ClassLabel string    `json:"class_label"`
+	RollNumber int       `json:"roll_number"`
+	Code       string    `json:"code"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type redeemLinkCodeRequest struct {
+	Code string `json:"code"`
+}
+
+const (
+	defaultLinkCodeDays = 30
+	maxLinkCodeDays     = 90
+	maxClassLinkCodes   = 500
+)
+
+// Student issues a new code for one student, replacing any open code. The
+// code is shown only in this response.
+func (h LinkCodesHandler) Student(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if student.Status != models.StudentStatusActive {
+		writeError(w, http.StatusConflict, "student is archived")
+		return
+	}
+	expiresAt, ok := parseLinkCodeExpiry(w, r)
+	if !ok {
+		return
+	}
+	codes, err := h.issue(r, user, []models.Student{*student}, expiresAt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to issue code")
+		return
+	}
+	auditLog(r.Context(), "parent_link_code.issued", user, map[string]interface{}{
+		"student_id": student.ID,
+		"expires_at": expiresAt,
+	})
+	w.Header().Set("Cache-Control", "private, no-store")
+	writeJSON(w, http.StatusCreated, linkCodeResponse{
+		StudentID:  student.ID,
+		FullName:   student.FullName,
+		ClassLabel: student.ClassLabel,
+		RollNumber: student.RollNumber,
+		Code:       codes[student.ID],
+		ExpiresAt:  expiresAt,
+	})
+}
+
+// Class issues fresh codes for every active student of a class and returns
+// them as printable slips. Earlier open codes for those students stop working.
+func (h LinkCodesHandler) Class(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	classLabel := strings.TrimSpace(r.PathValue("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "missing class")
+		return
+	}
+	expiresAt, ok := parseLinkCodeExpiry(w, r)
+	if !ok {
+		return
+	}
+	school, err := h.Store.GetSchool(r.Context(), user.SchoolID)
+	if err != nil || school == nil {
+		writeError(w, http.StatusInternalServerError, "failed to load school")
+		return
+	}
+	students, err := h.Store.ListStudentsBySchool(r.Context(), user.SchoolID, classLabel, maxClassLinkCodes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list students")
+		return
+	}
+	if len(students) == 0 {
+		writeError(w, http.StatusNotFound, "no students in class")
+		return
+	}
+	codes, err := h.issue(r, user, students, expiresAt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to issue codes")
+		return
+	}
+
+	slips := make([]linkcode.Slip, 0, len(students))
+	for _, student := range students {
+		slips = append(slips, linkcode.Slip{
+			SchoolName:  school.Name,
+			StudentName: student.FullName,
+			ClassLabel:  student.ClassLabel,
+			RollNumber:  student.RollNumber,
+			Code:        codes[student.ID],
+			ExpiresAt:   expiresAt,
+		})
+	}
+	doc := pdf.New()
+	linkcode.RenderSlips(doc, slips)
+
+	auditLog(r.Context(), "parent_link_code.batch_issued", user, map[string]interface{}{
+		"class":      classLabel,
+		"count":      len(slips),
+		"expires_at": expiresAt,
+	})
+	writePDF(w, doc, "link-codes-"+sanitizeFileName(classLabel)+".pdf")
+}
+
+func (h LinkCodesHandler) issue(r *http.Request, user *models.User, students []models.Student, expiresAt time.Time) (map[string]string, error) {
+	codes := make(map[string]string, len(students))
+	hashes := make(map[string]string, len(students))
+	for _, student := range students {
+		code, err := linkcode.Generate()
+		if err != nil {
+			return nil, err
+		}
+		codes[student.ID] = code
+		hashes[student.ID] = linkcode.Hash(code)
+	}
+	if err := h.Store.ReplaceParentLinkCodes(r.Context(), user.SchoolID, user.ID, hashes, expiresAt); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Redeem gives the parent an approved link to the student a code was issued
+// for. Failed redemptions count towards the same lockout as failed identity
+// challenges.
+func (h ParentLinkHandler) Redeem(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleParent) {
+		writeError(w, http.StatusForbidden, "parent role required")
+		return
+	}
+	var req redeemLinkCodeRequest
+	if err := decodeJSON(r, &req); err != nil || strings.TrimSpace(req.Code) == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	attemptID, ok := h.startLinkAttempt(w, r, user)
+	if !ok {
+		return
+	}
+
+	var link *models.ParentLink
+	var err error
+	if code, ok := linkcode.Normalize(req.Code); ok {
+		link, err = h.Store.RedeemParentLinkCode(r.Context(), linkcode.Hash(code), user.ID)
+		if errors.Is(err, store.ErrParentLinkRevoked) {
+			// The code was genuine, so this is not a failed guess.
+			_ = h.Store.FinishParentLinkAttempt(r.Context(), attemptID, "", true)
+			auditLog(r.Context(), "parent_link_code.refused", user, map[string]interface{}{"reason": "revoked"})
+			writeError(w, http.StatusForbidden, "your link to this student was revoked; contact the school")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to redeem code")
+			return
+		}
+	}
+	if link == nil {
+		if err := h.Store.FinishParentLinkAttempt(r.Context(), attemptID, "", false); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to record attempt")
+			return
+		}
+		auditLog(r.Context(), "parent_link_code.rejected", user, map[string]interface{}{})
+		writeError(w, http.StatusNotFound, "code is invalid, used or expired")
+		return
+	}
+
+	// The link exists now; a failure to record the attempt only leaves it
+	// counted as failed.
+	if err := h.Store.FinishParentLinkAttempt(r.Context(), attemptID, link.StudentID, true); err != nil {
+		log.Printf("parent link code: record attempt %s: %v", attemptID, err)
+	}
+	auditLog(r.Context(), "parent_link_code.redeemed", user, map[string]interface{}{
+		"parent_link_id": link.ID,
+		"student_id":     link.StudentID,
+	})
+	writeJSON(w, http.StatusOK, link)
+}
+
+func parseLinkCodeExpiry(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	days := defaultLinkCodeDays
+	if raw := strings.TrimSpace(r.URL.Query().Get("valid_days")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxLinkCodeDays {
+			writeError(w, http.StatusBadRequest, "valid_days must be between 1 and 90")
+			return time.Time{}, false
+		}
+		days = parsed
+	}
+	return time.Now().AddDate(0, 0, days), true
+}
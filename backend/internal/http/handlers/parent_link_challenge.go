@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"jnv/backend/internal/models"
+)
+
+// Limits on failed identity challenges within linkChallengeWindow. A parent
+// over the limit is told so; a student over the limit simply stops matching,
+// so the response cannot be used to learn that the student exists.
+const (
+	linkChallengeWindow          = 24 * time.Hour
+	maxFailedChallengesByParent  = 5
+	maxFailedChallengesByStudent = 10
+)
+
+// linkChallenge is what the parent offered to prove they know the student.
+type linkChallenge struct {
+	dob         time.Time
+	admissionNo string
+}
+
+// startLinkAttempt records an attempt, counted as failed until it succeeds,
+// and returns its ID. ok is false, with the response written, when the
+// parent is locked out or the attempt could not be recorded.
+func (h ParentLinkHandler) startLinkAttempt(w http.ResponseWriter, r *http.Request, user *models.User) (string, bool) {
+	attemptID, err := h.Store.StartParentLinkAttempt(r.Context(), user.ID, time.Now().Add(-linkChallengeWindow), maxFailedChallengesByParent)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check attempts")
+		return "", false
+	}
+	if attemptID == "" {
+		w.Header().Set("Retry-After", strconv.Itoa(int(linkChallengeWindow.Seconds())))
+		writeError(w, http.StatusTooManyRequests, "too many failed attempts; try again later")
+		return "", false
+	}
+	return attemptID, true
+}
+
+// verifyLinkChallenge returns why the challenge failed, or "" when the
+// student exists, is not locked and every offered factor matches.
+func (h ParentLinkHandler) verifyLinkChallenge(r *http.Request, student *models.Student, challenge linkChallenge) (string, error) {
+	if student == nil || student.Status != models.StudentStatusActive {
+		return "not_found", nil
+	}
+	failed, err := h.Store.CountFailedLinkAttemptsByStudent(r.Context(), student.ID, time.Now().Add(-linkChallengeWindow))
+	if err != nil {
+		return "", err
+	}
+	if failed >= maxFailedChallengesByStudent {
+		return "student_locked", nil
+	}
+	if !challenge.dob.IsZero() && challenge.dob.Format("2006-01-02") != student.DateOfBirth.Format("2006-01-02") {
+		return "date_of_birth_mismatch", nil
+	}
+	if challenge.admissionNo != "" && challenge.admissionNo != student.AdmissionNo {
+		return "admission_no_mismatch", nil
+	}
+	return "", nil
+}
+
+// failLinkChallenge records and audits a failed challenge, then answers the
+// same way whatever the reason.
+func (h ParentLinkHandler) failLinkChallenge(w http.ResponseWriter, r *http.Request, user *models.User, attemptID string, student *models.Student, reason string, details map[string]interface{}) {
+	studentID := ""
+	if student != nil {
+		studentID = student.ID
+	}
+	if err := h.Store.FinishParentLinkAttempt(r.Context(), attemptID, studentID, false); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record attempt")
+		return
+	}
+	details["reason"] = reason
+	if studentID != "" {
+		details["student_id"] = studentID
+	}
+	auditLog(r.Context(), "parent_link.challenge_failed", user, details)
+	writeError(w, http.StatusNotFound, "no student matches these details")
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+)
+
+const (
+	maxBulkParentLinks        = 200
+	defaultLinkMetricsDays    = 90
+	maxLinkMetricsDays        = 730
+	bulkParentLinkApprove     = "approve"
+	bulkParentLinkReject      = "reject"
+	bulkParentLinkStatusError = "error"
+)
+
+type bulkParentLinkRequest struct {
+	Action string   `json:"action"`
+	IDs    []string `json:"ids"`
+	Reason string   `json:"reason"`
+}
+
+type bulkParentLinkResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type bulkParentLinkResponse struct {
+	Action    string                 `json:"action"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []bulkParentLinkResult `json:"results"`
+}
+
+// Bulk approves or rejects several pending requests at once. Each id is
+// decided on its own, so one stale id does not hold back the rest; the
+// response reports the outcome per id in request order.
+func (h ParentLinkHandler) Bulk(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req bulkParentLinkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	if action != bulkParentLinkApprove && action != bulkParentLinkReject {
+		writeError(w, http.StatusBadRequest, "action must be approve or reject")
+		return
+	}
+	if len(req.IDs) == 0 {
+		writeError(w, http.StatusBadRequest, "ids are required")
+		return
+	}
+	if len(req.IDs) > maxBulkParentLinks {
+		writeError(w, http.StatusBadRequest, "too many ids")
+		return
+	}
+	var reason string
+	if action == bulkParentLinkReject {
+		var err error
+		if reason, err = decisionReason(req.Reason); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	resp := bulkParentLinkResponse{Action: action, Results: make([]bulkParentLinkResult, 0, len(req.IDs))}
+	seen := make(map[string]bool, len(req.IDs))
+	for _, raw := range req.IDs {
+		id := strings.TrimSpace(raw)
+		result := bulkParentLinkResult{ID: id}
+		if seen[id] {
+			result.Error = "duplicate id"
+		} else if action == bulkParentLinkApprove {
+			result.Status, result.Error = h.bulkApprove(r, user, id)
+		} else {
+			result.Status, result.Error = h.bulkReject(r, user, id, reason)
+		}
+		seen[id] = true
+		if result.Error != "" {
+			result.Status = bulkParentLinkStatusError
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	auditLog(r.Context(), "parent_link.bulk_"+action, user, map[string]interface{}{
+		"requested": len(req.IDs),
+		"succeeded": resp.Succeeded,
+		"failed":    resp.Failed,
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h ParentLinkHandler) bulkApprove(r *http.Request, user *models.User, id string) (string, string) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", "pending link not found"
+	}
+	if err := h.Store.ApproveParentLink(r.Context(), id, user.SchoolID, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "pending link not found"
+		}
+		return "", "failed to approve"
+	}
+	auditLog(r.Context(), "parent_link.approved", user, map[string]interface{}{
+		"parent_link_id": id,
+	})
+	return models.ParentLinkApproved, ""
+}
+
+func (h ParentLinkHandler) bulkReject(r *http.Request, user *models.User, id, reason string) (string, string) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", "link not found"
+	}
+	link, err := h.Store.GetParentLinkForSchool(r.Context(), id, user.SchoolID)
+	if err != nil {
+		return "", "failed to load link"
+	}
+	if link == nil {
+		return "", "link not found"
+	}
+	if err := h.applyDecision(r.Context(), user, link, models.ParentLinkRejected, reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "link is " + link.Status
+		}
+		return "", "failed to update link"
+	}
+	return models.ParentLinkRejected, ""
+}
+
+// Metrics reports approval turnaround over the last ?days= (default 90).
+// Admins see their own school; super admins see every school.
+func (h ParentLinkHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleSuperAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	days := defaultLinkMetricsDays
+	if raw := strings.TrimSpace(r.URL.Query().Get("days")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxLinkMetricsDays {
+			writeError(w, http.StatusBadRequest, "days must be between 1 and 730")
+			return
+		}
+		days = parsed
+	}
+	schoolID := user.SchoolID
+	if user.Role == models.RoleSuperAdmin {
+		schoolID = ""
+	}
+	items, err := h.Store.ParentLinkMetrics(r.Context(), schoolID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load metrics")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
@@ -1,27 +1,53 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
 	"jnv/backend/internal/httpctx"
 	"jnv/backend/internal/models"
+	"jnv/backend/internal/notify"
 	"jnv/backend/internal/store"
 )
 
 type ParentLinkHandler struct {
-	Store *store.Store
+	Store    *store.Store
+	Notifier notify.Sender
 }
 
+type parentLinkDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// How long a parent must wait before asking again for a student after an
+// admin turned them down.
+const (
+	parentLinkRejectCooldown = 7 * 24 * time.Hour
+	parentLinkRevokeCooldown = 30 * 24 * time.Hour
+)
+
 type createParentLinkRequest struct {
 	StudentID string `json:"student_id"`
 }
 
+type extendParentLinkRequest struct {
+	StudentIDs []string `json:"student_ids"`
+}
+
 type createParentLinkByClassRollRequest struct {
-	District   string `json:"district"`
-	ClassLabel string `json:"class_label"`
-	RollNumber int    `json:"roll_number"`
+	District    string `json:"district"`
+	ClassLabel  string `json:"class_label"`
+	RollNumber  int    `json:"roll_number"`
+	AdmissionNo string `json:"admission_no"`
+	APAARID     string `json:"apaar_id"`
+	DateOfBirth string `json:"date_of_birth"`
 }
 
 func (h ParentLinkHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -55,31 +81,9 @@ func (h ParentLinkHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := h.Store.FindParentLink(r.Context(), user.ID, req.StudentID)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to check existing link")
-		return
-	}
-	if existing != nil {
-		if existing.Status == "pending" {
-			writeError(w, http.StatusConflict, "link request already pending")
-			return
-		}
-		if existing.Status == "approved" {
-			writeJSON(w, http.StatusOK, existing)
-			return
-		}
-	}
-
-	link, err := h.Store.CreateParentLink(r.Context(), user.ID, req.StudentID)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create link")
-		return
-	}
-	auditLog(r.Context(), "parent_link.requested", user, map[string]interface{}{
-		"student_id": req.StudentID,
+	h.requestLink(w, r, user, student, map[string]interface{}{
+		"student_id": student.ID,
 	})
-	writeJSON(w, http.StatusCreated, link)
 }
 
 func (h ParentLinkHandler) CreateByClassRoll(w http.ResponseWriter, r *http.Request) {
@@ -94,48 +98,128 @@ func (h ParentLinkHandler) CreateByClassRoll(w http.ResponseWriter, r *http.Requ
 	}
 
 	var req createParentLinkByClassRollRequest
-	if err := decodeJSON(r, &req); err != nil || req.District == "" || req.ClassLabel == "" || req.RollNumber <= 0 {
-		writeError(w, http.StatusBadRequest, "district, class_label and roll_number are required")
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
 		return
 	}
-
-	var (
-		student *models.Student
-		err     error
-	)
-	school, err := h.Store.GetSchoolByDistrict(r.Context(), req.District)
+	admissionNo, apaarID, _, err := normalizeStudentIdentifiers(req.AdmissionNo, req.APAARID, "")
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to resolve district")
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var dob time.Time
+	if raw := strings.TrimSpace(req.DateOfBirth); raw != "" {
+		if dob, err = time.Parse("2006-01-02", raw); err != nil {
+			writeError(w, http.StatusBadRequest, "date_of_birth must be YYYY-MM-DD")
+			return
+		}
+	}
+	byClassRoll := req.ClassLabel != "" && req.RollNumber > 0
+	switch {
+	case apaarID == "" && (req.District == "" || (!byClassRoll && admissionNo == "")):
+		writeError(w, http.StatusBadRequest, "district with class_label and roll_number or admission_no, or apaar_id, is required")
+		return
+	case byClassRoll && apaarID == "" && dob.IsZero() && admissionNo == "":
+		writeError(w, http.StatusBadRequest, "date_of_birth or admission_no is required to verify the student")
+		return
+	case (!byClassRoll || apaarID != "") && dob.IsZero():
+		writeError(w, http.StatusBadRequest, "date_of_birth is required to verify the student")
+		return
+	}
+
+	// APAAR IDs are national, so no district is needed.
+	var school *models.School
+	if apaarID == "" {
+		school, err = h.Store.GetSchoolByDistrict(r.Context(), req.District)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to resolve district")
+			return
+		}
+		if school == nil {
+			writeError(w, http.StatusNotFound, "district school not found")
+			return
+		}
+	}
+
+	attemptID, ok := h.startLinkAttempt(w, r, user)
+	if !ok {
 		return
 	}
-	if school == nil {
-		writeError(w, http.StatusNotFound, "district school not found")
+
+	var student *models.Student
+	matchedBy := "class_roll"
+	switch {
+	case apaarID != "":
+		matchedBy = "apaar_id"
+		student, err = h.Store.GetStudentByIdentifier(r.Context(), "", "apaar_id", apaarID)
+	case byClassRoll:
+		student, err = h.Store.GetStudentByClassRoll(r.Context(), school.ID, req.ClassLabel, req.RollNumber)
+	default:
+		matchedBy = "admission_no"
+		student, err = h.Store.GetStudentByIdentifier(r.Context(), school.ID, "admission_no", admissionNo)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to lookup student")
 		return
 	}
 
-	student, err = h.Store.GetStudentByClassRoll(r.Context(), school.ID, req.ClassLabel, req.RollNumber)
+	challenge := linkChallenge{dob: dob}
+	if matchedBy == "class_roll" {
+		challenge.admissionNo = admissionNo
+	}
+	reason, err := h.verifyLinkChallenge(r, student, challenge)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, http.StatusInternalServerError, "failed to verify student")
 		return
 	}
-	if student == nil {
-		writeError(w, http.StatusNotFound, "student not found for class and roll")
+	if reason != "" {
+		h.failLinkChallenge(w, r, user, attemptID, student, reason, map[string]interface{}{
+			"district":   req.District,
+			"matched_by": matchedBy,
+		})
+		return
+	}
+	if err := h.Store.FinishParentLinkAttempt(r.Context(), attemptID, student.ID, true); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record attempt")
 		return
 	}
 
+	h.requestLink(w, r, user, student, map[string]interface{}{
+		"student_id": student.ID,
+		"district":   req.District,
+		"matched_by": matchedBy,
+	})
+}
+
+// requestLink files a link request for the parent, or returns the link that
+// already exists. Under the school's auto-approval policy a request from a
+// phone on the student's record is approved at once.
+func (h ParentLinkHandler) requestLink(w http.ResponseWriter, r *http.Request, user *models.User, student *models.Student, details map[string]interface{}) {
 	existing, err := h.Store.FindParentLink(r.Context(), user.ID, student.ID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to check existing link")
 		return
 	}
 	if existing != nil {
-		if existing.Status == "pending" {
+		switch existing.Status {
+		case models.ParentLinkPending:
 			writeError(w, http.StatusConflict, "link request already pending")
 			return
-		}
-		if existing.Status == "approved" {
+		case models.ParentLinkApproved:
 			writeJSON(w, http.StatusOK, existing)
 			return
+		case models.ParentLinkRejected, models.ParentLinkRevoked:
+			cooldown := parentLinkRejectCooldown
+			if existing.Status == models.ParentLinkRevoked {
+				cooldown = parentLinkRevokeCooldown
+			}
+			if existing.DecidedAt != nil {
+				if until := existing.DecidedAt.Add(cooldown); time.Now().Before(until) {
+					w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+					writeError(w, http.StatusTooManyRequests, "link was "+existing.Status+"; you can request again after "+until.In(schoolTimezone).Format("02 Jan 2006"))
+					return
+				}
+			}
 		}
 	}
 
@@ -144,13 +228,120 @@ func (h ParentLinkHandler) CreateByClassRoll(w http.ResponseWriter, r *http.Requ
 		writeError(w, http.StatusInternalServerError, "failed to create link")
 		return
 	}
-	auditLog(r.Context(), "parent_link.requested", user, map[string]interface{}{
-		"student_id": student.ID,
-		"district":   req.District,
-	})
+	auditLog(r.Context(), "parent_link.requested", user, details)
+
+	match, err := h.autoApprovalMatch(r, user, student)
+	if err != nil {
+		// The request stands; it simply waits for an admin.
+		writeJSON(w, http.StatusCreated, link)
+		return
+	}
+	if match != "" {
+		if err := h.Store.ApproveParentLink(r.Context(), link.ID, student.SchoolID, ""); err != nil {
+			writeJSON(w, http.StatusCreated, link)
+			return
+		}
+		link.Status = "approved"
+		auditLog(r.Context(), "parent_link.auto_approved", user, map[string]interface{}{
+			"parent_link_id": link.ID,
+			"student_id":     student.ID,
+			"phone_match":    match,
+		})
+	}
 	writeJSON(w, http.StatusCreated, link)
 }
 
+// autoApprovalMatch returns where the parent's verified phone appears on the
+// student's record when the school's policy accepts that match, or "".
+func (h ParentLinkHandler) autoApprovalMatch(r *http.Request, user *models.User, student *models.Student) (string, error) {
+	key := verifiedPhoneKey(user)
+	if key == "" {
+		return "", nil
+	}
+	policy, err := h.Store.GetParentLinkPolicy(r.Context(), student.SchoolID)
+	if err != nil || policy == store.ParentLinkPolicyOff {
+		return "", err
+	}
+	match, err := h.Store.MatchStudentPhone(r.Context(), student.ID, key)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case match == "parent_phone":
+		return match, nil
+	case match == "guardian" && policy == store.ParentLinkPolicyGuardians:
+		return match, nil
+	}
+	return "", nil
+}
+
+// verifiedPhoneKey is the phone the identity provider verified for the user.
+// Accounts signed in by email or uid have none.
+func verifiedPhoneKey(user *models.User) string {
+	if strings.HasPrefix(user.Phone, "email:") || strings.HasPrefix(user.Phone, "uid:") {
+		return ""
+	}
+	key := phoneKey(user.Phone)
+	if len(key) != 10 {
+		return ""
+	}
+	return key
+}
+
+type parentLinkPolicyRequest struct {
+	AutoApprove string `json:"auto_approve"`
+}
+
+func (h ParentLinkHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	policy, err := h.Store.GetParentLinkPolicy(r.Context(), user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load policy")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"auto_approve": policy})
+}
+
+func (h ParentLinkHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req parentLinkPolicyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	policy := strings.ToLower(strings.TrimSpace(req.AutoApprove))
+	switch policy {
+	case store.ParentLinkPolicyOff, store.ParentLinkPolicyParentPhone, store.ParentLinkPolicyGuardians:
+	default:
+		writeError(w, http.StatusBadRequest, "auto_approve must be off, parent_phone or guardians")
+		return
+	}
+	if err := h.Store.SetParentLinkPolicy(r.Context(), user.SchoolID, policy); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save policy")
+		return
+	}
+	auditLog(r.Context(), "parent_link.policy_updated", user, map[string]interface{}{
+		"auto_approve": policy,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"auto_approve": policy})
+}
+
 func (h ParentLinkHandler) ListPending(w http.ResponseWriter, r *http.Request) {
 	user := httpctx.UserFromContext(r.Context())
 	if user == nil {
@@ -187,7 +378,11 @@ func (h ParentLinkHandler) Approve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Store.ApproveParentLink(r.Context(), id, user.SchoolID); err != nil {
+	if err := h.Store.ApproveParentLink(r.Context(), id, user.SchoolID, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "pending link not found")
+			return
+		}
 		writeError(w, http.StatusInternalServerError, "failed to approve")
 		return
 	}
@@ -196,3 +391,209 @@ func (h ParentLinkHandler) Approve(w http.ResponseWriter, r *http.Request) {
 	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "approved"})
 }
+
+// Siblings lists the confirmed siblings of the parent's linked children that
+// the parent could extend their link to.
+func (h ParentLinkHandler) Siblings(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleParent) {
+		writeError(w, http.StatusForbidden, "parent role required")
+		return
+	}
+	offers, err := h.Store.ListSiblingOffers(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list siblings")
+		return
+	}
+	writeJSON(w, http.StatusOK, offers)
+}
+
+// Extend links the parent to siblings of a child they are already approved
+// for. The admin confirmed the family, so no second approval is needed.
+func (h ParentLinkHandler) Extend(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleParent) {
+		writeError(w, http.StatusForbidden, "parent role required")
+		return
+	}
+	var req extendParentLinkRequest
+	if err := decodeJSON(r, &req); err != nil || len(req.StudentIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "student_ids is required")
+		return
+	}
+
+	offers, err := h.Store.ListSiblingOffers(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list siblings")
+		return
+	}
+	offered := make(map[string]models.SiblingOffer, len(offers))
+	for _, offer := range offers {
+		offered[offer.StudentID] = offer
+	}
+	var studentIDs []string
+	seen := map[string]bool{}
+	for _, id := range req.StudentIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, ok := offered[id]; !ok {
+			writeError(w, http.StatusForbidden, "student "+id+" is not a confirmed sibling of a linked child")
+			return
+		}
+		studentIDs = append(studentIDs, id)
+	}
+
+	links, err := h.Store.CreateApprovedParentLinks(r.Context(), user.ID, studentIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create links")
+		return
+	}
+	for _, link := range links {
+		offer := offered[link.StudentID]
+		auditLog(r.Context(), "parent_link.extended", user, map[string]interface{}{
+			"parent_link_id":    link.ID,
+			"student_id":        link.StudentID,
+			"family_id":         offer.FamilyID,
+			"linked_student_id": offer.LinkedStudent,
+		})
+	}
+	writeJSON(w, http.StatusCreated, links)
+}
+
+// Reject turns down a pending request. The reason is shown to the parent.
+func (h ParentLinkHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, models.ParentLinkRejected)
+}
+
+// Revoke withdraws an approved link, for example after a custody change. The
+// parent loses access to the student immediately.
+func (h ParentLinkHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, models.ParentLinkRevoked)
+}
+
+func (h ParentLinkHandler) decide(w http.ResponseWriter, r *http.Request, status string) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req parentLinkDecisionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	reason, err := decisionReason(req.Reason)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+	link, err := h.Store.GetParentLinkForSchool(r.Context(), id, user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load link")
+		return
+	}
+	if link == nil {
+		writeError(w, http.StatusNotFound, "link not found")
+		return
+	}
+
+	if err := h.applyDecision(r.Context(), user, link, status, reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusConflict, "link is "+link.Status)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update link")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+func decisionReason(raw string) (string, error) {
+	reason := strings.TrimSpace(raw)
+	if reason == "" {
+		return "", errors.New("reason is required")
+	}
+	if len(reason) > 500 {
+		return "", errors.New("reason is too long")
+	}
+	return reason, nil
+}
+
+// applyDecision rejects or revokes link, tells the parent and audits it. It
+// returns sql.ErrNoRows when the link is no longer in a state that allows it.
+func (h ParentLinkHandler) applyDecision(ctx context.Context, user *models.User, link *models.ParentLink, status, reason string) error {
+	var (
+		title, body string
+		err         error
+	)
+	if status == models.ParentLinkRejected {
+		err = h.Store.RejectParentLink(ctx, link.ID, user.ID, reason)
+		title = "Link request declined"
+		body = "Your request to link to a student was declined: " + reason
+	} else {
+		err = h.Store.RevokeParentLink(ctx, link.ID, user.ID, reason)
+		title = "Student link removed"
+		body = "Your access to a student's records was removed: " + reason
+	}
+	if err != nil {
+		return err
+	}
+
+	_ = h.Notifier.SendToUser(ctx, link.ParentID, title, body, map[string]string{
+		"type":           "parent_link",
+		"parent_link_id": link.ID,
+		"student_id":     link.StudentID,
+		"status":         status,
+	})
+	auditLog(ctx, "parent_link."+status, user, map[string]interface{}{
+		"parent_link_id": link.ID,
+		"parent_id":      link.ParentID,
+		"student_id":     link.StudentID,
+		"reason":         reason,
+	})
+	return nil
+}
+
+// ListForStudent shows every link request ever made for a student, so an
+// admin can find the link to revoke.
+func (h ParentLinkHandler) ListForStudent(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	items, err := h.Store.ListParentLinksByStudent(r.Context(), student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list links")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"jnv/backend/internal/httpctx"
 	"jnv/backend/internal/models"
@@ -12,12 +13,38 @@ type ParentsHandler struct {
 	Store *store.Store
 }
 
+// parentOverviewResponse keeps the single-child fields older app versions
+// read (the approved child, or the latest request) and lists every child.
 type parentOverviewResponse struct {
-	Status  string          `json:"status"`
-	Student *models.Student `json:"student,omitempty"`
-	Scores  []models.Score  `json:"scores,omitempty"`
+	Status   string          `json:"status"`
+	Reason   string          `json:"reason,omitempty"`
+	Student  *models.Student `json:"student,omitempty"`
+	Scores   []models.Score  `json:"scores,omitempty"`
+	Children []childOverview `json:"children"`
 }
 
+// childOverview is one child in the app's switcher. Details of a student are
+// included only once the link is approved.
+type childOverview struct {
+	LinkID              string                `json:"link_id"`
+	StudentID           string                `json:"student_id"`
+	Status              string                `json:"status"`
+	Reason              string                `json:"reason,omitempty"`
+	Student             *models.Student       `json:"student,omitempty"`
+	LatestExam          *models.ExamSummary   `json:"latest_exam,omitempty"`
+	UpcomingEvents      []models.Event        `json:"upcoming_events,omitempty"`
+	UnreadCount         int                   `json:"unread_announcements"`
+	UnreadAnnouncements []models.Announcement `json:"announcements,omitempty"`
+	Scores              []models.Score        `json:"scores,omitempty"`
+}
+
+const (
+	overviewEventLimit        = 5
+	overviewAnnouncementLimit = 5
+	// Announcements older than this no longer count as unread.
+	unreadAnnouncementWindow = 60 * 24 * time.Hour
+)
+
 func (h ParentsHandler) Overview(w http.ResponseWriter, r *http.Request) {
 	user := httpctx.UserFromContext(r.Context())
 	if user == nil {
@@ -29,39 +56,124 @@ func (h ParentsHandler) Overview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	link, err := h.Store.LatestParentLinkByParent(r.Context(), user.ID)
+	links, err := h.Store.ListParentLinksByParent(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load parent links")
+		return
+	}
+	resp := parentOverviewResponse{Status: "not_linked", Children: []childOverview{}}
+	summaries := map[string]*schoolSummary{}
+	for _, link := range links {
+		child, err := h.childOverview(r, user, link, summaries)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load overview")
+			return
+		}
+		resp.Children = append(resp.Children, child)
+	}
+
+	primary, err := h.Store.LatestParentLinkByParent(r.Context(), user.ID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to load parent link")
 		return
 	}
-	if link == nil {
-		writeJSON(w, http.StatusOK, parentOverviewResponse{Status: "not_linked"})
+	if primary != nil {
+		resp.Status = primary.Status
+		resp.Reason = primary.Reason
+		for _, child := range resp.Children {
+			if child.LinkID == primary.ID && child.Student != nil {
+				resp.Student = child.Student
+				resp.Scores, err = h.Store.ListScoresByStudent(r.Context(), child.StudentID, true)
+				if err != nil {
+					writeError(w, http.StatusInternalServerError, "failed to load scores")
+					return
+				}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Child returns the overview of one linked child with all their scores.
+func (h ParentsHandler) Child(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-	if link.Status != "approved" {
-		writeJSON(w, http.StatusOK, parentOverviewResponse{Status: "pending"})
+	if !hasRole(user, models.RoleParent) {
+		writeError(w, http.StatusForbidden, "parent role required")
 		return
 	}
-
-	student, err := h.Store.GetStudent(r.Context(), link.StudentID)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to load student")
+	student, ok := authorizeStudentView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
 		return
 	}
-	if student == nil {
-		writeJSON(w, http.StatusOK, parentOverviewResponse{Status: "pending"})
+	link, err := h.Store.FindParentLink(r.Context(), user.ID, student.ID)
+	if err != nil || link == nil {
+		writeError(w, http.StatusInternalServerError, "failed to load parent link")
 		return
 	}
-
-	scores, err := h.Store.ListScoresByStudent(r.Context(), student.ID)
+	child, err := h.childOverview(r, user, *link, map[string]*schoolSummary{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load overview")
+		return
+	}
+	child.Scores, err = h.Store.ListScoresByStudent(r.Context(), student.ID, true)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to load scores")
 		return
 	}
+	writeJSON(w, http.StatusOK, child)
+}
 
-	writeJSON(w, http.StatusOK, parentOverviewResponse{
-		Status:  "approved",
-		Student: student,
-		Scores:  scores,
-	})
+// schoolSummary holds what every child at the same school shares.
+type schoolSummary struct {
+	events        []models.Event
+	announcements []models.Announcement
+	unread        int
+}
+
+func (h ParentsHandler) childOverview(r *http.Request, user *models.User, link models.ParentLink, summaries map[string]*schoolSummary) (childOverview, error) {
+	child := childOverview{
+		LinkID:    link.ID,
+		StudentID: link.StudentID,
+		Status:    link.Status,
+		Reason:    link.Reason,
+	}
+	if link.Status != models.ParentLinkApproved {
+		return child, nil
+	}
+	student, err := h.Store.GetStudent(r.Context(), link.StudentID)
+	if err != nil {
+		return child, err
+	}
+	if student == nil {
+		// Archived or removed: keep the entry but show nothing about it.
+		return child, nil
+	}
+	child.Student = student
+	if child.LatestExam, err = h.Store.LatestExamSummary(r.Context(), student.ID); err != nil {
+		return child, err
+	}
+
+	summary, ok := summaries[student.SchoolID]
+	if !ok {
+		now := time.Now().In(schoolTimezone)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, schoolTimezone)
+		summary = &schoolSummary{}
+		if summary.events, err = h.Store.ListUpcomingEvents(r.Context(), student.SchoolID, today, overviewEventLimit); err != nil {
+			return child, err
+		}
+		summary.announcements, summary.unread, err = h.Store.ListUnreadAnnouncements(r.Context(), student.SchoolID,
+			user.ID, time.Now().Add(-unreadAnnouncementWindow), overviewAnnouncementLimit)
+		if err != nil {
+			return child, err
+		}
+		summaries[student.SchoolID] = summary
+	}
+	child.UpcomingEvents = summary.events
+	child.UnreadAnnouncements = summary.announcements
+	child.UnreadCount = summary.unread
+	return child, nil
 }
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/pdf"
+	"jnv/backend/internal/reportcard"
+	"jnv/backend/internal/store"
+)
+
+const maxClassReportCards = 500
+
+var errNoTermResults = errors.New("no results for this term")
+
+type ReportCardsHandler struct {
+	Store *store.Store
+}
+
+type reportCardRemarksRequest struct {
+	Term        string `json:"term"`
+	Remarks     string `json:"remarks"`
+	DaysPresent *int   `json:"days_present"`
+	WorkingDays *int   `json:"working_days"`
+}
+
+// Student renders one student's report card for ?term=. Parents may fetch
+// their linked children's cards, which only include published exams.
+func (h ReportCardsHandler) Student(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	term := strings.TrimSpace(r.URL.Query().Get("term"))
+	if term == "" {
+		writeError(w, http.StatusBadRequest, "term required")
+		return
+	}
+	student, ok := authorizeStudentView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	cards, tpl, err := h.buildCards(r.Context(), student.SchoolID, student.ClassLabel, []models.Student{*student},
+		term, hasRole(user, models.RoleParent))
+	if err != nil {
+		writeReportCardError(w, err)
+		return
+	}
+	doc := pdf.New()
+	reportcard.Render(doc, tpl, cards[0])
+
+	auditLog(r.Context(), "report_card.generated", user, map[string]interface{}{
+		"student_id": student.ID,
+		"term":       term,
+	})
+	writePDF(w, doc, "report-card-"+sanitizeFileName(term)+"-"+student.ID+".pdf")
+}
+
+// Class returns a ZIP with one report card PDF per student of the class.
+func (h ReportCardsHandler) Class(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	classLabel := strings.TrimSpace(r.PathValue("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "missing class")
+		return
+	}
+	term := strings.TrimSpace(r.URL.Query().Get("term"))
+	if term == "" {
+		writeError(w, http.StatusBadRequest, "term required")
+		return
+	}
+	students, err := h.Store.ListStudentsBySchool(r.Context(), user.SchoolID, classLabel, maxClassReportCards)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list students")
+		return
+	}
+	if len(students) == 0 {
+		writeError(w, http.StatusNotFound, "no students in class")
+		return
+	}
+	cards, tpl, err := h.buildCards(r.Context(), user.SchoolID, classLabel, students, term, false)
+	if err != nil {
+		writeReportCardError(w, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for i, card := range cards {
+		doc := pdf.New()
+		reportcard.Render(doc, tpl, card)
+		data, err := doc.Bytes()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to render report cards")
+			return
+		}
+		name := fmt.Sprintf("%03d-%s.pdf", students[i].RollNumber, sanitizeFileName(students[i].FullName))
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: card.GeneratedAt})
+		if err == nil {
+			_, err = file.Write(data)
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to render report cards")
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render report cards")
+		return
+	}
+
+	auditLog(r.Context(), "report_card.batch_generated", user, map[string]interface{}{
+		"class": classLabel,
+		"term":  term,
+		"count": len(cards),
+	})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Content-Disposition", `attachment; filename="report-cards-`+sanitizeFileName(classLabel)+"-"+sanitizeFileName(term)+`.zip"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// buildCards gathers the term's exams of the class, the students' scores in
+// them, the grading scheme and remarks, and assembles one card per student
+// in the order given.
+func (h ReportCardsHandler) buildCards(ctx context.Context, schoolID, class string, students []models.Student, term string, publishedOnly bool) ([]reportcard.Card, models.ReportCardTemplate, error) {
+	tpl := reportcard.DefaultTemplate()
+	school, err := h.Store.GetSchool(ctx, schoolID)
+	if err != nil {
+		return nil, tpl, err
+	}
+	if school == nil {
+		return nil, tpl, errors.New("school not found")
+	}
+	saved, err := h.Store.GetReportCardTemplate(ctx, schoolID)
+	if err != nil {
+		return nil, tpl, err
+	}
+	if saved != nil {
+		tpl = *saved
+	}
+
+	all, err := h.Store.ListExams(ctx, schoolID, store.ExamFilter{Class: class, Term: term})
+	if err != nil {
+		return nil, tpl, err
+	}
+	var (
+		exams   []models.Exam
+		examIDs []string
+	)
+	for _, exam := range all {
+		if publishedOnly && exam.Status != models.ExamResultsPublished && exam.Status != models.ExamLocked {
+			continue
+		}
+		exams = append(exams, exam)
+		examIDs = append(examIDs, exam.ID)
+	}
+	if len(exams) == 0 {
+		return nil, tpl, errNoTermResults
+	}
+
+	studentIDs := make([]string, len(students))
+	for i, student := range students {
+		studentIDs[i] = student.ID
+	}
+	scores, err := h.Store.ListScoresForExams(ctx, examIDs, studentIDs)
+	if err != nil {
+		return nil, tpl, err
+	}
+	byStudent := map[string][]models.Score{}
+	for _, score := range scores {
+		byStudent[score.StudentID] = append(byStudent[score.StudentID], score)
+	}
+	var bands []models.GradeBand
+	scheme, err := h.Store.GradingSchemeForClass(ctx, schoolID, class)
+	if err != nil {
+		return nil, tpl, err
+	}
+	if scheme != nil {
+		bands = scheme.Bands
+	}
+	remarks, err := h.Store.ListReportCardRemarks(ctx, studentIDs, term)
+	if err != nil {
+		return nil, tpl, err
+	}
+
+	now := time.Now()
+	cards := make([]reportcard.Card, len(students))
+	for i, student := range students {
+		var studentRemarks *models.ReportCardRemarks
+		if item, ok := remarks[student.ID]; ok {
+			studentRemarks = &item
+		}
+		cards[i] = reportcard.Build(*school, student, term, exams, byStudent[student.ID], bands, studentRemarks, now)
+	}
+	return cards, tpl, nil
+}
+
+func writeReportCardError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNoTermResults) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "failed to prepare report card")
+}
+
+// SaveRemarks records the teacher's remarks and, optionally, attendance for
+// a student's report card in a term.
+func (h ReportCardsHandler) SaveRemarks(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	var req reportCardRemarksRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	remarks := models.ReportCardRemarks{
+		StudentID:   student.ID,
+		Term:        strings.TrimSpace(req.Term),
+		Remarks:     strings.TrimSpace(req.Remarks),
+		DaysPresent: req.DaysPresent,
+		WorkingDays: req.WorkingDays,
+		UpdatedBy:   user.ID,
+	}
+	if remarks.Term == "" {
+		writeError(w, http.StatusBadRequest, "term required")
+		return
+	}
+	if len(remarks.Remarks) > 1000 {
+		writeError(w, http.StatusBadRequest, "remarks are too long")
+		return
+	}
+	if (remarks.DaysPresent == nil) != (remarks.WorkingDays == nil) {
+		writeError(w, http.StatusBadRequest, "days_present and working_days go together")
+		return
+	}
+	if remarks.WorkingDays != nil && (*remarks.WorkingDays <= 0 || *remarks.DaysPresent < 0 || *remarks.DaysPresent > *remarks.WorkingDays) {
+		writeError(w, http.StatusBadRequest, "days_present must be between 0 and working_days")
+		return
+	}
+
+	saved, err := h.Store.SaveReportCardRemarks(r.Context(), remarks)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save remarks")
+		return
+	}
+	auditLog(r.Context(), "report_card.remarks_updated", user, map[string]interface{}{
+		"student_id": student.ID,
+		"term":       saved.Term,
+	})
+	writeJSON(w, http.StatusOK, saved)
+}
+
+func (h ReportCardsHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	tpl, err := h.Store.GetReportCardTemplate(r.Context(), user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load template")
+		return
+	}
+	if tpl == nil {
+		def := reportcard.DefaultTemplate()
+		tpl = &def
+	}
+	writeJSON(w, http.StatusOK, tpl)
+}
+
+func (h ReportCardsHandler) SaveTemplate(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req models.ReportCardTemplate
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	tpl, err := reportcard.NormalizeTemplate(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.Store.SaveReportCardTemplate(r.Context(), user.SchoolID, tpl, user.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save template")
+		return
+	}
+	auditLog(r.Context(), "report_card.template_updated", user, map[string]interface{}{
+		"title": tpl.Title,
+	})
+	writeJSON(w, http.StatusOK, tpl)
+}
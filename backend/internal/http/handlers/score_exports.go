@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"jnv/backend/internal/export"
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/storage"
+	"jnv/backend/internal/store"
+	"jnv/backend/internal/xlsx"
+)
+
+// ScoreExportsHandler lets staff download results for review or board
+// submission, in the layouts score uploads accept.
+type ScoreExportsHandler struct {
+	Store *store.Store
+}
+
+func (h ScoreExportsHandler) ExamCSV(w http.ResponseWriter, r *http.Request) {
+	h.exam(w, r, "csv")
+}
+
+func (h ScoreExportsHandler) ExamXLSX(w http.ResponseWriter, r *http.Request) {
+	h.exam(w, r, "xlsx")
+}
+
+// exam exports one exam's scores, ?layout=matrix (the default) or rows.
+func (h ScoreExportsHandler) exam(w http.ResponseWriter, r *http.Request, format string) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	layout := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("layout")))
+	if layout == "" {
+		layout = export.LayoutMatrix
+	}
+	if layout != export.LayoutMatrix && layout != export.LayoutRows {
+		writeError(w, http.StatusBadRequest, "layout must be matrix or rows")
+		return
+	}
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	scores, err := h.Store.ListScoresForExams(r.Context(), []string{exam.ID}, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load scores")
+		return
+	}
+	students, err := exportStudents(r.Context(), h.Store, user.SchoolID, exam.Class, scores)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list students")
+		return
+	}
+	bands, err := examGradeBands(r.Context(), h.Store, exam)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load grading scheme")
+		return
+	}
+
+	table := export.Exam(*exam, students, scores, bands, layout)
+	filename := "scores-" + sanitizeFileName(exam.Class) + "-" + sanitizeFileName(exam.Title) + "-" + layout
+	var buf bytes.Buffer
+	contentType := storage.ContentTypeXLSX
+	if format == "csv" {
+		// The byte order mark makes Excel read names in Indian scripts as
+		// UTF-8; uploads strip it again.
+		buf.WriteString("\ufeff")
+		err = table.WriteCSV(&buf)
+		contentType = storage.ContentTypeCSV + "; charset=utf-8"
+	} else {
+		file := xlsx.NewFile()
+		table.AddTo(file)
+		err = file.Write(&buf)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to write export")
+		return
+	}
+	auditLog(r.Context(), "scores.exported", user, map[string]interface{}{
+		"exam_id": exam.ID,
+		"format":  format,
+		"layout":  layout,
+		"rows":    len(table.Rows),
+	})
+	writeDownload(w, buf.Bytes(), contentType, filename+"."+format)
+}
+
+// ClassXLSX exports a workbook for a class: a summary of every student's
+// percentage in each exam with their overall grade and rank, followed by one
+// matrix sheet per exam. ?term= limits it to one term. Draft exams have no
+// scores yet and are left out.
+func (h ScoreExportsHandler) ClassXLSX(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	classLabel := strings.TrimSpace(r.PathValue("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "missing class")
+		return
+	}
+	term := strings.TrimSpace(r.URL.Query().Get("term"))
+	all, err := h.Store.ListExams(r.Context(), user.SchoolID, store.ExamFilter{Class: classLabel, Term: term})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list exams")
+		return
+	}
+	var (
+		exams   []models.Exam
+		examIDs []string
+	)
+	for _, exam := range all {
+		if exam.Status != models.ExamDraft {
+			exams = append(exams, exam)
+			examIDs = append(examIDs, exam.ID)
+		}
+	}
+	if len(exams) == 0 {
+		writeError(w, http.StatusNotFound, "no exams with scores")
+		return
+	}
+	scores, err := h.Store.ListScoresForExams(r.Context(), examIDs, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load scores")
+		return
+	}
+	students, err := exportStudents(r.Context(), h.Store, user.SchoolID, classLabel, scores)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list students")
+		return
+	}
+	var bands []models.GradeBand
+	scheme, err := h.Store.GradingSchemeForClass(r.Context(), user.SchoolID, classLabel)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load grading scheme")
+		return
+	}
+	if scheme != nil {
+		bands = scheme.Bands
+	}
+
+	file := xlsx.NewFile()
+	export.ClassSummary(exams, students, scores, bands).AddTo(file)
+	for _, exam := range exams {
+		export.Exam(exam, students, scores, bands, export.LayoutMatrix).AddTo(file)
+	}
+	var buf bytes.Buffer
+	if err := file.Write(&buf); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to write export")
+		return
+	}
+	auditLog(r.Context(), "scores.exported", user, map[string]interface{}{
+		"class":  classLabel,
+		"term":   term,
+		"format": "xlsx",
+		"exams":  len(exams),
+	})
+	filename := "scores-" + sanitizeFileName(classLabel)
+	if term != "" {
+		filename += "-" + sanitizeFileName(term)
+	}
+	writeDownload(w, buf.Bytes(), storage.ContentTypeXLSX, filename+".xlsx")
+}
+
+// exportStudents returns the class's students plus anyone else with one of
+// the scores, e.g. a student who has since changed class.
+func exportStudents(ctx context.Context, s *store.Store, schoolID, class string, scores []models.Score) ([]models.Student, error) {
+	roster, err := s.ListStudentsBySchool(ctx, schoolID, class, maxClassRoster)
+	if err != nil {
+		return nil, err
+	}
+	inRoster := make(map[string]bool, len(roster))
+	for _, student := range roster {
+		inRoster[student.ID] = true
+	}
+	var missing []string
+	for _, score := range scores {
+		if !inRoster[score.StudentID] {
+			inRoster[score.StudentID] = true
+			missing = append(missing, score.StudentID)
+		}
+	}
+	var others []models.Student
+	if len(missing) > 0 {
+		if others, err = s.ListStudentsByIDs(ctx, schoolID, missing); err != nil {
+			return nil, err
+		}
+	}
+	return export.Students(roster, others), nil
+}
+
+func writeDownload(w http.ResponseWriter, data []byte, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// maxClassRoster bounds the roster read for one exam upload.
+const maxClassRoster = 2000
+
+// scoreRoster resolves score rows to students of the exam's class from
+// lookups made once per upload rather than once per row.
+type scoreRoster struct {
+	exam        *models.Exam
+	byID        map[string]*models.Student
+	byRoll      map[int]*models.Student
+	byAdmission map[string]*models.Student
+}
+
+// loadScoreRoster reads the exam's class in one query. Admission numbers the
+// sheet uses that are not in the class are looked up together in a second
+// query, only so the error can say where the student is.
+func loadScoreRoster(ctx context.Context, s *store.Store, exam *models.Exam, admissionNos []string) (*scoreRoster, error) {
+	students, err := s.ListStudentsBySchool(ctx, exam.SchoolID, exam.Class, maxClassRoster)
+	if err != nil {
+		return nil, err
+	}
+	roster := &scoreRoster{
+		exam:        exam,
+		byID:        make(map[string]*models.Student, len(students)),
+		byRoll:      make(map[int]*models.Student, len(students)),
+		byAdmission: make(map[string]*models.Student, len(students)),
+	}
+	for i := range students {
+		student := &students[i]
+		roster.byID[student.ID] = student
+		roster.byRoll[student.RollNumber] = student
+		if student.AdmissionNo != "" {
+			roster.byAdmission[student.AdmissionNo] = student
+		}
+	}
+
+	var missing []string
+	for _, admissionNo := range admissionNos {
+		if _, ok := roster.byAdmission[admissionNo]; !ok {
+			missing = append(missing, admissionNo)
+		}
+	}
+	if len(missing) > 0 {
+		others, err := s.ListStudentsByAdmissionNos(ctx, exam.SchoolID, missing)
+		if err != nil {
+			return nil, err
+		}
+		for i := range others {
+			roster.byAdmission[others[i].AdmissionNo] = &others[i]
+		}
+	}
+	return roster, nil
+}
+
+// sheetAdmissionNos collects the valid admission numbers of a sheet so the
+// roster can be loaded before the rows are read.
+func sheetAdmissionNos(rows [][]string, headerIndex map[string]int) []string {
+	var admissionNos []string
+	for _, record := range rows {
+		raw := getCell(record, headerIndex, "admission_no")
+		if raw == "" {
+			continue
+		}
+		if admissionNo, _, _, err := normalizeStudentIdentifiers(raw, "", ""); err == nil {
+			admissionNos = append(admissionNos, admissionNo)
+		}
+	}
+	return admissionNos
+}
+
+// studentForRow resolves the student a score row belongs to. A non-empty
+// admission_no takes precedence over roll, since roll numbers are reassigned
+// every year.
+func (ro *scoreRoster) studentForRow(record []string, headerIndex map[string]int, hasAdmissionNo bool) (*models.Student, string) {
+	if hasAdmissionNo {
+		if raw := getCell(record, headerIndex, "admission_no"); raw != "" {
+			admissionNo, _, _, err := normalizeStudentIdentifiers(raw, "", "")
+			if err != nil {
+				return nil, "invalid admission_no"
+			}
+			student := ro.byAdmission[admissionNo]
+			if student == nil || student.Status != models.StudentStatusActive {
+				return nil, "student not found"
+			}
+			if student.ClassLabel != ro.exam.Class {
+				return nil, "admission_no " + admissionNo + " is not in class " + ro.exam.Class
+			}
+			return student, ""
+		}
+	}
+
+	roll := getCell(record, headerIndex, "roll")
+	if roll == "" {
+		if hasAdmissionNo {
+			return nil, "missing roll or admission_no"
+		}
+		return nil, "missing roll"
+	}
+	rollNumberValue, err := strconv.Atoi(roll)
+	if err != nil {
+		return nil, "invalid roll"
+	}
+	student := ro.byRoll[rollNumberValue]
+	if student == nil {
+		return nil, "student not found"
+	}
+	return student, ""
+}
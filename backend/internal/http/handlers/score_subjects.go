@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// scoreSubjects checks the subject of each score against the class
+// catalogue and, when the exam declares its subjects, against those. A class
+// with no catalogue yet accepts any subject name.
+type scoreSubjects struct {
+	catalogue *subjectCatalogue
+	declared  map[string]models.ExamSubject
+}
+
+func loadScoreSubjects(ctx context.Context, s *store.Store, exam *models.Exam) (*scoreSubjects, error) {
+	subjects, err := s.ListSubjects(ctx, exam.SchoolID, exam.Class)
+	if err != nil {
+		return nil, err
+	}
+	ss := &scoreSubjects{}
+	if len(subjects) > 0 {
+		ss.catalogue = newSubjectCatalogue(subjects)
+	}
+	if len(exam.Subjects) > 0 {
+		ss.declared = make(map[string]models.ExamSubject, len(exam.Subjects))
+		for _, subject := range exam.Subjects {
+			ss.declared[subject.SubjectID] = subject
+		}
+	}
+	return ss, nil
+}
+
+// resolve returns the catalogue name of subject and the max score to store.
+// maxScore is the max the input gives, or 0 when it gives none; it must
+// agree with the exam's declared max, and defaults to it, or to 100.
+func (ss *scoreSubjects) resolve(subject string, maxScore float32) (string, float32, error) {
+	subject = strings.TrimSpace(subject)
+	if ss.catalogue != nil {
+		entry := ss.catalogue.match(subject)
+		if entry == nil {
+			return "", 0, errors.New(ss.catalogue.unknown(subject))
+		}
+		subject = entry.Name
+		if entry.Locked {
+			return "", 0, errors.New(subject + " is locked")
+		}
+		if ss.declared != nil {
+			declared, ok := ss.declared[entry.ID]
+			if !ok {
+				return "", 0, errors.New(subject + " is not part of this exam")
+			}
+			if maxScore == 0 {
+				maxScore = declared.MaxScore
+			} else if maxScore != declared.MaxScore {
+				return "", 0, errors.New(subject + " is out of " + formatMarks(declared.MaxScore) + " in this exam")
+			}
+		}
+	}
+	if maxScore == 0 {
+		maxScore = 100
+	}
+	return subject, maxScore, nil
+}
+
+func checkScoreRange(score, maxScore float32) error {
+	if maxScore <= 0 {
+		return errors.New("max_score must be positive")
+	}
+	if score < 0 || score > maxScore {
+		return errors.New("score must be between 0 and " + formatMarks(maxScore))
+	}
+	return nil
+}
+
+func formatMarks(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
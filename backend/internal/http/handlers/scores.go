@@ -1,28 +1,30 @@
 package handlers
 
 import (
-	"archive/zip"
-	"bytes"
 	"context"
-	"encoding/csv"
-	"encoding/xml"
-	"fmt"
-	"io"
+	"errors"
+	"math"
 	"net/http"
-	"path/filepath"
-	"sort"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"jnv/backend/internal/grading"
 	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/jobs"
 	"jnv/backend/internal/models"
 	"jnv/backend/internal/notify"
+	"jnv/backend/internal/storage"
 	"jnv/backend/internal/store"
 )
 
 type ScoresHandler struct {
 	Store    *store.Store
 	Notifier notify.Sender
+	Files    storage.Bucket
+	// QueueImports sends archived uploads to the import workers. It is off
+	// when this instance runs none, and uploads are then imported inline.
+	QueueImports bool
 }
 
 type createScoresRequest struct {
@@ -32,7 +34,18 @@ type createScoresRequest struct {
 		Score     float32 `json:"score"`
 		MaxScore  float32 `json:"max_score"`
 		Grade     string  `json:"grade"`
+		// Status is present by default; absent, medical_leave and exempt
+		// (or AB, ML and EX) record a paper without marks.
+		Status string `json:"status"`
 	} `json:"scores"`
+	Mode   string `json:"mode"`
+	Reason string `json:"reason"`
+}
+
+type scoreWriteResponse struct {
+	Status string `json:"status"`
+	Mode   string `json:"mode"`
+	store.ScoreWriteResult
 }
 
 func (h ScoresHandler) AddForExam(w http.ResponseWriter, r *http.Request) {
@@ -46,11 +59,15 @@ func (h ScoresHandler) AddForExam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	examID := r.PathValue("id")
-	if examID == "" {
-		writeError(w, http.StatusBadRequest, "missing exam id")
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if exam.Status == models.ExamLocked {
+		writeError(w, http.StatusConflict, "exam is locked")
 		return
 	}
+	examID := exam.ID
 
 	var req createScoresRequest
 	if err := decodeJSON(r, &req); err != nil {
@@ -62,37 +79,167 @@ func (h ScoresHandler) AddForExam(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "no scores")
 		return
 	}
+	write, err := scoreWriteOptions(req.Mode, req.Reason, user)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bands, err := examGradeBands(r.Context(), h.Store, exam)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load grading scheme")
+		return
+	}
+	subjects, err := loadScoreSubjects(r.Context(), h.Store, exam)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load subjects")
+		return
+	}
 
 	var scores []models.Score
-	for _, item := range req.Scores {
+	seen := map[string]bool{}
+	for i, item := range req.Scores {
+		if item.StudentID == "" || strings.TrimSpace(item.Subject) == "" {
+			writeError(w, http.StatusBadRequest, "scores["+strconv.Itoa(i)+"]: student_id and subject required")
+			return
+		}
+		status, err := scoreStatus(item.Status)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "scores["+strconv.Itoa(i)+"]: "+err.Error())
+			return
+		}
+		if !isFinite(item.Score) || !isFinite(item.MaxScore) {
+			writeError(w, http.StatusBadRequest, "scores["+strconv.Itoa(i)+"]: score and max_score must be numbers")
+			return
+		}
+		subject, maxScore, err := subjects.resolve(item.Subject, item.MaxScore)
+		if err == nil && grading.HasMarks(status) {
+			err = checkScoreRange(item.Score, maxScore)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "scores["+strconv.Itoa(i)+"]: "+err.Error())
+			return
+		}
+		key := item.StudentID + "\x00" + strings.ToLower(subject)
+		if seen[key] {
+			writeError(w, http.StatusBadRequest, "scores["+strconv.Itoa(i)+"]: duplicate "+subject+" score for student")
+			return
+		}
+		seen[key] = true
+		score, grade := item.Score, item.Grade
+		if !grading.HasMarks(status) {
+			score, grade = 0, ""
+		} else if bands != nil {
+			grade, err = grading.Resolve(bands, item.Score, maxScore, item.Grade)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "scores["+strconv.Itoa(i)+"]: "+err.Error())
+				return
+			}
+		}
 		scores = append(scores, models.Score{
 			ExamID:    examID,
 			StudentID: item.StudentID,
-			Subject:   item.Subject,
-			Score:     item.Score,
-			MaxScore:  item.MaxScore,
-			Grade:     item.Grade,
+			Subject:   subject,
+			Score:     score,
+			MaxScore:  maxScore,
+			Grade:     grade,
+			Status:    status,
 		})
 	}
 
-	if err := h.Store.AddScores(r.Context(), examID, scores); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to add scores")
+	studentIDs := make([]string, 0, len(scores))
+	for _, score := range scores {
+		studentIDs = append(studentIDs, score.StudentID)
+	}
+	archived, err := h.Store.ArchivedStudentIDs(r.Context(), studentIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to validate students")
+		return
+	}
+	if len(archived) > 0 {
+		writeError(w, http.StatusConflict, "archived student records are read-only: "+strings.Join(archived, ", "))
+		return
+	}
+	roster, err := loadScoreRoster(r.Context(), h.Store, exam, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to validate students")
+		return
+	}
+	for i, score := range scores {
+		if roster.byID[score.StudentID] == nil {
+			writeError(w, http.StatusBadRequest, "scores["+strconv.Itoa(i)+"]: student is not in class "+exam.Class)
+			return
+		}
+	}
+
+	result, err := h.Store.SaveScores(r.Context(), examID, scores, write)
+	if err != nil {
+		writeScoreSaveError(w, err)
 		return
 	}
-	_ = h.Notifier.SendToSchoolParents(r.Context(), user.SchoolID, "Scores updated", "New exam scores were uploaded.", map[string]string{
-		"type":    "score_upload",
-		"exam_id": examID,
-	})
+	h.scoresAdded(r.Context(), user, exam, "Scores updated", "New exam scores were uploaded.")
 	auditLog(r.Context(), "scores.created.manual", user, map[string]interface{}{
-		"exam_id": examID,
-		"count":   len(scores),
+		"exam_id":   examID,
+		"count":     len(scores),
+		"mode":      write.Mode,
+		"created":   result.Created,
+		"updated":   result.Updated,
+		"unchanged": result.Unchanged,
+		"deleted":   result.Deleted,
+		"reason":    write.Reason,
 	})
-	writeJSON(w, http.StatusCreated, map[string]string{"status": "uploaded"})
+	writeJSON(w, http.StatusCreated, scoreWriteResponse{Status: "uploaded", Mode: write.Mode, ScoreWriteResult: result})
+}
+
+// scoreWriteOptions reads the upload mode, merge by default, and the reason
+// recorded with each revision.
+func scoreWriteOptions(mode, reason string, user *models.User) (store.ScoreWrite, error) {
+	write := store.ScoreWrite{
+		Mode:      strings.ToLower(strings.TrimSpace(mode)),
+		ChangedBy: user.ID,
+		Reason:    strings.TrimSpace(reason),
+	}
+	if write.Mode == "" {
+		write.Mode = store.ScoreModeMerge
+	}
+	if write.Mode != store.ScoreModeMerge && write.Mode != store.ScoreModeReplace {
+		return write, errors.New("mode must be merge or replace")
+	}
+	if len(write.Reason) > 500 {
+		return write, errors.New("reason is too long")
+	}
+	return write, nil
+}
+
+// examGradeBands returns the bands of the grading scheme that applies to the
+// exam's class, or nil when the school has none and grades stay free text.
+func examGradeBands(ctx context.Context, s *store.Store, exam *models.Exam) ([]models.GradeBand, error) {
+	scheme, err := s.GradingSchemeForClass(ctx, exam.SchoolID, exam.Class)
+	if err != nil || scheme == nil {
+		return nil, err
+	}
+	return scheme.Bands, nil
+}
+
+func writeScoreSaveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrExamLocked) {
+		writeError(w, http.StatusConflict, "exam is locked")
+		return
+	}
+	if errors.Is(err, store.ErrSubjectLocked) {
+		writeError(w, http.StatusConflict, "subject is locked")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "failed to add scores")
 }
 
 type csvUploadResponse struct {
-	Inserted int      `json:"inserted"`
-	Errors   []string `json:"errors"`
+	Inserted  int      `json:"inserted"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Deleted   int      `json:"deleted"`
+	Mode      string   `json:"mode,omitempty"`
+	Errors    []string `json:"errors"`
 }
 
 func (h ScoresHandler) UploadCSV(w http.ResponseWriter, r *http.Request) {
@@ -110,27 +257,27 @@ func (h ScoresHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	examID := r.PathValue("id")
-	if examID == "" {
-		writeError(w, http.StatusBadRequest, "missing exam id")
-		return
-	}
-
-	exam, err := h.Store.GetExam(r.Context(), examID)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to load exam")
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
 		return
 	}
-	if exam == nil {
-		writeError(w, http.StatusNotFound, "exam not found")
+	if exam.Status == models.ExamLocked {
+		writeError(w, http.StatusConflict, "exam is locked")
 		return
 	}
+	examID := exam.ID
 
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid multipart form")
 		return
 	}
 
+	write, err := scoreWriteOptions(r.FormValue("mode"), r.FormValue("reason"), user)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	file, header, err := r.FormFile("file")
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "file is required")
@@ -138,69 +285,127 @@ func (h ScoresHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	var (
-		headers []string
-		rows    [][]string
-	)
-
-	switch ext {
-	case ".csv":
-		reader := csv.NewReader(file)
-		reader.TrimLeadingSpace = true
-		allRows, parseErr := reader.ReadAll()
-		if parseErr != nil {
-			writeError(w, http.StatusBadRequest, "failed to parse csv file")
-			return
-		}
-		if len(allRows) == 0 {
-			writeError(w, http.StatusBadRequest, "file is empty")
-			return
-		}
-		headers = allRows[0]
-		if len(allRows) > 1 {
-			rows = allRows[1:]
-		}
-	case ".xlsx":
-		rowsData, parseErr := parseXLSXRows(file)
-		if parseErr != nil {
-			writeError(w, http.StatusBadRequest, "failed to parse xlsx file")
-			return
-		}
-		if len(rowsData) == 0 {
-			writeError(w, http.StatusBadRequest, "file is empty")
-			return
-		}
-		headers = rowsData[0]
-		if len(rowsData) > 1 {
-			rows = rowsData[1:]
-		}
-	default:
-		writeError(w, http.StatusBadRequest, "supported file types: .csv, .xlsx")
+	data, contentType, status, err := readUpload(file, header, spreadsheetUploadPolicy)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	if err := checkSheetType(header.Filename); err != nil {
+		writeImportError(w, err)
+		return
+	}
+	if sheetSelectionFromParams(sheetParams(r, nil)).perClass {
+		writeError(w, http.StatusBadRequest, "score sheets are imported one exam at a time; pick a sheet")
 		return
 	}
 
-	scores, errorsList := h.buildScoresFromRows(r.Context(), examID, exam, headers, rows)
+	archived, err := archiveUpload(r.Context(), h.Files, user, "scores", header.Filename, contentType, data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to archive upload")
+		return
+	}
+	if archived != nil && h.QueueImports {
+		queueImport(r.Context(), w, h.Store, user, ImportScores, archived, header.Filename, sheetParams(r, map[string]string{
+			"exam_id": examID,
+			"mode":    write.Mode,
+			"reason":  write.Reason,
+		}))
+		return
+	}
 
-	if len(errorsList) > 0 {
-		writeJSON(w, http.StatusBadRequest, csvUploadResponse{Inserted: 0, Errors: errorsList})
+	selection := sheetSelectionFromParams(sheetParams(r, nil))
+	resp, err := h.importSheet(r.Context(), user, exam, write, header.Filename, selection, archived, data, noProgress)
+	if err != nil {
+		writeImportError(w, err)
 		return
 	}
+	status = http.StatusCreated
+	if len(resp.Errors) > 0 {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, resp)
+}
+
+// importSheet validates and saves a score sheet for an exam. Row errors
+// reject the whole sheet and are returned in the response; err is for
+// failures of the sheet as a whole.
+func (h ScoresHandler) importSheet(ctx context.Context, user *models.User, exam *models.Exam, write store.ScoreWrite, filename string, selection sheetSelection, archived *storage.Object, data []byte, progress jobs.Progress) (csvUploadResponse, error) {
+	if exam.Status == models.ExamLocked {
+		return csvUploadResponse{}, &importError{http.StatusConflict, "exam is locked"}
+	}
+	if selection.perClass {
+		return csvUploadResponse{}, &importError{http.StatusBadRequest, "score sheets are imported one exam at a time; pick a sheet"}
+	}
+	// A workbook with a sheet per class can be uploaded as is: the exam's
+	// class sheet is used unless another one is picked.
+	selection.prefer = exam.Class
+	sheets, err := readSheets(filename, data, selection)
+	if err != nil {
+		return csvUploadResponse{}, err
+	}
+	headers, rows := sheets[0].rows[0], sheets[0].rows[1:]
+
+	auditFields := uploadAuditFields(archived, filename)
+	auditFields["exam_id"] = exam.ID
+	auditFields["mode"] = write.Mode
+
+	scores, errorsList := h.buildScoresFromRows(ctx, exam.ID, exam, headers, rows, progress)
+
+	if len(errorsList) > 0 {
+		auditFields["errors"] = len(errorsList)
+		auditLog(ctx, "scores.bulk_rejected", user, auditFields)
+		return csvUploadResponse{Inserted: 0, Errors: errorsList}, nil
+	}
+
+	result, err := h.Store.SaveScores(ctx, exam.ID, scores, write)
+	if errors.Is(err, store.ErrExamLocked) {
+		return csvUploadResponse{}, &importError{http.StatusConflict, "exam is locked"}
+	}
+	if errors.Is(err, store.ErrSubjectLocked) {
+		return csvUploadResponse{}, &importError{http.StatusConflict, "subject is locked"}
+	}
+	if err != nil {
+		return csvUploadResponse{}, &importError{http.StatusInternalServerError, "failed to add scores"}
+	}
+	h.scoresAdded(ctx, user, exam, "Scores uploaded", "Bulk scores have been published by school staff.")
+	auditFields["count"] = len(scores)
+	auditFields["created"] = result.Created
+	auditFields["updated"] = result.Updated
+	auditFields["unchanged"] = result.Unchanged
+	auditFields["deleted"] = result.Deleted
+	auditFields["reason"] = write.Reason
+	auditLog(ctx, "scores.created.bulk", user, auditFields)
+
+	return csvUploadResponse{
+		Inserted:  result.Created,
+		Updated:   result.Updated,
+		Unchanged: result.Unchanged,
+		Deleted:   result.Deleted,
+		Mode:      write.Mode,
+		Errors:    nil,
+	}, nil
+}
 
-	if err := h.Store.AddScores(r.Context(), examID, scores); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to add scores")
+// scoresAdded moves a draft exam on to scores_entered. Parents only hear
+// about new scores when the exam's results are already published.
+func (h ScoresHandler) scoresAdded(ctx context.Context, user *models.User, exam *models.Exam, title, body string) {
+	if exam.Status == models.ExamDraft {
+		examScoresEntered(ctx, h.Store, h.Notifier, user, exam)
 		return
 	}
-	_ = h.Notifier.SendToSchoolParents(r.Context(), user.SchoolID, "Scores uploaded", "Bulk scores have been published by school staff.", map[string]string{
-		"type":    "score_upload",
-		"exam_id": examID,
-	})
-	auditLog(r.Context(), "scores.created.bulk", user, map[string]interface{}{
-		"exam_id": examID,
-		"count":   len(scores),
-	})
+	if exam.Status == models.ExamResultsPublished {
+		_ = h.Notifier.SendToClassGuardians(ctx, user.SchoolID, exam.Class, title, body, map[string]string{
+			"type":    "score_upload",
+			"exam_id": exam.ID,
+		})
+	}
+}
 
-	writeJSON(w, http.StatusCreated, csvUploadResponse{Inserted: len(scores), Errors: nil})
+// matrixInfoColumns are the normalized headers of a matrix sheet that are
+// not subjects: the student columns, and the summary columns exports add.
+var matrixInfoColumns = map[string]bool{
+	"roll": true, "admissionno": true, "studentname": true,
+	"total": true, "maxtotal": true, "percentage": true, "grade": true, "rank": true,
 }
 
 func (h ScoresHandler) buildScoresFromRows(
@@ -209,14 +414,16 @@ func (h ScoresHandler) buildScoresFromRows(
 	exam *models.Exam,
 	headers []string,
 	rows [][]string,
+	progress jobs.Progress,
 ) ([]models.Score, []string) {
 	headerIndex := map[string]int{}
 	for i, value := range headers {
 		headerIndex[normalizeHeader(value)] = i
 	}
 
-	if !hasHeader(headerIndex, "roll") {
-		return nil, []string{"missing required column: roll"}
+	hasAdmissionNo := hasHeader(headerIndex, "admission_no")
+	if !hasHeader(headerIndex, "roll") && !hasAdmissionNo {
+		return nil, []string{"missing required column: roll or admission_no"}
 	}
 
 	isRowBased := hasHeader(headerIndex, "subject")
@@ -224,34 +431,81 @@ func (h ScoresHandler) buildScoresFromRows(
 		return nil, []string{"missing required column: score"}
 	}
 
+	var admissionNos []string
+	if hasAdmissionNo {
+		admissionNos = sheetAdmissionNos(rows, headerIndex)
+	}
+	roster, err := loadScoreRoster(ctx, h.Store, exam, admissionNos)
+	if err != nil {
+		return nil, []string{"failed to load class roster"}
+	}
+	bands, err := examGradeBands(ctx, h.Store, exam)
+	if err != nil {
+		return nil, []string{"failed to load grading scheme"}
+	}
+	subjects, err := loadScoreSubjects(ctx, h.Store, exam)
+	if err != nil {
+		return nil, []string{"failed to load subjects"}
+	}
+
 	errorsList := []string{}
 	var scores []models.Score
-
-	for idx, record := range rows {
-		rowNumber := idx + 2 // header is row 1
-		if len(record) == 0 {
-			continue
+	firstRow := map[string]int{}
+	addScore := func(rowNumber int, score models.Score) {
+		score.Subject = strings.TrimSpace(score.Subject)
+		key := score.StudentID + "\x00" + strings.ToLower(score.Subject)
+		if first, ok := firstRow[key]; ok {
+			errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": duplicate "+score.Subject+" score (first in row "+strconv.Itoa(first)+")")
+			return
 		}
-
-		roll := getCell(record, headerIndex, "roll")
-		if roll == "" {
-			errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": missing roll")
-			continue
+		firstRow[key] = rowNumber
+		if !grading.HasMarks(score.Status) {
+			score.Score, score.Grade = 0, ""
+			scores = append(scores, score)
+			return
 		}
-
-		rollNumberValue, err := strconv.Atoi(roll)
-		if err != nil {
-			errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": invalid roll")
-			continue
+		if err := checkScoreRange(score.Score, score.MaxScore); err != nil {
+			errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": "+score.Subject+": "+err.Error())
+			return
+		}
+		if bands != nil {
+			grade, err := grading.Resolve(bands, score.Score, score.MaxScore, score.Grade)
+			if err != nil {
+				errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": "+score.Subject+": "+err.Error())
+				return
+			}
+			score.Grade = grade
 		}
+		scores = append(scores, score)
+	}
+
+	// Matrix sheets have one column per subject; match each header to the
+	// catalogue once rather than on every row.
+	headerRows := 1
+	var columns []matrixColumn
+	if !isRowBased {
+		if len(rows) > 0 && isMaxMarksRow(rows[0], headerIndex) {
+			columns, errorsList = matrixColumns(headers, rows[0], subjects)
+			rows = rows[1:]
+			headerRows++
+		} else {
+			columns, errorsList = matrixColumns(headers, nil, subjects)
+		}
+		if len(errorsList) > 0 {
+			return nil, errorsList
+		}
+	}
 
-		student, err := h.Store.GetStudentByClassRoll(ctx, exam.SchoolID, exam.Class, rollNumberValue)
-		if err != nil {
-			errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": student lookup failed")
+	for idx, record := range rows {
+		rowNumber := idx + headerRows + 1
+		progress(idx, len(rows))
+		if len(record) == 0 {
 			continue
 		}
-		if student == nil {
-			errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": student not found")
+
+		student, rowErr := roster.studentForRow(record, headerIndex, hasAdmissionNo)
+		if rowErr != "" {
+			errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": "+rowErr)
 			continue
 		}
 
@@ -266,245 +520,202 @@ func (h ScoresHandler) buildScoresFromRows(
 				continue
 			}
 
-			scoreFloat, err := strconv.ParseFloat(scoreValue, 32)
+			scoreFloat, status, err := parseScoreCell(scoreValue)
 			if err != nil {
-				errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": invalid score")
+				errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": "+err.Error())
 				continue
 			}
 
-			maxFloat := float32(100)
+			var maxFloat float32
 			if maxValue != "" {
 				maxParsed, err := strconv.ParseFloat(maxValue, 32)
-				if err != nil {
+				if err != nil || maxParsed <= 0 || !isFinite(float32(maxParsed)) {
 					errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": invalid max_score")
 					continue
 				}
 				maxFloat = float32(maxParsed)
 			}
+			subject, maxFloat, err = subjects.resolve(subject, maxFloat)
+			if err != nil {
+				errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": "+err.Error())
+				continue
+			}
 
-			scores = append(scores, models.Score{
+			addScore(rowNumber, models.Score{
 				ExamID:    examID,
 				StudentID: student.ID,
 				Subject:   subject,
-				Score:     float32(scoreFloat),
+				Score:     scoreFloat,
 				MaxScore:  maxFloat,
 				Grade:     grade,
+				Status:    status,
 			})
 			continue
 		}
 
-		for headerName, colIdx := range headerIndex {
-			if headerName == "roll" || headerName == "studentname" {
-				continue
+		for _, column := range columns {
+			if column.index >= len(record) {
+				break
 			}
-			value := getCell(record, headerIndex, headerName)
+			value := strings.TrimSpace(record[column.index])
 			if value == "" {
 				continue
 			}
-			scoreFloat, err := strconv.ParseFloat(value, 32)
+			scoreFloat, status, err := parseScoreCell(value)
 			if err != nil {
-				errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": invalid score for "+headerName)
+				errorsList = append(errorsList, "row "+strconv.Itoa(rowNumber)+": "+column.subject+": "+err.Error())
 				continue
 			}
-			scores = append(scores, models.Score{
+			addScore(rowNumber, models.Score{
 				ExamID:    examID,
 				StudentID: student.ID,
-				Subject:   headers[colIdx],
-				Score:     float32(scoreFloat),
-				MaxScore:  100,
-				Grade:     "",
+				Subject:   column.subject,
+				Score:     scoreFloat,
+				MaxScore:  column.max,
+				Status:    status,
 			})
 		}
 	}
 
+	progress(len(rows), len(rows))
 	return scores, errorsList
 }
 
-func getCell(record []string, headerIndex map[string]int, key string) string {
-	idx, ok := headerIndex[normalizeHeader(key)]
-	if !ok || idx >= len(record) {
-		return ""
-	}
-	return strings.TrimSpace(record[idx])
+// matrixColumn is a subject column of a matrix sheet.
+type matrixColumn struct {
+	index   int
+	subject string
+	max     float32
 }
 
-func normalizeHeader(value string) string {
-	normalized := strings.ToLower(strings.TrimSpace(value))
-	normalized = strings.ReplaceAll(normalized, " ", "")
-	normalized = strings.ReplaceAll(normalized, "_", "")
-	return normalized
-}
+// subjectMaxPattern matches a "Subject (max)" header such as "Maths (80)" or
+// "Maths (out of 80)".
+var subjectMaxPattern = regexp.MustCompile(`^(.*?)\s*\(\s*(?i:max(?:imum)?(?:\s*marks)?|out\s+of)?\s*[:.]?\s*(\d+(?:\.\d+)?)\s*\)$`)
 
-func hasHeader(headers map[string]int, key string) bool {
-	_, ok := headers[normalizeHeader(key)]
-	return ok
+// maxMarksLabels are what the student columns of a max marks row may say,
+// normalized like headers.
+var maxMarksLabels = map[string]bool{
+	"max": true, "maxmarks": true, "maxscore": true, "maximum": true, "maximummarks": true,
+	"outof": true, "fullmarks": true,
 }
 
-func parseXLSXRows(reader io.Reader) ([][]string, error) {
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, err
-	}
-
-	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
-	if err != nil {
-		return nil, err
-	}
-
-	files := map[string]*zip.File{}
-	for _, f := range zr.File {
-		files[f.Name] = f
-	}
-
-	sharedStrings, _ := readSharedStrings(files["xl/sharedStrings.xml"])
-
-	sheetPath := ""
-	if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
-		sheetPath = "xl/worksheets/sheet1.xml"
-	} else {
-		var candidates []string
-		for name := range files {
-			if strings.HasPrefix(name, "xl/worksheets/") && strings.HasSuffix(name, ".xml") {
-				candidates = append(candidates, name)
-			}
+// isMaxMarksRow reports whether the row under a matrix header gives each
+// subject's maximum rather than a student's marks: its student columns are
+// empty or say something like "Max marks".
+func isMaxMarksRow(record []string, headerIndex map[string]int) bool {
+	for _, key := range []string{"roll", "admission_no", "student_name"} {
+		value := strings.Trim(strings.ReplaceAll(normalizeHeader(getCell(record, headerIndex, key)), ".", ""), ":")
+		if value != "" && !maxMarksLabels[value] {
+			return false
 		}
-		sort.Strings(candidates)
-		if len(candidates) == 0 {
-			return nil, fmt.Errorf("xlsx has no worksheets")
-		}
-		sheetPath = candidates[0]
 	}
-
-	return readSheetRows(files[sheetPath], sharedStrings)
+	return true
 }
 
-func readSharedStrings(file *zip.File) ([]string, error) {
-	if file == nil {
-		return nil, nil
-	}
-	rc, err := file.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer rc.Close()
-
-	type richRun struct {
-		T string `xml:"t"`
-	}
-	type si struct {
-		T string    `xml:"t"`
-		R []richRun `xml:"r"`
-	}
-	var doc struct {
-		Items []si `xml:"si"`
-	}
-	if err := xml.NewDecoder(rc).Decode(&doc); err != nil {
-		return nil, err
-	}
-
-	out := make([]string, 0, len(doc.Items))
-	for _, item := range doc.Items {
-		if item.T != "" {
-			out = append(out, item.T)
+// matrixColumns resolves the subject columns of a matrix sheet in sheet
+// order. A column's maximum comes from a "Subject (max)" header or from
+// maxRow, which may be nil; they must agree when both give one. Without
+// either the exam's declared maximum, or 100, applies.
+func matrixColumns(headers, maxRow []string, subjects *scoreSubjects) ([]matrixColumn, []string) {
+	var columns []matrixColumn
+	var errorsList []string
+	seen := map[string]string{}
+	for i, header := range headers {
+		header = strings.TrimSpace(header)
+		if header == "" || matrixInfoColumns[normalizeHeader(header)] {
 			continue
 		}
-		var b strings.Builder
-		for _, run := range item.R {
-			b.WriteString(run.T)
-		}
-		out = append(out, b.String())
-	}
-	return out, nil
-}
-
-func readSheetRows(file *zip.File, sharedStrings []string) ([][]string, error) {
-	if file == nil {
-		return nil, fmt.Errorf("worksheet file missing")
-	}
-	rc, err := file.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer rc.Close()
-
-	type inlineStr struct {
-		T string `xml:"t"`
-	}
-	type cell struct {
-		R  string     `xml:"r,attr"`
-		T  string     `xml:"t,attr"`
-		V  string     `xml:"v"`
-		IS *inlineStr `xml:"is"`
-	}
-	type row struct {
-		Cells []cell `xml:"c"`
-	}
-	var ws struct {
-		Rows []row `xml:"sheetData>row"`
-	}
-	if err := xml.NewDecoder(rc).Decode(&ws); err != nil {
-		return nil, err
-	}
-
-	var result [][]string
-	for _, r := range ws.Rows {
-		maxCol := -1
-		values := map[int]string{}
-		for _, c := range r.Cells {
-			col := cellColumnIndex(c.R)
-			if col > maxCol {
-				maxCol = col
+		name, max := header, float32(0)
+		if m := subjectMaxPattern.FindStringSubmatch(header); m != nil && m[1] != "" {
+			parsed, err := strconv.ParseFloat(m[2], 32)
+			if err != nil || parsed <= 0 {
+				errorsList = append(errorsList, "column "+header+": max marks must be positive")
+				continue
 			}
-			switch c.T {
-			case "s":
-				idx, parseErr := strconv.Atoi(strings.TrimSpace(c.V))
-				if parseErr == nil && idx >= 0 && idx < len(sharedStrings) {
-					values[col] = sharedStrings[idx]
-				} else {
-					values[col] = c.V
+			name, max = m[1], float32(parsed)
+		}
+		if i < len(maxRow) {
+			if value := strings.TrimSpace(maxRow[i]); value != "" {
+				parsed, err := strconv.ParseFloat(value, 32)
+				if err != nil || parsed <= 0 || !isFinite(float32(parsed)) {
+					errorsList = append(errorsList, "row 2: invalid max marks for "+name)
+					continue
 				}
-			case "inlineStr":
-				if c.IS != nil {
-					values[col] = c.IS.T
+				if max != 0 && float32(parsed) != max {
+					errorsList = append(errorsList, "column "+header+": max marks row says "+value+" but the header says "+formatMarks(max))
+					continue
 				}
-			default:
-				values[col] = c.V
+				max = float32(parsed)
 			}
 		}
-		if maxCol < 0 {
-			result = append(result, []string{})
+		subject, max, err := subjects.resolve(name, max)
+		if err != nil {
+			errorsList = append(errorsList, "column "+header+": "+err.Error())
 			continue
 		}
-		rowValues := make([]string, maxCol+1)
-		for idx, val := range values {
-			rowValues[idx] = strings.TrimSpace(val)
+		key := strings.ToLower(subject)
+		if first, ok := seen[key]; ok {
+			errorsList = append(errorsList, "column "+header+": "+subject+" is already in column "+first)
+			continue
 		}
-		result = append(result, rowValues)
+		seen[key] = header
+		columns = append(columns, matrixColumn{index: i, subject: subject, max: max})
 	}
-	return result, nil
+	return columns, errorsList
 }
 
-func cellColumnIndex(ref string) int {
-	if ref == "" {
-		return 0
+// parseScoreCell reads the marks in a score cell, or a status code such as
+// AB for a paper the student did not sit.
+func parseScoreCell(value string) (float32, string, error) {
+	if status, ok := grading.ParseStatus(value); ok {
+		return 0, status, nil
 	}
-	col := 0
-	for _, ch := range ref {
-		if ch >= '0' && ch <= '9' {
-			break
-		}
-		if ch >= 'a' && ch <= 'z' {
-			ch = ch - 'a' + 'A'
-		}
-		if ch < 'A' || ch > 'Z' {
-			continue
-		}
-		col = col*26 + int(ch-'A'+1)
+	score, err := strconv.ParseFloat(value, 32)
+	if err != nil || !isFinite(float32(score)) {
+		return 0, "", errors.New("invalid score " + strconv.Quote(value) + " (use marks, AB, ML or EX)")
+	}
+	return float32(score), models.ScorePresent, nil
+}
+
+// isFinite rejects the NaN and infinities strconv accepts as numbers, which
+// would otherwise slip past the range checks.
+func isFinite(value float32) bool {
+	return !math.IsNaN(float64(value)) && !math.IsInf(float64(value), 0)
+}
+
+// scoreStatus reads the status of a score entered by hand: a status name, a
+// code such as AB, or empty for present.
+func scoreStatus(value string) (string, error) {
+	switch status := strings.ToLower(strings.TrimSpace(value)); status {
+	case "":
+		return models.ScorePresent, nil
+	case models.ScorePresent, models.ScoreAbsent, models.ScoreMedicalLeave, models.ScoreExempt:
+		return status, nil
 	}
-	if col == 0 {
-		return 0
+	if status, ok := grading.ParseStatus(value); ok {
+		return status, nil
+	}
+	return "", errors.New("status must be present, absent, medical_leave or exempt")
+}
+
+func getCell(record []string, headerIndex map[string]int, key string) string {
+	idx, ok := headerIndex[normalizeHeader(key)]
+	if !ok || idx >= len(record) {
+		return ""
 	}
-	return col - 1
+	return strings.TrimSpace(record[idx])
+}
+
+func normalizeHeader(value string) string {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	normalized = strings.ReplaceAll(normalized, " ", "")
+	normalized = strings.ReplaceAll(normalized, "_", "")
+	return normalized
+}
+
+func hasHeader(headers map[string]int, key string) bool {
+	_, ok := headers[normalizeHeader(key)]
+	return ok
 }
 
 func (h ScoresHandler) ListByStudent(w http.ResponseWriter, r *http.Request) {
@@ -532,10 +743,37 @@ func (h ScoresHandler) ListByStudent(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	items, err := h.Store.ListScoresByStudent(r.Context(), studentID)
+	items, err := h.Store.ListScoresByStudent(r.Context(), studentID, hasRole(user, models.RoleParent))
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to list scores")
 		return
 	}
 	writeJSON(w, http.StatusOK, items)
 }
+
+// History lists every change to a student's scores in an exam, newest first.
+func (h ScoresHandler) History(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	exam, ok := loadSchoolExam(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("student"))
+	if !ok {
+		return
+	}
+	items, err := h.Store.ListScoreRevisions(r.Context(), exam.ID, student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list score history")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
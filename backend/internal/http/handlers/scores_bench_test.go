@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/db"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// benchSubjects are the subject columns of the benchmark sheet.
+var benchSubjects = []string{"English", "Hindi", "Mathematics", "Science", "Social Science", "Sanskrit"}
+
+// BenchmarkMatrixImport measures what an upload request spends on a 1,000
+// row matrix sheet: resolving the rows and saving the scores. Every
+// iteration changes every mark, so SaveScores writes a revision for each.
+// It needs a migrated database in TEST_DATABASE_URL; the students and exam
+// it creates are removed afterwards.
+func BenchmarkMatrixImport(b *testing.B) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		b.Skip("TEST_DATABASE_URL is not set")
+	}
+	conn, err := db.Open(databaseURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	s := store.New(conn)
+
+	schoolID, err := s.FirstSchoolID(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	class := "bench-" + uuid.NewString()[:8]
+	defer func() {
+		conn.ExecContext(ctx, `DELETE FROM scores WHERE exam_id IN (SELECT id FROM exams WHERE school_id = $1 AND class = $2)`, schoolID, class)
+		conn.ExecContext(ctx, `DELETE FROM exams WHERE school_id = $1 AND class = $2`, schoolID, class)
+		conn.ExecContext(ctx, `DELETE FROM students WHERE school_id = $1 AND class_label = $2`, schoolID, class)
+	}()
+
+	const students = 1000
+	for roll := 1; roll <= students; roll++ {
+		_, err := s.CreateStudent(ctx, models.Student{
+			SchoolID:      schoolID,
+			FullName:      "Bench Student " + strconv.Itoa(roll),
+			ClassLabel:    class,
+			RollNumber:    roll,
+			DateOfBirth:   time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+			AdmissionYear: 2024,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	exam, err := s.CreateExam(ctx, models.Exam{
+		SchoolID: schoolID,
+		Class:    class,
+		Title:    "Benchmark",
+		Term:     "Term 1",
+		Date:     time.Now(),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	headers := append([]string{"Roll", "Student Name"}, benchSubjects...)
+	sheets := [2][][]string{}
+	for n := range sheets {
+		for roll := 1; roll <= students; roll++ {
+			row := []string{strconv.Itoa(roll), "Bench Student " + strconv.Itoa(roll)}
+			for i := range benchSubjects {
+				row = append(row, strconv.Itoa((roll+i+n*7)%100))
+			}
+			sheets[n] = append(sheets[n], row)
+		}
+	}
+
+	h := ScoresHandler{Store: s}
+	write := store.ScoreWrite{Mode: store.ScoreModeMerge}
+	progress := func(int, int) {}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scores, errorsList := h.buildScoresFromRows(ctx, exam.ID, exam, headers, sheets[i%2], progress)
+		if len(errorsList) > 0 {
+			b.Fatal(errorsList[0])
+		}
+		if len(scores) != students*len(benchSubjects) {
+			b.Fatalf("got %d scores, want %d", len(scores), students*len(benchSubjects))
+		}
+		if _, err := s.SaveScores(ctx, exam.ID, scores, write); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/db"
+	"jnv/backend/internal/export"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// TestExportMatrixRoundTrip uploads an exported matrix sheet back and checks
+// it reads as the scores it was exported from. It needs a migrated database
+// in TEST_DATABASE_URL; the students and exam it creates are removed
+// afterwards.
+func TestExportMatrixRoundTrip(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	conn, err := db.Open(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	s := store.New(conn)
+
+	schoolID, err := s.FirstSchoolID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	class := "test-" + uuid.NewString()[:8]
+	defer func() {
+		conn.ExecContext(ctx, `DELETE FROM scores WHERE exam_id IN (SELECT id FROM exams WHERE school_id = $1 AND class = $2)`, schoolID, class)
+		conn.ExecContext(ctx, `DELETE FROM exams WHERE school_id = $1 AND class = $2`, schoolID, class)
+		conn.ExecContext(ctx, `DELETE FROM students WHERE school_id = $1 AND class_label = $2`, schoolID, class)
+	}()
+
+	var students []models.Student
+	for roll := 1; roll <= 4; roll++ {
+		student, err := s.CreateStudent(ctx, models.Student{
+			SchoolID:      schoolID,
+			FullName:      "Test Student " + strconv.Itoa(roll),
+			ClassLabel:    class,
+			RollNumber:    roll,
+			AdmissionNo:   class + "/" + strconv.Itoa(roll),
+			DateOfBirth:   time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+			AdmissionYear: 2024,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		students = append(students, *student)
+	}
+	exam, err := s.CreateExam(ctx, models.Exam{
+		SchoolID: schoolID,
+		Class:    class,
+		Title:    "Round Trip",
+		Term:     "Term 1",
+		Date:     time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score := func(student int, subject string, marks, max float32, status string) models.Score {
+		return models.Score{ExamID: exam.ID, StudentID: students[student].ID, Subject: subject, Score: marks, MaxScore: max, Status: status}
+	}
+	want := []models.Score{
+		score(0, "English", 71.5, 80, models.ScorePresent),
+		score(0, "Mathematics", 18, 20, models.ScorePresent),
+		score(1, "English", 0, 80, models.ScoreAbsent),
+		score(1, "Mathematics", 17.25, 20, models.ScorePresent),
+		score(2, "English", 64, 80, models.ScorePresent),
+		score(2, "Mathematics", 0, 20, models.ScoreMedicalLeave),
+		score(3, "English", 0, 80, models.ScoreExempt),
+	}
+
+	table := export.Exam(*exam, students, want, nil, export.LayoutMatrix)
+	var buf bytes.Buffer
+	if err := table.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := ScoresHandler{Store: s}
+	got, errorsList := h.buildScoresFromRows(ctx, exam.ID, exam, records[0], records[1:], noProgress)
+	if len(errorsList) > 0 {
+		t.Fatalf("exported sheet rejected: %v", errorsList)
+	}
+	// The school's grading scheme, if any, grades the marks on the way in.
+	for i := range got {
+		got[i].Grade = ""
+	}
+	sortScores := func(scores []models.Score) {
+		sort.Slice(scores, func(i, j int) bool {
+			if scores[i].StudentID != scores[j].StudentID {
+				return scores[i].StudentID < scores[j].StudentID
+			}
+			return scores[i].Subject < scores[j].Subject
+		})
+	}
+	sortScores(got)
+	sortScores(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip =\n%+v\nwant\n%+v", got, want)
+	}
+}
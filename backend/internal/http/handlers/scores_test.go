@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"testing"
+
+	"jnv/backend/internal/models"
+)
+
+func TestParseScoreCell(t *testing.T) {
+	tests := []struct {
+		value  string
+		score  float32
+		status string
+		ok     bool
+	}{
+		{"42", 42, models.ScorePresent, true},
+		{"17.5", 17.5, models.ScorePresent, true},
+		{"AB", 0, models.ScoreAbsent, true},
+		{"ml", 0, models.ScoreMedicalLeave, true},
+		{"EX", 0, models.ScoreExempt, true},
+		{"NaN", 0, "", false},
+		{"Inf", 0, "", false},
+		{"-Infinity", 0, "", false},
+		{"1e39", 0, "", false},
+		{"forty", 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			score, status, err := parseScoreCell(tt.value)
+			if (err == nil) != tt.ok {
+				t.Fatalf("parseScoreCell(%q) error = %v, want ok %v", tt.value, err, tt.ok)
+			}
+			if score != tt.score || status != tt.status {
+				t.Errorf("parseScoreCell(%q) = %v, %q; want %v, %q", tt.value, score, status, tt.score, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/notify"
+	"jnv/backend/internal/store"
+)
+
+type HealthHandler struct {
+	Store    *store.Store
+	Notifier notify.Sender
+}
+
+type healthProfileRequest struct {
+	BloodGroup        string `json:"blood_group"`
+	Allergies         string `json:"allergies"`
+	ChronicConditions string `json:"chronic_conditions"`
+	Medications       string `json:"medications"`
+	Notes             string `json:"notes"`
+}
+
+type infirmaryVisitRequest struct {
+	VisitedAt string `json:"visited_at"`
+	Complaint string `json:"complaint"`
+	Diagnosis string `json:"diagnosis"`
+	Treatment string `json:"treatment"`
+	Status    string `json:"status"`
+}
+
+type dischargeRequest struct {
+	Notes string `json:"notes"`
+}
+
+var bloodGroups = map[string]bool{
+	"A+": true, "A-": true, "B+": true, "B-": true,
+	"AB+": true, "AB-": true, "O+": true, "O-": true,
+}
+
+// Visit outcomes. "admitted" stays open until the student is discharged.
+var infirmaryVisitStatuses = map[string]bool{
+	"treated":  true,
+	"admitted": true,
+	"referred": true,
+}
+
+// schoolTimezone is used to decide which calendar day a visit belongs to.
+var schoolTimezone = time.FixedZone("IST", 5*60*60+30*60)
+
+const maxVisitHistory = 200
+
+// authorizeHealthView limits medical records to nurses and admins of the
+// student's school, and to parents with an approved link to the student.
+func authorizeHealthView(w http.ResponseWriter, r *http.Request, s *store.Store, user *models.User, studentID string) (*models.Student, bool) {
+	if hasRole(user, models.RoleParent) {
+		return authorizeStudentView(w, r, s, user, studentID)
+	}
+	if !hasRole(user, models.RoleNurse, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return nil, false
+	}
+	return loadSchoolStudent(w, r, s, user, studentID)
+}
+
+func (h HealthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := authorizeHealthView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	profile, err := h.Store.GetHealthProfile(r.Context(), student.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load health profile")
+		return
+	}
+	if profile == nil {
+		profile = &models.HealthProfile{StudentID: student.ID, SchoolID: student.SchoolID}
+	}
+	w.Header().Set("Cache-Control", "private, no-store")
+	writeJSON(w, http.StatusOK, profile)
+}
+
+func (h HealthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleNurse, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	var req healthProfileRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	bloodGroup := strings.ToUpper(strings.ReplaceAll(req.BloodGroup, " ", ""))
+	if bloodGroup != "" && !bloodGroups[bloodGroup] {
+		writeError(w, http.StatusBadRequest, "blood_group must be one of A+, A-, B+, B-, AB+, AB-, O+, O-")
+		return
+	}
+
+	profile, err := h.Store.UpsertHealthProfile(r.Context(), models.HealthProfile{
+		StudentID:         student.ID,
+		SchoolID:          student.SchoolID,
+		BloodGroup:        bloodGroup,
+		Allergies:         strings.TrimSpace(req.Allergies),
+		ChronicConditions: strings.TrimSpace(req.ChronicConditions),
+		Medications:       strings.TrimSpace(req.Medications),
+		Notes:             strings.TrimSpace(req.Notes),
+		UpdatedBy:         user.ID,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save health profile")
+		return
+	}
+	// Medical details stay out of the audit trail; only the fact of the change.
+	auditLog(r.Context(), "health_profile.updated", user, map[string]interface{}{
+		"student_id": student.ID,
+	})
+	writeJSON(w, http.StatusOK, profile)
+}
+
+func (h HealthHandler) ListVisits(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	student, ok := authorizeHealthView(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	items, err := h.Store.ListInfirmaryVisitsByStudent(r.Context(), student.ID, maxVisitHistory)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list visits")
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-store")
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h HealthHandler) RecordVisit(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleNurse, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	var req infirmaryVisitRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	complaint := strings.TrimSpace(req.Complaint)
+	if complaint == "" {
+		writeError(w, http.StatusBadRequest, "complaint is required")
+		return
+	}
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	if status == "" {
+		status = "treated"
+	}
+	if !infirmaryVisitStatuses[status] {
+		writeError(w, http.StatusBadRequest, "status must be treated, admitted or referred")
+		return
+	}
+	var visitedAt time.Time
+	if raw := strings.TrimSpace(req.VisitedAt); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "visited_at must be RFC 3339")
+			return
+		}
+		if parsed.After(time.Now().Add(5 * time.Minute)) {
+			writeError(w, http.StatusBadRequest, "visited_at is in the future")
+			return
+		}
+		visitedAt = parsed
+	}
+
+	visit, err := h.Store.CreateInfirmaryVisit(r.Context(), models.InfirmaryVisit{
+		SchoolID:   student.SchoolID,
+		StudentID:  student.ID,
+		VisitedAt:  visitedAt,
+		Complaint:  complaint,
+		Diagnosis:  strings.TrimSpace(req.Diagnosis),
+		Treatment:  strings.TrimSpace(req.Treatment),
+		Status:     status,
+		RecordedBy: user.ID,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record visit")
+		return
+	}
+	visit.StudentName = student.FullName
+	visit.ClassLabel = student.ClassLabel
+
+	// The push text says only what happened, not why; details are in the app.
+	body := student.FullName + " visited the school infirmary and was treated."
+	switch status {
+	case "admitted":
+		body = student.FullName + " has been admitted to the school infirmary."
+	case "referred":
+		body = student.FullName + " has been referred to a hospital by the school infirmary."
+	}
+	_ = h.Notifier.SendToStudentGuardians(r.Context(), student.ID, "Infirmary visit", body, map[string]string{
+		"type":       "infirmary_visit",
+		"student_id": student.ID,
+		"visit_id":   visit.ID,
+		"status":     status,
+	})
+	auditLog(r.Context(), "infirmary_visit.recorded", user, map[string]interface{}{
+		"student_id": student.ID,
+		"visit_id":   visit.ID,
+		"status":     status,
+	})
+	writeJSON(w, http.StatusCreated, visit)
+}
+
+func (h HealthHandler) Discharge(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleNurse, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	visitID := r.PathValue("id")
+	if _, err := uuid.Parse(visitID); err != nil {
+		writeError(w, http.StatusNotFound, "visit not found")
+		return
+	}
+	var req dischargeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	visit, err := h.Store.GetInfirmaryVisit(r.Context(), visitID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load visit")
+		return
+	}
+	if visit == nil || visit.SchoolID != user.SchoolID {
+		writeError(w, http.StatusNotFound, "visit not found")
+		return
+	}
+	if err := h.Store.DischargeInfirmaryVisit(r.Context(), visit.ID, strings.TrimSpace(req.Notes)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusConflict, "student is not currently admitted")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to discharge")
+		return
+	}
+
+	_ = h.Notifier.SendToStudentGuardians(r.Context(), visit.StudentID, "Infirmary discharge",
+		visit.StudentName+" has been discharged from the school infirmary.", map[string]string{
+			"type":       "infirmary_visit",
+			"student_id": visit.StudentID,
+			"visit_id":   visit.ID,
+			"status":     "discharged",
+		})
+	auditLog(r.Context(), "infirmary_visit.discharged", user, map[string]interface{}{
+		"student_id": visit.StudentID,
+		"visit_id":   visit.ID,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "discharged"})
+}
+
+// Summary is the nurse's daily sheet: everyone currently admitted, plus the
+// number of visits and referrals on the given day (default today).
+func (h HealthHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleNurse, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+
+	day := time.Now().In(schoolTimezone)
+	if raw := strings.TrimSpace(r.URL.Query().Get("date")); raw != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", raw, schoolTimezone)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+			return
+		}
+		day = parsed
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, schoolTimezone)
+	to := from.AddDate(0, 0, 1)
+
+	admitted, err := h.Store.ListAdmittedStudents(r.Context(), user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load admitted students")
+		return
+	}
+	visits, referred, err := h.Store.CountInfirmaryVisits(r.Context(), user.SchoolID, from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to count visits")
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-store")
+	writeJSON(w, http.StatusOK, models.InfirmarySummary{
+		Date:          from.Format("2006-01-02"),
+		VisitCount:    visits,
+		ReferredCount: referred,
+		AdmittedCount: len(admitted),
+		Admitted:      admitted,
+	})
+}
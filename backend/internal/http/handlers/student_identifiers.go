@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+type studentIdentifiersRequest struct {
+	AdmissionNo string `json:"admission_no"`
+	APAARID     string `json:"apaar_id"`
+	PEN         string `json:"pen"`
+}
+
+var (
+	admissionNoPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9/-]{0,31}$`)
+	apaarIDPattern     = regexp.MustCompile(`^[0-9]{12}$`)
+	penPattern         = regexp.MustCompile(`^[0-9]{11}$`)
+)
+
+// normalizeStudentIdentifiers canonicalises the identifiers: admission
+// numbers are upper-cased, and spaces and hyphens are dropped from the
+// numeric APAAR ID (12 digits) and PEN (11 digits).
+func normalizeStudentIdentifiers(admissionNo, apaarID, pen string) (string, string, string, error) {
+	admissionNo = strings.ToUpper(strings.TrimSpace(admissionNo))
+	if admissionNo != "" && !admissionNoPattern.MatchString(admissionNo) {
+		return "", "", "", &validationError{message: "admission_no may contain only letters, digits, '/' and '-' (max 32)"}
+	}
+	apaarID = stripIdentifierSeparators(apaarID)
+	if apaarID != "" && !apaarIDPattern.MatchString(apaarID) {
+		return "", "", "", &validationError{message: "apaar_id must be 12 digits"}
+	}
+	pen = stripIdentifierSeparators(pen)
+	if pen != "" && !penPattern.MatchString(pen) {
+		return "", "", "", &validationError{message: "pen must be 11 digits"}
+	}
+	return admissionNo, apaarID, pen, nil
+}
+
+func stripIdentifierSeparators(value string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(value))
+}
+
+// checkIdentifierConflicts reports an identifier that already belongs to a
+// student other than studentID (empty for a new student).
+func checkIdentifierConflicts(ctx context.Context, s *store.Store, schoolID, studentID, admissionNo, apaarID, pen string) error {
+	checks := []struct{ kind, value string }{
+		{"admission_no", admissionNo},
+		{"apaar_id", apaarID},
+		{"pen", pen},
+	}
+	for _, check := range checks {
+		if check.value == "" {
+			continue
+		}
+		existing, err := s.GetStudentByIdentifier(ctx, schoolID, check.kind, check.value)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != studentID {
+			return &validationError{message: check.kind + " already assigned to another student"}
+		}
+	}
+	return nil
+}
+
+func (h StudentsHandler) UpdateIdentifiers(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	student, ok := loadSchoolStudent(w, r, h.Store, user, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	var req studentIdentifiersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	admissionNo, apaarID, pen, err := normalizeStudentIdentifiers(req.AdmissionNo, req.APAARID, req.PEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkIdentifierConflicts(r.Context(), h.Store, user.SchoolID, student.ID, admissionNo, apaarID, pen); err != nil {
+		var vErr *validationError
+		if errors.As(err, &vErr) {
+			writeError(w, http.StatusConflict, vErr.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to validate identifiers")
+		return
+	}
+
+	if err := h.Store.UpdateStudentIdentifiers(r.Context(), student.ID, admissionNo, apaarID, pen); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "student not found")
+			return
+		}
+		writeError(w, http.StatusConflict, "failed to update identifiers (possible duplicate)")
+		return
+	}
+	auditLog(r.Context(), "student.identifiers.updated", user, map[string]interface{}{
+		"student_id":   student.ID,
+		"admission_no": admissionNo,
+		"apaar_id_set": apaarID != "",
+		"pen_set":      pen != "",
+	})
+	student.AdmissionNo, student.APAARID, student.PEN = admissionNo, apaarID, pen
+	writeJSON(w, http.StatusOK, student)
+}
@@ -1,32 +1,42 @@
 package handlers
 
 import (
-	"encoding/csv"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/jobs"
 	"jnv/backend/internal/models"
+	"jnv/backend/internal/storage"
 	"jnv/backend/internal/store"
 )
 
 type StudentsHandler struct {
 	Store *store.Store
+	Files storage.Bucket
+	// QueueImports sends archived uploads to the import workers. It is off
+	// when this instance runs none, and uploads are then imported inline.
+	QueueImports bool
 }
 
 type createStudentRequest struct {
-	FullName      string `json:"full_name"`
-	ClassLabel    string `json:"class_label"`
-	RollNumber    int    `json:"roll_number"`
-	DateOfBirth   string `json:"date_of_birth"`
-	House         string `json:"house"`
-	ParentPhone   string `json:"parent_phone"`
-	AdmissionYear int    `json:"admission_year"`
+	FullName      string            `json:"full_name"`
+	ClassLabel    string            `json:"class_label"`
+	RollNumber    int               `json:"roll_number"`
+	DateOfBirth   string            `json:"date_of_birth"`
+	House         string            `json:"house"`
+	ParentPhone   string            `json:"parent_phone"`
+	AdmissionYear int               `json:"admission_year"`
+	AdmissionNo   string            `json:"admission_no"`
+	APAARID       string            `json:"apaar_id"`
+	PEN           string            `json:"pen"`
+	Guardians     []guardianRequest `json:"guardians"`
 }
 
 type studentUploadResponse struct {
@@ -76,8 +86,33 @@ func (h StudentsHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if req.AdmissionYear <= 0 {
 		req.AdmissionYear = time.Now().Year()
 	}
+	admissionNo, apaarID, pen, err := normalizeStudentIdentifiers(req.AdmissionNo, req.APAARID, req.PEN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := checkIdentifierConflicts(r.Context(), h.Store, user.SchoolID, "", admissionNo, apaarID, pen); err != nil {
+		var vErr *validationError
+		if errors.As(err, &vErr) {
+			writeError(w, http.StatusConflict, vErr.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to validate identifiers")
+		return
+	}
 
-	student, err := h.Store.CreateStudent(r.Context(), models.Student{
+	var guardians []models.Guardian
+	for _, item := range req.Guardians {
+		guardian, err := item.toGuardian()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		guardians = append(guardians, guardian)
+	}
+	guardians, normalizedPhone = assignPrimaryGuardian(guardians, normalizedPhone)
+
+	student, err := h.Store.CreateStudentWithGuardians(r.Context(), models.Student{
 		SchoolID:      user.SchoolID,
 		FullName:      req.FullName,
 		ClassLabel:    req.ClassLabel,
@@ -86,9 +121,12 @@ func (h StudentsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		House:         req.House,
 		ParentPhone:   normalizedPhone,
 		AdmissionYear: req.AdmissionYear,
-	})
+		AdmissionNo:   admissionNo,
+		APAARID:       apaarID,
+		PEN:           pen,
+	}, guardians)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "failed to create student (possible duplicate roll number)")
+		writeError(w, http.StatusBadRequest, "failed to create student (possible duplicate roll number or identifier)")
 		return
 	}
 	writeJSON(w, http.StatusCreated, student)
@@ -119,118 +157,263 @@ func (h StudentsHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	var rowsData [][]string
-	switch ext {
-	case ".csv":
-		reader := csv.NewReader(file)
-		reader.TrimLeadingSpace = true
-		rowsData, err = reader.ReadAll()
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "failed to parse csv file")
-			return
-		}
-	case ".xlsx":
-		rowsData, err = parseXLSXRows(file)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "failed to parse xlsx file")
-			return
-		}
-	default:
-		writeError(w, http.StatusBadRequest, "supported file types: .csv, .xlsx")
+	data, contentType, status, err := readUpload(file, header, spreadsheetUploadPolicy)
+	if err != nil {
+		writeError(w, status, err.Error())
 		return
 	}
-	if len(rowsData) == 0 {
-		writeError(w, http.StatusBadRequest, "file is empty")
+	if err := checkSheetType(header.Filename); err != nil {
+		writeImportError(w, err)
 		return
 	}
 
-	headerIdx := buildStudentHeaderIndex(rowsData[0])
-	required := []string{"full_name", "class_label", "roll_number", "date_of_birth"}
-	for _, key := range required {
-		if _, ok := headerIdx[key]; !ok {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("missing required column: %s", key))
-			return
-		}
+	archived, err := archiveUpload(r.Context(), h.Files, user, "students", header.Filename, contentType, data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to archive upload")
+		return
+	}
+	if archived != nil && h.QueueImports {
+		queueImport(r.Context(), w, h.Store, user, ImportStudents, archived, header.Filename, sheetParams(r, nil))
+		return
+	}
+
+	// Without a bucket there is nothing for a worker to read the file from,
+	// and without workers nothing would claim the job, so the sheet is
+	// imported inline.
+	selection := sheetSelectionFromParams(sheetParams(r, nil))
+	resp, err := h.importSheet(r.Context(), user, header.Filename, selection, archived, data, noProgress)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+	status = http.StatusOK
+	if len(resp.Errors) > 0 {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, resp)
+}
+
+// importSheet validates and imports a student sheet. Row errors reject the
+// whole sheet and are returned in the response; err is for failures of the
+// sheet as a whole.
+func (h StudentsHandler) importSheet(ctx context.Context, user *models.User, filename string, selection sheetSelection, archived *storage.Object, data []byte, progress jobs.Progress) (studentUploadResponse, error) {
+	sheets, err := readSheets(filename, data, selection)
+	if err != nil {
+		return studentUploadResponse{}, err
+	}
+	total := 0
+	for _, sheet := range sheets {
+		total += len(sheet.rows) - 1
 	}
 
-	inserted := 0
+	// Every row is validated first, against the sheet itself and against the
+	// school's existing students in a single query. Only a clean sheet is
+	// imported, in one transaction. With one sheet per class, the sheets are
+	// checked and imported together, and a row without a class_label takes
+	// its sheet's name.
+	type studentRow struct {
+		label string
+		item  store.StudentImport
+	}
+	var parsed []studentRow
 	errorsList := []string{}
-	for i, row := range rowsData[1:] {
-		rowNum := i + 2
-		fullName := strings.TrimSpace(getStudentCell(row, headerIdx, "full_name"))
-		classLabel := strings.TrimSpace(getStudentCell(row, headerIdx, "class_label"))
-		rollRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "roll_number"))
-		dobRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "date_of_birth"))
-		house := strings.TrimSpace(getStudentCell(row, headerIdx, "house"))
-		parentPhoneRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "parent_phone"))
-		admissionYearRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "admission_year"))
-
-		if fullName == "" && classLabel == "" && rollRaw == "" && dobRaw == "" {
-			continue
-		}
-		roll, err := strconv.Atoi(rollRaw)
-		if err != nil || roll <= 0 {
-			errorsList = append(errorsList, fmt.Sprintf("row %d: invalid roll_number", rowNum))
-			continue
+	done := 0
+	for _, sheet := range sheets {
+		headerIdx := buildStudentHeaderIndex(sheet.rows[0])
+		required := []string{"full_name", "class_label", "roll_number", "date_of_birth"}
+		if selection.perClass {
+			required = []string{"full_name", "roll_number", "date_of_birth"}
 		}
-		dateOfBirth, err := time.Parse("2006-01-02", dobRaw)
-		if err != nil {
-			errorsList = append(errorsList, fmt.Sprintf("row %d: date_of_birth must be YYYY-MM-DD", rowNum))
-			continue
+		for _, key := range required {
+			if _, ok := headerIdx[key]; !ok {
+				message := fmt.Sprintf("missing required column: %s", key)
+				if selection.perClass {
+					message = fmt.Sprintf("sheet %s: %s", sheet.name, message)
+				}
+				return studentUploadResponse{}, &importError{http.StatusBadRequest, message}
+			}
 		}
-		parentPhone, err := normalizeParentPhone(parentPhoneRaw)
-		if err != nil {
-			errorsList = append(errorsList, fmt.Sprintf("row %d: %s", rowNum, err.Error()))
-			continue
+		sheetName := ""
+		if selection.perClass {
+			sheetName = sheet.name
 		}
-		admissionYear := time.Now().Year()
-		if admissionYearRaw != "" {
-			parsedYear, parseErr := strconv.Atoi(admissionYearRaw)
-			if parseErr != nil || parsedYear <= 0 {
-				errorsList = append(errorsList, fmt.Sprintf("row %d: invalid admission_year", rowNum))
+		for i, row := range sheet.rows[1:] {
+			progress(done, total)
+			done++
+			label := rowLabel(sheetName, i+2)
+			fullName := strings.TrimSpace(getStudentCell(row, headerIdx, "full_name"))
+			classLabel := strings.TrimSpace(getStudentCell(row, headerIdx, "class_label"))
+			rollRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "roll_number"))
+			dobRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "date_of_birth"))
+			house := strings.TrimSpace(getStudentCell(row, headerIdx, "house"))
+			parentPhoneRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "parent_phone"))
+			admissionYearRaw := strings.TrimSpace(getStudentCell(row, headerIdx, "admission_year"))
+			admissionNo, apaarID, pen, err := normalizeStudentIdentifiers(
+				getStudentCell(row, headerIdx, "admission_no"),
+				getStudentCell(row, headerIdx, "apaar_id"),
+				getStudentCell(row, headerIdx, "pen"),
+			)
+			if err != nil {
+				errorsList = append(errorsList, fmt.Sprintf("%s: %s", label, err.Error()))
 				continue
 			}
-			admissionYear = parsedYear
+
+			if fullName == "" && classLabel == "" && rollRaw == "" && dobRaw == "" {
+				continue
+			}
+			if classLabel == "" {
+				classLabel = strings.TrimSpace(sheetName)
+			}
+			roll, err := strconv.Atoi(rollRaw)
+			if err != nil || roll <= 0 {
+				errorsList = append(errorsList, fmt.Sprintf("%s: invalid roll_number", label))
+				continue
+			}
+			dateOfBirth, err := time.Parse("2006-01-02", dobRaw)
+			if err != nil {
+				errorsList = append(errorsList, fmt.Sprintf("%s: date_of_birth must be YYYY-MM-DD", label))
+				continue
+			}
+			parentPhone, err := normalizeParentPhone(parentPhoneRaw)
+			if err != nil {
+				errorsList = append(errorsList, fmt.Sprintf("%s: %s", label, err.Error()))
+				continue
+			}
+			admissionYear := time.Now().Year()
+			if admissionYearRaw != "" {
+				parsedYear, parseErr := strconv.Atoi(admissionYearRaw)
+				if parseErr != nil || parsedYear <= 0 {
+					errorsList = append(errorsList, fmt.Sprintf("%s: invalid admission_year", label))
+					continue
+				}
+				admissionYear = parsedYear
+			}
+			guardians, err := guardiansFromRow(row, headerIdx)
+			if err != nil {
+				errorsList = append(errorsList, fmt.Sprintf("%s: %s", label, err.Error()))
+				continue
+			}
+			guardians, parentPhone = assignPrimaryGuardian(guardians, parentPhone)
+			parsed = append(parsed, studentRow{label: label, item: store.StudentImport{
+				Student: models.Student{
+					SchoolID:      user.SchoolID,
+					FullName:      fullName,
+					ClassLabel:    classLabel,
+					RollNumber:    roll,
+					DateOfBirth:   dateOfBirth,
+					House:         house,
+					ParentPhone:   parentPhone,
+					AdmissionYear: admissionYear,
+					AdmissionNo:   admissionNo,
+					APAARID:       apaarID,
+					PEN:           pen,
+				},
+				Guardians: guardians,
+			}})
 		}
-		existing, err := h.Store.GetStudentByClassRoll(r.Context(), user.SchoolID, classLabel, roll)
-		if err != nil {
-			errorsList = append(errorsList, fmt.Sprintf("row %d: failed to validate duplicate", rowNum))
-			continue
+	}
+	progress(total, total)
+
+	var keys store.StudentKeys
+	for _, row := range parsed {
+		student := row.item.Student
+		keys.ClassRolls = append(keys.ClassRolls, store.ClassRollKey(student.ClassLabel, student.RollNumber))
+		if student.AdmissionNo != "" {
+			keys.AdmissionNos = append(keys.AdmissionNos, student.AdmissionNo)
 		}
-		if existing != nil {
-			errorsList = append(errorsList, fmt.Sprintf("row %d: duplicate class+roll already exists", rowNum))
-			continue
+		if student.APAARID != "" {
+			keys.APAARIDs = append(keys.APAARIDs, student.APAARID)
 		}
-		_, err = h.Store.CreateStudent(r.Context(), models.Student{
-			SchoolID:      user.SchoolID,
-			FullName:      fullName,
-			ClassLabel:    classLabel,
-			RollNumber:    roll,
-			DateOfBirth:   dateOfBirth,
-			House:         house,
-			ParentPhone:   parentPhone,
-			AdmissionYear: admissionYear,
-		})
-		if err != nil {
-			errorsList = append(errorsList, fmt.Sprintf("row %d: failed to create student", rowNum))
+		if student.PEN != "" {
+			keys.PENs = append(keys.PENs, student.PEN)
+		}
+	}
+	existing, err := h.Store.ExistingStudentKeys(ctx, user.SchoolID, keys)
+	if err != nil {
+		return studentUploadResponse{}, fmt.Errorf("check existing students: %w", err)
+	}
+	taken := map[string]map[string]bool{
+		"class+roll":   setOf(existing.ClassRolls),
+		"admission_no": setOf(existing.AdmissionNos),
+		"apaar_id":     setOf(existing.APAARIDs),
+		"pen":          setOf(existing.PENs),
+	}
+	firstRow := map[string]map[string]string{}
+	for kind := range taken {
+		firstRow[kind] = map[string]string{}
+	}
+	items := make([]store.StudentImport, 0, len(parsed))
+	for _, row := range parsed {
+		student := row.item.Student
+		checks := []struct{ kind, value string }{
+			{"class+roll", store.ClassRollKey(student.ClassLabel, student.RollNumber)},
+			{"admission_no", student.AdmissionNo},
+			{"apaar_id", student.APAARID},
+			{"pen", student.PEN},
+		}
+		var rowErr string
+		for _, check := range checks {
+			if check.value == "" {
+				continue
+			}
+			if first, ok := firstRow[check.kind][check.value]; ok {
+				rowErr = fmt.Sprintf("duplicate %s (first in %s)", check.kind, first)
+				break
+			}
+			firstRow[check.kind][check.value] = row.label
+			if taken[check.kind][check.value] {
+				if check.kind == "class+roll" {
+					rowErr = "duplicate class+roll already exists"
+				} else {
+					rowErr = check.kind + " already assigned to another student"
+				}
+				break
+			}
+		}
+		if rowErr != "" {
+			errorsList = append(errorsList, fmt.Sprintf("%s: %s", row.label, rowErr))
 			continue
 		}
-		inserted++
+		items = append(items, row.item)
+	}
+
+	auditFields := uploadAuditFields(archived, filename)
+	if len(errorsList) > 0 {
+		auditFields["errors"] = len(errorsList)
+		auditLog(ctx, "students.bulk_rejected", user, auditFields)
+		return studentUploadResponse{
+			Inserted: 0,
+			Failed:   len(errorsList),
+			Errors:   errorsList,
+		}, nil
+	}
+	if err := h.Store.ImportStudents(ctx, user.SchoolID, items); err != nil {
+		return studentUploadResponse{}, &importError{http.StatusInternalServerError, "failed to import students; nothing was saved"}
 	}
 
-	auditLog(r.Context(), "students.bulk_upload", user, map[string]interface{}{
-		"inserted": inserted,
-		"failed":   len(errorsList),
-	})
-	writeJSON(w, http.StatusOK, studentUploadResponse{
-		Inserted: inserted,
-		Failed:   len(errorsList),
+	auditFields["inserted"] = len(items)
+	auditFields["failed"] = 0
+	auditLog(ctx, "students.bulk_upload", user, auditFields)
+	return studentUploadResponse{
+		Inserted: len(items),
+		Failed:   0,
 		Errors:   errorsList,
-	})
+	}, nil
+}
+
+func setOf(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
 }
 
 func normalizeParentPhone(value string) (string, error) {
+	return normalizePhone(value, "parent_phone")
+}
+
+func normalizePhone(value, field string) (string, error) {
 	input := strings.TrimSpace(value)
 	if input == "" {
 		return "", nil
@@ -239,7 +422,7 @@ func normalizeParentPhone(value string) (string, error) {
 	if strings.HasPrefix(input, "+91") {
 		digits := strings.TrimPrefix(input, "+91")
 		if len(digits) != 10 || !phoneDigitsRegex.MatchString(digits) {
-			return "", errInvalidPhoneFormat()
+			return "", errInvalidPhoneFormat(field)
 		}
 		return "+91" + digits, nil
 	}
@@ -252,17 +435,37 @@ func normalizeParentPhone(value string) (string, error) {
 		return input, nil
 	}
 
-	return "", errInvalidPhoneFormat()
+	return "", errInvalidPhoneFormat(field)
+}
+
+func errInvalidPhoneFormat(field string) error {
+	return &validationError{message: field + " must be 10 digits, 91XXXXXXXXXX, or +91XXXXXXXXXX"}
 }
 
-func errInvalidPhoneFormat() error {
-	return &validationError{message: "parent_phone must be 10 digits, 91XXXXXXXXXX, or +91XXXXXXXXXX"}
+// phoneKey reduces a phone number to its last ten digits so that the stored
+// 10-digit, 91-prefixed and +91 forms compare equal.
+func phoneKey(value string) string {
+	var b strings.Builder
+	for _, ch := range value {
+		if ch >= '0' && ch <= '9' {
+			b.WriteRune(ch)
+		}
+	}
+	digits := b.String()
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return digits
 }
 
 func buildStudentHeaderIndex(headers []string) map[string]int {
 	idx := map[string]int{}
 	for i, raw := range headers {
-		key := strings.ToLower(strings.TrimSpace(raw))
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(raw)), " ", "_")
+		if isGuardianColumn(key) {
+			idx[key] = i
+			continue
+		}
 		switch key {
 		case "full_name", "name", "student_name":
 			idx["full_name"] = i
@@ -278,6 +481,12 @@ func buildStudentHeaderIndex(headers []string) map[string]int {
 			idx["parent_phone"] = i
 		case "admission_year":
 			idx["admission_year"] = i
+		case "admission_no", "admission_number", "adm_no":
+			idx["admission_no"] = i
+		case "apaar_id", "apaar":
+			idx["apaar_id"] = i
+		case "pen", "udise_pen":
+			idx["pen"] = i
 		}
 	}
 	return idx
@@ -330,10 +539,43 @@ func (h StudentsHandler) Lookup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	classLabel := r.URL.Query().Get("class")
-	rollStr := r.URL.Query().Get("roll")
+	query := r.URL.Query()
+	for _, kind := range []string{"admission_no", "apaar_id", "pen"} {
+		raw := query.Get(kind)
+		if raw == "" {
+			continue
+		}
+		var value string
+		var err error
+		switch kind {
+		case "admission_no":
+			value, _, _, err = normalizeStudentIdentifiers(raw, "", "")
+		case "apaar_id":
+			_, value, _, err = normalizeStudentIdentifiers("", raw, "")
+		case "pen":
+			_, _, value, err = normalizeStudentIdentifiers("", "", raw)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		student, err := h.Store.GetStudentByIdentifier(r.Context(), user.SchoolID, kind, value)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to lookup student")
+			return
+		}
+		if student == nil || student.SchoolID != user.SchoolID {
+			writeError(w, http.StatusNotFound, "student not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, student)
+		return
+	}
+
+	classLabel := query.Get("class")
+	rollStr := query.Get("roll")
 	if classLabel == "" || rollStr == "" {
-		writeError(w, http.StatusBadRequest, "class and roll required")
+		writeError(w, http.StatusBadRequest, "class and roll, admission_no, apaar_id or pen required")
 		return
 	}
 
@@ -354,3 +596,55 @@ func (h StudentsHandler) Lookup(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusOK, student)
 }
+
+func loadSchoolStudent(w http.ResponseWriter, r *http.Request, s *store.Store, user *models.User, studentID string) (*models.Student, bool) {
+	if studentID == "" {
+		writeError(w, http.StatusBadRequest, "missing student id")
+		return nil, false
+	}
+	student, err := s.GetStudent(r.Context(), studentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load student")
+		return nil, false
+	}
+	if student == nil || student.SchoolID != user.SchoolID {
+		writeError(w, http.StatusNotFound, "student not found")
+		return nil, false
+	}
+	return student, true
+}
+
+// authorizeStudentView lets school staff see students of their own school and
+// parents see only the students they have an approved link to.
+func authorizeStudentView(w http.ResponseWriter, r *http.Request, s *store.Store, user *models.User, studentID string) (*models.Student, bool) {
+	if !hasRole(user, models.RoleParent) {
+		if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+			writeError(w, http.StatusForbidden, "insufficient permissions")
+			return nil, false
+		}
+		return loadSchoolStudent(w, r, s, user, studentID)
+	}
+	if studentID == "" {
+		writeError(w, http.StatusBadRequest, "missing student id")
+		return nil, false
+	}
+	allowed, err := s.IsParentLinkedToStudent(r.Context(), user.ID, studentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to validate access")
+		return nil, false
+	}
+	if !allowed {
+		writeError(w, http.StatusForbidden, "not linked to this student")
+		return nil, false
+	}
+	student, err := s.GetStudent(r.Context(), studentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load student")
+		return nil, false
+	}
+	if student == nil {
+		writeError(w, http.StatusNotFound, "student not found")
+		return nil, false
+	}
+	return student, true
+}
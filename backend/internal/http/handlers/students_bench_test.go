@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/db"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// BenchmarkStudentImport measures what an upload request spends on a 1,000
+// row student sheet: validating the rows, checking them against the school's
+// existing students and importing them with their guardians. Each iteration
+// uploads a new class so no row collides with an earlier one. It needs a
+// migrated database in TEST_DATABASE_URL; the students it creates are
+// removed afterwards.
+func BenchmarkStudentImport(b *testing.B) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		b.Skip("TEST_DATABASE_URL is not set")
+	}
+	conn, err := db.Open(databaseURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	s := store.New(conn)
+
+	schoolID, err := s.FirstSchoolID(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	prefix := "bench-" + uuid.NewString()[:8]
+	defer func() {
+		conn.ExecContext(ctx, `DELETE FROM students WHERE school_id = $1 AND class_label LIKE $2`, schoolID, prefix+"-%")
+	}()
+
+	const students = 1000
+	sheet := func(class string) []byte {
+		var buf bytes.Buffer
+		buf.WriteString("full_name,class_label,roll_number,date_of_birth,house,parent_phone,admission_year,father_name,father_phone,mother_name,mother_phone,primary_guardian\n")
+		for roll := 1; roll <= students; roll++ {
+			fmt.Fprintf(&buf, "Bench Student %d,%s,%d,2012-01-01,Aravali,+9198%08d,2024,Bench Father %d,+9198%08d,Bench Mother %d,+9197%08d,father\n",
+				roll, class, roll, roll, roll, roll, roll, roll)
+		}
+		return buf.Bytes()
+	}
+
+	h := StudentsHandler{Store: s}
+	user := &models.User{ID: uuid.NewString(), SchoolID: schoolID, Role: models.RoleAdmin}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		data := sheet(fmt.Sprintf("%s-%d", prefix, i))
+		b.StartTimer()
+		resp, err := h.importSheet(ctx, user, "students.csv", sheetSelection{}, nil, data, noProgress)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if resp.Inserted != students {
+			b.Fatalf("inserted %d students, want %d: %v", resp.Inserted, students, resp.Errors)
+		}
+	}
+}
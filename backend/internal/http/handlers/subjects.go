@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/httpctx"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+type SubjectsHandler struct {
+	Store *store.Store
+}
+
+type subjectRequest struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// List returns the subject catalogue of a class.
+func (h SubjectsHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin, models.RoleStaff, models.RoleTeacher) {
+		writeError(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	classLabel := strings.TrimSpace(r.PathValue("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "missing class")
+		return
+	}
+	items, err := h.Store.ListSubjects(r.Context(), user.SchoolID, classLabel)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list subjects")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h SubjectsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	classLabel := strings.TrimSpace(r.PathValue("class"))
+	if classLabel == "" {
+		writeError(w, http.StatusBadRequest, "missing class")
+		return
+	}
+	var req subjectRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	subject, err := subjectFromRequest(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	subject.SchoolID, subject.Class = user.SchoolID, classLabel
+	if !h.checkNames(w, r, subject) {
+		return
+	}
+
+	created, err := h.Store.CreateSubject(r.Context(), subject)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create subject")
+		return
+	}
+	auditLog(r.Context(), "subject.created", user, map[string]interface{}{
+		"subject_id": created.ID,
+		"class":      created.Class,
+		"name":       created.Name,
+		"aliases":    created.Aliases,
+	})
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// Update renames a subject or replaces its aliases. Scores of the class are
+// renamed with it.
+func (h SubjectsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	var req subjectRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	existing, ok := h.load(w, r, user)
+	if !ok {
+		return
+	}
+	if existing.Locked {
+		writeError(w, http.StatusConflict, "subject is locked")
+		return
+	}
+	subject, err := subjectFromRequest(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	subject.ID, subject.SchoolID, subject.Class = existing.ID, existing.SchoolID, existing.Class
+	if !h.checkNames(w, r, subject) {
+		return
+	}
+	if err := h.Store.UpdateSubject(r.Context(), subject, existing.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusConflict, "subject is locked")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update subject")
+		return
+	}
+	auditLog(r.Context(), "subject.updated", user, map[string]interface{}{
+		"subject_id":    existing.ID,
+		"class":         existing.Class,
+		"previous_name": existing.Name,
+		"name":          subject.Name,
+		"aliases":       subject.Aliases,
+	})
+	existing.Name, existing.Aliases = subject.Name, subject.Aliases
+	writeJSON(w, http.StatusOK, existing)
+}
+
+// Delete removes a subject that is not locked and no exam declares.
+func (h SubjectsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	subject, ok := h.load(w, r, user)
+	if !ok {
+		return
+	}
+	if subject.Locked {
+		writeError(w, http.StatusConflict, "subject is locked")
+		return
+	}
+	if subject.ExamCount > 0 {
+		writeError(w, http.StatusConflict, "subject is used by "+strconv.Itoa(subject.ExamCount)+" exams")
+		return
+	}
+	if err := h.Store.DeleteSubject(r.Context(), subject.ID, user.SchoolID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusConflict, "subject is locked or in use")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete subject")
+		return
+	}
+	auditLog(r.Context(), "subject.deleted", user, map[string]interface{}{
+		"subject_id": subject.ID,
+		"class":      subject.Class,
+		"name":       subject.Name,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h SubjectsHandler) Lock(w http.ResponseWriter, r *http.Request) {
+	h.setLocked(w, r, true)
+}
+
+func (h SubjectsHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	h.setLocked(w, r, false)
+}
+
+func (h SubjectsHandler) setLocked(w http.ResponseWriter, r *http.Request, locked bool) {
+	user := httpctx.UserFromContext(r.Context())
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if !hasRole(user, models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, "admin required")
+		return
+	}
+	subject, ok := h.load(w, r, user)
+	if !ok {
+		return
+	}
+	if err := h.Store.SetSubjectLocked(r.Context(), subject.ID, user.SchoolID, locked); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update subject")
+		return
+	}
+	action := "subject.unlocked"
+	if locked {
+		action = "subject.locked"
+	}
+	auditLog(r.Context(), action, user, map[string]interface{}{
+		"subject_id": subject.ID,
+		"class":      subject.Class,
+		"name":       subject.Name,
+	})
+	subject.Locked = locked
+	writeJSON(w, http.StatusOK, subject)
+}
+
+func (h SubjectsHandler) load(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Subject, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, http.StatusNotFound, "subject not found")
+		return nil, false
+	}
+	subject, err := h.Store.GetSubject(r.Context(), id, user.SchoolID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load subject")
+		return nil, false
+	}
+	if subject == nil {
+		writeError(w, http.StatusNotFound, "subject not found")
+		return nil, false
+	}
+	return subject, true
+}
+
+// checkNames rejects a name or alias that already refers to another subject
+// of the class, since uploads could then not tell the two apart.
+func (h SubjectsHandler) checkNames(w http.ResponseWriter, r *http.Request, subject models.Subject) bool {
+	existing, err := h.Store.ListSubjects(r.Context(), subject.SchoolID, subject.Class)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load subjects")
+		return false
+	}
+	catalogue := newSubjectCatalogue(existing)
+	for _, name := range append([]string{subject.Name}, subject.Aliases...) {
+		if other := catalogue.match(name); other != nil && other.ID != subject.ID {
+			writeError(w, http.StatusConflict, name+" already refers to "+other.Name)
+			return false
+		}
+	}
+	return true
+}
+
+func subjectFromRequest(req subjectRequest) (models.Subject, error) {
+	subject := models.Subject{Name: strings.TrimSpace(req.Name), Aliases: []string{}}
+	if subject.Name == "" || len(subject.Name) > 60 || subjectKey(subject.Name) == "" {
+		return subject, errors.New("name must be 1 to 60 characters")
+	}
+	if len(req.Aliases) > 20 {
+		return subject, errors.New("at most 20 aliases are allowed")
+	}
+	seen := map[string]bool{subjectKey(subject.Name): true}
+	for _, alias := range req.Aliases {
+		alias = strings.TrimSpace(alias)
+		key := subjectKey(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		if len(alias) > 60 {
+			return subject, errors.New("aliases must be at most 60 characters")
+		}
+		seen[key] = true
+		subject.Aliases = append(subject.Aliases, alias)
+	}
+	return subject, nil
+}
+
+// subjectCatalogue matches the subject names a sheet or request uses to the
+// class catalogue by name or alias, ignoring case, spaces and punctuation.
+type subjectCatalogue struct {
+	byKey map[string]*models.Subject
+}
+
+func newSubjectCatalogue(subjects []models.Subject) *subjectCatalogue {
+	catalogue := &subjectCatalogue{byKey: map[string]*models.Subject{}}
+	for i := range subjects {
+		subject := &subjects[i]
+		catalogue.byKey[subjectKey(subject.Name)] = subject
+		for _, alias := range subject.Aliases {
+			catalogue.byKey[subjectKey(alias)] = subject
+		}
+	}
+	return catalogue
+}
+
+func (c *subjectCatalogue) match(name string) *models.Subject {
+	return c.byKey[subjectKey(name)]
+}
+
+// unknown describes a name that matches nothing, suggesting the closest
+// subject when the name looks like a typo of it.
+func (c *subjectCatalogue) unknown(name string) string {
+	key := subjectKey(name)
+	limit := 2
+	if len(key) < 5 {
+		limit = 1
+	}
+	var best *models.Subject
+	bestDist := limit + 1
+	for candidate, subject := range c.byKey {
+		dist := editDistance(key, candidate)
+		if dist < bestDist || (dist == bestDist && best != nil && subject.Name < best.Name) {
+			best, bestDist = subject, dist
+		}
+	}
+	if best == nil {
+		return "unknown subject " + name
+	}
+	return "unknown subject " + name + " (did you mean " + best.Name + "?)"
+}
+
+func subjectKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
@@ -57,7 +57,7 @@ func (h UsersHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 	}
 	role := models.Role(strings.ToLower(strings.TrimSpace(req.Role)))
 	switch role {
-	case models.RoleParent, models.RoleTeacher, models.RoleStaff, models.RoleAdmin:
+	case models.RoleParent, models.RoleTeacher, models.RoleNurse, models.RoleStaff, models.RoleAdmin:
 	default:
 		writeError(w, http.StatusBadRequest, "unsupported role")
 		return
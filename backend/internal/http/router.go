@@ -6,7 +6,9 @@ import (
 
 	"jnv/backend/internal/auth"
 	"jnv/backend/internal/http/handlers"
+	"jnv/backend/internal/idcard"
 	"jnv/backend/internal/notify"
+	"jnv/backend/internal/storage"
 	"jnv/backend/internal/store"
 )
 
@@ -14,6 +16,10 @@ type API struct {
 	Store         *store.Store
 	AuthProvider  auth.Provider
 	Notifier      notify.Sender
+	Files         storage.Bucket
+	QueueImports  bool
+	CardSigner    *idcard.Signer
+	PublicBaseURL string
 	CORSAllowList []string
 }
 
@@ -36,6 +42,7 @@ func (a API) Router() http.Handler {
 	mux.Handle("POST /api/v1/announcements", protected(http.HandlerFunc(announcementHandler.Create)))
 	mux.Handle("POST /api/v1/announcements/{id}/publish", protected(http.HandlerFunc(announcementHandler.Publish)))
 	mux.Handle("DELETE /api/v1/announcements/{id}", protected(http.HandlerFunc(announcementHandler.Delete)))
+	mux.Handle("POST /api/v1/announcements/{id}/read", protected(http.HandlerFunc(announcementHandler.MarkRead)))
 
 	eventsHandler := handlers.EventsHandler{Store: a.Store, Notifier: a.Notifier}
 	mux.Handle("GET /api/v1/events", protected(http.HandlerFunc(eventsHandler.List)))
@@ -47,29 +54,135 @@ func (a API) Router() http.Handler {
 	mux.Handle("GET /api/v1/app-config", protected(http.HandlerFunc(appConfigHandler.Get)))
 	mux.Handle("POST /api/v1/app-config", protected(http.HandlerFunc(appConfigHandler.Upsert)))
 
-	parentLinkHandler := handlers.ParentLinkHandler{Store: a.Store}
+	parentLinkHandler := handlers.ParentLinkHandler{Store: a.Store, Notifier: a.Notifier}
 	mux.Handle("POST /api/v1/parent-links", protected(http.HandlerFunc(parentLinkHandler.Create)))
 	mux.Handle("POST /api/v1/parent-links/request", protected(http.HandlerFunc(parentLinkHandler.CreateByClassRoll)))
 	mux.Handle("GET /api/v1/parent-links/pending", protected(http.HandlerFunc(parentLinkHandler.ListPending)))
 	mux.Handle("POST /api/v1/parent-links/{id}/approve", protected(http.HandlerFunc(parentLinkHandler.Approve)))
+	mux.Handle("POST /api/v1/parent-links/{id}/reject", protected(http.HandlerFunc(parentLinkHandler.Reject)))
+	mux.Handle("POST /api/v1/parent-links/{id}/revoke", protected(http.HandlerFunc(parentLinkHandler.Revoke)))
+	mux.Handle("GET /api/v1/students/{id}/parent-links", protected(http.HandlerFunc(parentLinkHandler.ListForStudent)))
+	mux.Handle("GET /api/v1/parent-links/policy", protected(http.HandlerFunc(parentLinkHandler.GetPolicy)))
+	mux.Handle("POST /api/v1/parent-links/policy", protected(http.HandlerFunc(parentLinkHandler.UpdatePolicy)))
+	mux.Handle("POST /api/v1/parent-links/bulk", protected(http.HandlerFunc(parentLinkHandler.Bulk)))
+	mux.Handle("GET /api/v1/parent-links/metrics", protected(http.HandlerFunc(parentLinkHandler.Metrics)))
+	mux.Handle("POST /api/v1/parent-links/redeem", protected(http.HandlerFunc(parentLinkHandler.Redeem)))
+	mux.Handle("GET /api/v1/parent-links/siblings", protected(http.HandlerFunc(parentLinkHandler.Siblings)))
+	mux.Handle("POST /api/v1/parent-links/extend", protected(http.HandlerFunc(parentLinkHandler.Extend)))
 
 	parentsHandler := handlers.ParentsHandler{Store: a.Store}
 	mux.Handle("GET /api/v1/parents/me/overview", protected(http.HandlerFunc(parentsHandler.Overview)))
-
-	examHandler := handlers.ExamHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/parents/me/children/{id}", protected(http.HandlerFunc(parentsHandler.Child)))
+
+	subjectsHandler := handlers.SubjectsHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/classes/{class}/subjects", protected(http.HandlerFunc(subjectsHandler.List)))
+	mux.Handle("POST /api/v1/classes/{class}/subjects", protected(http.HandlerFunc(subjectsHandler.Create)))
+	mux.Handle("POST /api/v1/subjects/{id}", protected(http.HandlerFunc(subjectsHandler.Update)))
+	mux.Handle("DELETE /api/v1/subjects/{id}", protected(http.HandlerFunc(subjectsHandler.Delete)))
+	mux.Handle("POST /api/v1/subjects/{id}/lock", protected(http.HandlerFunc(subjectsHandler.Lock)))
+	mux.Handle("POST /api/v1/subjects/{id}/unlock", protected(http.HandlerFunc(subjectsHandler.Unlock)))
+
+	reportCardsHandler := handlers.ReportCardsHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/students/{id}/report-card", protected(http.HandlerFunc(reportCardsHandler.Student)))
+	mux.Handle("POST /api/v1/students/{id}/report-card/remarks", protected(http.HandlerFunc(reportCardsHandler.SaveRemarks)))
+	mux.Handle("GET /api/v1/classes/{class}/report-cards.zip", protected(http.HandlerFunc(reportCardsHandler.Class)))
+	mux.Handle("GET /api/v1/report-card/template", protected(http.HandlerFunc(reportCardsHandler.GetTemplate)))
+	mux.Handle("POST /api/v1/report-card/template", protected(http.HandlerFunc(reportCardsHandler.SaveTemplate)))
+
+	analyticsHandler := handlers.AnalyticsHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/students/{id}/analytics", protected(http.HandlerFunc(analyticsHandler.Student)))
+	mux.Handle("GET /api/v1/classes/{class}/analytics", protected(http.HandlerFunc(analyticsHandler.Class)))
+
+	scoreExportsHandler := handlers.ScoreExportsHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/exams/{id}/scores.csv", protected(http.HandlerFunc(scoreExportsHandler.ExamCSV)))
+	mux.Handle("GET /api/v1/exams/{id}/scores.xlsx", protected(http.HandlerFunc(scoreExportsHandler.ExamXLSX)))
+	mux.Handle("GET /api/v1/classes/{class}/scores.xlsx", protected(http.HandlerFunc(scoreExportsHandler.ClassXLSX)))
+
+	importJobsHandler := handlers.ImportJobsHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/jobs", protected(http.HandlerFunc(importJobsHandler.List)))
+	mux.Handle("GET /api/v1/jobs/{id}", protected(http.HandlerFunc(importJobsHandler.Get)))
+	mux.Handle("POST /api/v1/jobs/{id}/cancel", protected(http.HandlerFunc(importJobsHandler.Cancel)))
+
+	gradingHandler := handlers.GradingSchemesHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/grading-schemes", protected(http.HandlerFunc(gradingHandler.List)))
+	mux.Handle("POST /api/v1/grading-schemes", protected(http.HandlerFunc(gradingHandler.Upsert)))
+	mux.Handle("GET /api/v1/grading-schemes/presets", protected(http.HandlerFunc(gradingHandler.Presets)))
+	mux.Handle("DELETE /api/v1/grading-schemes/{id}", protected(http.HandlerFunc(gradingHandler.Delete)))
+
+	examHandler := handlers.ExamHandler{Store: a.Store, Notifier: a.Notifier}
+	mux.Handle("GET /api/v1/exams", protected(http.HandlerFunc(examHandler.List)))
 	mux.Handle("POST /api/v1/exams", protected(http.HandlerFunc(examHandler.Create)))
-
-	scoresHandler := handlers.ScoresHandler{Store: a.Store, Notifier: a.Notifier}
+	mux.Handle("GET /api/v1/exams/{id}", protected(http.HandlerFunc(examHandler.Get)))
+	mux.Handle("POST /api/v1/exams/{id}", protected(http.HandlerFunc(examHandler.Update)))
+	mux.Handle("DELETE /api/v1/exams/{id}", protected(http.HandlerFunc(examHandler.Delete)))
+	mux.Handle("POST /api/v1/exams/{id}/publish", protected(http.HandlerFunc(examHandler.Publish)))
+	mux.Handle("POST /api/v1/exams/{id}/unpublish", protected(http.HandlerFunc(examHandler.Unpublish)))
+	mux.Handle("POST /api/v1/exams/{id}/lock", protected(http.HandlerFunc(examHandler.Lock)))
+
+	scoresHandler := handlers.ScoresHandler{Store: a.Store, Notifier: a.Notifier, Files: a.Files, QueueImports: a.QueueImports}
 	mux.Handle("POST /api/v1/exams/{id}/scores", protected(http.HandlerFunc(scoresHandler.AddForExam)))
 	mux.Handle("POST /api/v1/exams/{id}/scores/csv", protected(http.HandlerFunc(scoresHandler.UploadCSV)))
 	mux.Handle("POST /api/v1/exams/{id}/scores/upload", protected(http.HandlerFunc(scoresHandler.UploadFile)))
+	mux.Handle("GET /api/v1/exams/{id}/scores/{student}/history", protected(http.HandlerFunc(scoresHandler.History)))
 	mux.Handle("GET /api/v1/students/{id}/scores", protected(http.HandlerFunc(scoresHandler.ListByStudent)))
 
-	studentsHandler := handlers.StudentsHandler{Store: a.Store}
+	studentsHandler := handlers.StudentsHandler{Store: a.Store, Files: a.Files, QueueImports: a.QueueImports}
 	mux.Handle("GET /api/v1/students", protected(http.HandlerFunc(studentsHandler.List)))
 	mux.Handle("POST /api/v1/students", protected(http.HandlerFunc(studentsHandler.Create)))
 	mux.Handle("POST /api/v1/students/upload", protected(http.HandlerFunc(studentsHandler.Upload)))
 	mux.Handle("GET /api/v1/students/lookup", protected(http.HandlerFunc(studentsHandler.Lookup)))
+	mux.Handle("POST /api/v1/students/{id}/identifiers", protected(http.HandlerFunc(studentsHandler.UpdateIdentifiers)))
+
+	guardiansHandler := handlers.GuardiansHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/students/{id}/guardians", protected(http.HandlerFunc(guardiansHandler.List)))
+	mux.Handle("POST /api/v1/students/{id}/guardians", protected(http.HandlerFunc(guardiansHandler.Create)))
+	mux.Handle("POST /api/v1/students/{id}/guardians/{guardianID}", protected(http.HandlerFunc(guardiansHandler.Update)))
+	mux.Handle("DELETE /api/v1/students/{id}/guardians/{guardianID}", protected(http.HandlerFunc(guardiansHandler.Delete)))
+
+	familiesHandler := handlers.FamiliesHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/families", protected(http.HandlerFunc(familiesHandler.List)))
+	mux.Handle("POST /api/v1/families", protected(http.HandlerFunc(familiesHandler.Create)))
+	mux.Handle("GET /api/v1/families/suggestions", protected(http.HandlerFunc(familiesHandler.Suggestions)))
+	mux.Handle("POST /api/v1/families/suggestions/dismiss", protected(http.HandlerFunc(familiesHandler.Dismiss)))
+	mux.Handle("GET /api/v1/families/{id}", protected(http.HandlerFunc(familiesHandler.Get)))
+	mux.Handle("POST /api/v1/families/{id}/members", protected(http.HandlerFunc(familiesHandler.AddMembers)))
+	mux.Handle("DELETE /api/v1/families/{id}/members/{studentID}", protected(http.HandlerFunc(familiesHandler.RemoveMember)))
+	mux.Handle("GET /api/v1/students/{id}/family", protected(http.HandlerFunc(familiesHandler.ForStudent)))
+
+	documentsHandler := handlers.DocumentsHandler{Store: a.Store, Files: a.Files, Notifier: a.Notifier}
+	mux.Handle("GET /api/v1/students/{id}/documents", protected(http.HandlerFunc(documentsHandler.List)))
+	mux.Handle("POST /api/v1/students/{id}/documents", protected(http.HandlerFunc(documentsHandler.Upload)))
+	mux.Handle("GET /api/v1/students/{id}/documents/{docID}/file", protected(http.HandlerFunc(documentsHandler.Download)))
+	mux.Handle("POST /api/v1/students/{id}/documents/{docID}/verify", protected(http.HandlerFunc(documentsHandler.Verify)))
+	mux.Handle("POST /api/v1/students/{id}/documents/{docID}/reject", protected(http.HandlerFunc(documentsHandler.Reject)))
+	mux.Handle("GET /api/v1/documents/requirements", protected(http.HandlerFunc(documentsHandler.Requirements)))
+	mux.Handle("POST /api/v1/documents/requirements", protected(http.HandlerFunc(documentsHandler.SetRequirements)))
+	mux.Handle("GET /api/v1/documents/checklist", protected(http.HandlerFunc(documentsHandler.Checklist)))
+
+	alumniHandler := handlers.AlumniHandler{Store: a.Store}
+	mux.Handle("GET /api/v1/alumni", protected(http.HandlerFunc(alumniHandler.List)))
+	mux.Handle("POST /api/v1/alumni/graduate", protected(http.HandlerFunc(alumniHandler.Graduate)))
+	mux.Handle("GET /api/v1/alumni/{id}/contact", protected(http.HandlerFunc(alumniHandler.GetContact)))
+	mux.Handle("POST /api/v1/alumni/{id}/contact", protected(http.HandlerFunc(alumniHandler.UpdateContact)))
+	mux.Handle("GET /api/v1/alumni/{id}/scores.csv", protected(http.HandlerFunc(alumniHandler.ExportScores)))
+
+	healthHandler := handlers.HealthHandler{Store: a.Store, Notifier: a.Notifier}
+	mux.Handle("GET /api/v1/students/{id}/health", protected(http.HandlerFunc(healthHandler.GetProfile)))
+	mux.Handle("POST /api/v1/students/{id}/health", protected(http.HandlerFunc(healthHandler.UpdateProfile)))
+	mux.Handle("GET /api/v1/students/{id}/infirmary-visits", protected(http.HandlerFunc(healthHandler.ListVisits)))
+	mux.Handle("POST /api/v1/students/{id}/infirmary-visits", protected(http.HandlerFunc(healthHandler.RecordVisit)))
+	mux.Handle("POST /api/v1/infirmary-visits/{id}/discharge", protected(http.HandlerFunc(healthHandler.Discharge)))
+	mux.Handle("GET /api/v1/infirmary/summary", protected(http.HandlerFunc(healthHandler.Summary)))
+
+	idCardsHandler := handlers.IDCardsHandler{Store: a.Store, Files: a.Files, Signer: a.CardSigner, PublicBaseURL: a.PublicBaseURL}
+	mux.Handle("GET /api/v1/students/{id}/id-card.pdf", protected(http.HandlerFunc(idCardsHandler.Student)))
+	mux.Handle("GET /api/v1/classes/{class}/id-cards.pdf", protected(http.HandlerFunc(idCardsHandler.Class)))
+	verifyLimiter := newAuthRateLimiter(60, time.Minute)
+	mux.Handle("GET /api/v1/verify/{token}", withAuthRateLimit(http.HandlerFunc(idCardsHandler.Verify), verifyLimiter))
+
+	linkCodesHandler := handlers.LinkCodesHandler{Store: a.Store}
+	mux.Handle("POST /api/v1/students/{id}/link-code", protected(http.HandlerFunc(linkCodesHandler.Student)))
+	mux.Handle("POST /api/v1/classes/{class}/link-codes.pdf", protected(http.HandlerFunc(linkCodesHandler.Class)))
 
 	referenceHandler := handlers.ReferenceHandler{Store: a.Store}
 	mux.Handle("GET /api/v1/reference/districts", protected(http.HandlerFunc(referenceHandler.Districts)))
@@ -81,6 +194,10 @@ func (a API) Router() http.Handler {
 	devicesHandler := handlers.DevicesHandler{Store: a.Store}
 	mux.Handle("POST /api/v1/devices/token", protected(http.HandlerFunc(devicesHandler.RegisterToken)))
 
+	filesHandler := handlers.FilesHandler{Files: a.Files}
+	mux.HandleFunc("GET /api/v1/files/{key...}", filesHandler.Download)
+	mux.Handle("POST /api/v1/files/sign", protected(http.HandlerFunc(filesHandler.Sign)))
+
 	auditLogsHandler := handlers.AuditLogsHandler{Store: a.Store}
 	mux.Handle("GET /api/v1/audit-logs", protected(http.HandlerFunc(auditLogsHandler.List)))
 
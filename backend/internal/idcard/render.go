@@ -0,0 +1,180 @@
+// Package idcard lays out printable student identity cards and signs the
+// verification tokens encoded in their QR codes.
+package idcard
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"jnv/backend/internal/pdf"
+	"jnv/backend/internal/qrcode"
+)
+
+// Card dimensions follow ISO/IEC 7810 ID-1 (85.6 x 54 mm).
+const (
+	CardWidth  = 85.6 * pdf.MM
+	CardHeight = 54 * pdf.MM
+)
+
+const (
+	sheetColumns = 2
+	sheetRows    = 5
+	sheetGap     = 6.0
+)
+
+type Card struct {
+	SchoolName    string
+	StudentName   string
+	ClassLabel    string
+	RollNumber    int
+	House         string
+	DateOfBirth   time.Time
+	GuardianPhone string
+	Photo         *pdf.Image
+	VerifyURL     string
+	ValidUntil    time.Time
+}
+
+// RenderSingle puts one card on a page cut to card size.
+func RenderSingle(doc *pdf.Document, card Card) error {
+	page := doc.AddPage(CardWidth, CardHeight)
+	return draw(page, 0, 0, card)
+}
+
+// RenderSheet lays cards out ten to an A4 page for printing on card stock.
+func RenderSheet(doc *pdf.Document, cards []Card) error {
+	marginX := (pdf.A4Width - sheetColumns*CardWidth - (sheetColumns-1)*sheetGap) / 2
+	marginY := (pdf.A4Height - sheetRows*CardHeight - (sheetRows-1)*sheetGap) / 2
+	perPage := sheetColumns * sheetRows
+
+	var page *pdf.Page
+	for i, card := range cards {
+		slot := i % perPage
+		if slot == 0 {
+			page = doc.AddPage(pdf.A4Width, pdf.A4Height)
+		}
+		x := marginX + float64(slot%sheetColumns)*(CardWidth+sheetGap)
+		y := marginY + float64(slot/sheetColumns)*(CardHeight+sheetGap)
+		if err := draw(page, x, y, card); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func draw(page *pdf.Page, x, y float64, card Card) error {
+	code, err := qrcode.Encode([]byte(card.VerifyURL))
+	if err != nil {
+		return err
+	}
+
+	page.SetLineWidth(0.5)
+	page.SetStrokeColor(180, 180, 180)
+	page.SetFillColor(255, 255, 255)
+	page.Rect(x, y, CardWidth, CardHeight, true, true)
+
+	bandHeight := 11 * pdf.MM
+	page.SetFillColor(27, 54, 93)
+	page.Rect(x, y, CardWidth, bandHeight, true, false)
+	page.SetFillColor(255, 255, 255)
+	headerWidth := CardWidth - 8*pdf.MM
+	page.Text(x+4*pdf.MM, y+5*pdf.MM, pdf.HelveticaBold, 8, pdf.Truncate(pdf.HelveticaBold, 8, headerWidth, card.SchoolName))
+	page.Text(x+4*pdf.MM, y+8.5*pdf.MM, pdf.Helvetica, 6, "STUDENT IDENTITY CARD")
+
+	photoX, photoY := x+4*pdf.MM, y+14*pdf.MM
+	photoW, photoH := 20*pdf.MM, 25*pdf.MM
+	if card.Photo != nil {
+		drawPhoto(page, card.Photo, photoX, photoY, photoW, photoH)
+	} else {
+		page.SetFillColor(235, 235, 235)
+		page.Rect(photoX, photoY, photoW, photoH, true, false)
+		page.SetFillColor(130, 130, 130)
+		label := "PHOTO"
+		page.Text(photoX+(photoW-pdf.TextWidth(pdf.Helvetica, 6, label))/2, photoY+photoH/2+2, pdf.Helvetica, 6, label)
+	}
+	page.SetStrokeColor(180, 180, 180)
+	page.Rect(photoX, photoY, photoW, photoH, false, true)
+
+	qrSize := 22 * pdf.MM
+	qrX, qrY := x+CardWidth-qrSize-2*pdf.MM, y+13*pdf.MM
+	drawQR(page, code, qrX, qrY, qrSize)
+
+	textX := photoX + photoW + 3*pdf.MM
+	textWidth := qrX - textX
+	page.SetFillColor(20, 20, 20)
+	page.Text(textX, y+17*pdf.MM, pdf.HelveticaBold, 8.5, pdf.Truncate(pdf.HelveticaBold, 8.5, textWidth, strings.ToUpper(card.StudentName)))
+
+	class := card.ClassLabel
+	if card.RollNumber > 0 {
+		class += " / Roll " + strconv.Itoa(card.RollNumber)
+	}
+	fields := [][2]string{
+		{"Class", class},
+		{"House", card.House},
+		{"DOB", formatDate(card.DateOfBirth)},
+		{"Guardian", card.GuardianPhone},
+	}
+	lineY := y + 22*pdf.MM
+	labelWidth := 11 * pdf.MM
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		page.SetFillColor(110, 110, 110)
+		page.Text(textX, lineY, pdf.Helvetica, 6, field[0])
+		page.SetFillColor(20, 20, 20)
+		page.Text(textX+labelWidth, lineY, pdf.HelveticaBold, 6.5, pdf.Truncate(pdf.HelveticaBold, 6.5, textWidth-labelWidth, field[1]))
+		lineY += 4 * pdf.MM
+	}
+
+	page.SetFillColor(110, 110, 110)
+	footer := "Valid until " + formatDate(card.ValidUntil)
+	page.Text(x+4*pdf.MM, y+CardHeight-4*pdf.MM, pdf.Helvetica, 5.5, footer)
+	scan := "Scan to verify"
+	page.Text(qrX+(qrSize-pdf.TextWidth(pdf.Helvetica, 5.5, scan))/2, qrY+qrSize+2.5*pdf.MM, pdf.Helvetica, 5.5, scan)
+	return nil
+}
+
+// drawPhoto fills the box with the image, cropping nothing and keeping the
+// aspect ratio, centred on a light background.
+func drawPhoto(page *pdf.Page, img *pdf.Image, x, y, w, h float64) {
+	page.SetFillColor(245, 245, 245)
+	page.Rect(x, y, w, h, true, false)
+	iw, ih := img.Size()
+	if iw == 0 || ih == 0 {
+		return
+	}
+	scale := min(w/float64(iw), h/float64(ih))
+	dw, dh := float64(iw)*scale, float64(ih)*scale
+	page.Image(img, x+(w-dw)/2, y+(h-dh)/2, dw, dh)
+}
+
+// drawQR draws the symbol inside a square of the given size, including the
+// four-module quiet zone. Runs of dark modules are merged into one rectangle.
+func drawQR(page *pdf.Page, code *qrcode.Code, x, y, size float64) {
+	module := size / float64(code.Size+8)
+	page.SetFillColor(255, 255, 255)
+	page.Rect(x, y, size, size, true, false)
+	page.SetFillColor(0, 0, 0)
+	for row := 0; row < code.Size; row++ {
+		for col := 0; col < code.Size; {
+			if !code.Dark(col, row) {
+				col++
+				continue
+			}
+			start := col
+			for col < code.Size && code.Dark(col, row) {
+				col++
+			}
+			page.Rect(x+float64(start+4)*module, y+float64(row+4)*module, float64(col-start)*module, module, true, false)
+		}
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("02 Jan 2006")
+}
@@ -0,0 +1,229 @@
+// Package jobs runs queued imports in the background. The queue lives in
+// Postgres (see store.ClaimImportJob), so any number of API instances can run
+// workers and jobs outlive the process that accepted them.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/store"
+)
+
+// Progress reports how many of a job's rows have been processed so far.
+type Progress func(done, total int)
+
+// Outcome is what a Func reports for a job it ran to the end.
+type Outcome struct {
+	// Result is stored as the job's result and must marshal to a JSON object.
+	Result interface{}
+	// Rejected marks a sheet that had row errors and imported nothing.
+	Rejected bool
+}
+
+// Func runs one job. The error is shown to the uploader, so it should be a
+// message they can act on. Func must stop when ctx is cancelled; imports run
+// in a transaction, so a cancelled job leaves nothing behind.
+type Func func(ctx context.Context, job models.ImportJob, progress Progress) (Outcome, error)
+
+// Runner claims jobs for the kinds in Funcs and runs them on Workers
+// goroutines. A running job holds a lease that is renewed every Heartbeat;
+// if the process dies, the job is claimed again once the lease expires, up to
+// MaxAttempts times.
+type Runner struct {
+	Store        *store.Store
+	Funcs        map[string]Func
+	Workers      int
+	PollInterval time.Duration
+	Heartbeat    time.Duration
+	Lease        time.Duration
+	MaxAttempts  int
+}
+
+func (r Runner) withDefaults() Runner {
+	if r.Workers <= 0 {
+		r.Workers = 1
+	}
+	if r.PollInterval <= 0 {
+		r.PollInterval = 2 * time.Second
+	}
+	if r.Heartbeat <= 0 {
+		r.Heartbeat = 5 * time.Second
+	}
+	if r.Lease <= r.Heartbeat {
+		r.Lease = 12 * r.Heartbeat
+	}
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = 3
+	}
+	return r
+}
+
+// Run starts the workers and blocks until ctx is done.
+func (r Runner) Run(ctx context.Context) {
+	r = r.withDefaults()
+	var wg sync.WaitGroup
+	for i := 0; i < r.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				ran, err := r.RunOnce(ctx)
+				if err != nil {
+					log.Printf("import jobs: %v", err)
+				}
+				if ran && err == nil {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(r.PollInterval):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// RunOnce claims and runs a single job, reporting whether there was one.
+func (r Runner) RunOnce(ctx context.Context) (bool, error) {
+	r = r.withDefaults()
+	kinds := make([]string, 0, len(r.Funcs))
+	for kind := range r.Funcs {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	job, err := r.Store.ClaimImportJob(ctx, kinds, r.Lease, r.MaxAttempts)
+	if err != nil {
+		return false, fmt.Errorf("claim: %w", err)
+	}
+	if job == nil {
+		return false, nil
+	}
+	return true, r.run(ctx, *job)
+}
+
+func (r Runner) run(ctx context.Context, job models.ImportJob) error {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var done, total atomic.Int64
+	progress := func(d, t int) {
+		done.Store(int64(d))
+		total.Store(int64(t))
+	}
+
+	// The heartbeat keeps the lease, publishes progress and notices cancel
+	// requests. Losing the job (another worker claimed it after our lease
+	// lapsed, or it was finished) stops the run as well.
+	var cancelled, lost atomic.Bool
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(r.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			requested, err := r.Store.RenewImportJob(ctx, job.ID, job.Attempts, r.Lease, int(done.Load()), int(total.Load()))
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				lost.Store(true)
+				cancel()
+				return
+			case err != nil:
+				log.Printf("import job %s: renew lease: %v", job.ID, err)
+			case requested:
+				cancelled.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	outcome, runErr := r.call(jobCtx, job, progress)
+	close(stop)
+	<-stopped
+
+	if lost.Load() {
+		return fmt.Errorf("job %s: lost its lease", job.ID)
+	}
+	if ctx.Err() != nil {
+		// Shutting down: the lease runs out and another worker retries.
+		return nil
+	}
+	job.ProgressDone, job.ProgressTotal = int(done.Load()), int(total.Load())
+	switch {
+	case runErr != nil && (cancelled.Load() || job.CancelRequested):
+		job.Status = models.ImportJobCancelled
+	case runErr != nil:
+		job.Status = models.ImportJobFailed
+		job.Error = runErr.Error()
+	case outcome.Rejected:
+		job.Status = models.ImportJobRejected
+	default:
+		job.Status = models.ImportJobSucceeded
+	}
+	if runErr == nil {
+		result, err := resultObject(outcome.Result)
+		if err != nil {
+			return fmt.Errorf("job %s: encode result: %w", job.ID, err)
+		}
+		job.Result = result
+	}
+	err := r.Store.FinishImportJob(ctx, job)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("job %s: lost its lease", job.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("job %s: finish: %w", job.ID, err)
+	}
+	log.Printf(`{"action":"import_job.%s","job_id":"%s","kind":"%s","school_id":"%s"}`,
+		job.Status, job.ID, job.Kind, job.SchoolID)
+	return nil
+}
+
+// call runs the job's Func, turning a panic into a failed job rather than a
+// crashed process.
+func (r Runner) call(ctx context.Context, job models.ImportJob, progress Progress) (outcome Outcome, err error) {
+	fn, ok := r.Funcs[job.Kind]
+	if !ok {
+		return Outcome{}, fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("import job %s: panic: %v", job.ID, p)
+			err = errors.New("import failed unexpectedly")
+		}
+	}()
+	return fn(ctx, job, progress)
+}
+
+func resultObject(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
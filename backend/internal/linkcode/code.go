@@ -0,0 +1,69 @@
+// Package linkcode issues the one-time codes printed on admission slips that
+// let a parent link to their child without waiting for an admin.
+package linkcode
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// alphabet is Crockford's base32: no I, L, O or U, so a code read aloud or
+// copied from paper survives the usual confusions.
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Length is the number of symbols in a code, 60 bits in all.
+const Length = 12
+
+// Generate returns a new random code grouped for reading, e.g. 7K3M-Q9TZ-2HXD.
+func Generate() (string, error) {
+	buf := make([]byte, Length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, Length)
+	for i, b := range buf {
+		code[i] = alphabet[b&31]
+	}
+	return format(string(code)), nil
+}
+
+// Normalize strips separators and case from user input and maps the letters
+// Crockford treats as digits. It reports false when the result cannot be a
+// code.
+func Normalize(input string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		switch r {
+		case ' ', '-':
+			continue
+		case 'O':
+			r = '0'
+		case 'I', 'L':
+			r = '1'
+		}
+		if !strings.ContainsRune(alphabet, r) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() != Length {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// Hash is what is stored for a code. The input may be formatted or not.
+func Hash(code string) string {
+	normalized, ok := Normalize(code)
+	if !ok {
+		normalized = code
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func format(code string) string {
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12]
+}
@@ -0,0 +1,99 @@
+package linkcode
+
+import (
+	"strconv"
+	"time"
+
+	"jnv/backend/internal/pdf"
+)
+
+const (
+	slipColumns = 2
+	slipRows    = 5
+	slipMargin  = 12 * pdf.MM
+)
+
+type Slip struct {
+	SchoolName  string
+	StudentName string
+	ClassLabel  string
+	RollNumber  int
+	Code        string
+	ExpiresAt   time.Time
+}
+
+// RenderSlips lays slips out ten to an A4 page, separated by cut lines.
+func RenderSlips(doc *pdf.Document, slips []Slip) {
+	width := (pdf.A4Width - 2*slipMargin) / slipColumns
+	height := (pdf.A4Height - 2*slipMargin) / slipRows
+	perPage := slipColumns * slipRows
+
+	var page *pdf.Page
+	for i, slip := range slips {
+		n := i % perPage
+		if n == 0 {
+			page = doc.AddPage(pdf.A4Width, pdf.A4Height)
+			drawCutLines(page, width, height)
+		}
+		x := slipMargin + float64(n%slipColumns)*width
+		y := slipMargin + float64(n/slipColumns)*height
+		drawSlip(page, x, y, width, height, slip)
+	}
+}
+
+func drawCutLines(page *pdf.Page, width, height float64) {
+	page.SetLineWidth(0.3)
+	page.SetStrokeColor(170, 170, 170)
+	for c := 0; c <= slipColumns; c++ {
+		x := slipMargin + float64(c)*width
+		page.Line(x, slipMargin, x, pdf.A4Height-slipMargin)
+	}
+	for r := 0; r <= slipRows; r++ {
+		y := slipMargin + float64(r)*height
+		page.Line(slipMargin, y, pdf.A4Width-slipMargin, y)
+	}
+}
+
+func drawSlip(page *pdf.Page, x, y, width, height float64, slip Slip) {
+	pad := 5 * pdf.MM
+	inner := width - 2*pad
+	lineY := y + pad + 3*pdf.MM
+
+	page.SetFillColor(27, 54, 93)
+	page.Text(x+pad, lineY, pdf.HelveticaBold, 9, pdf.Truncate(pdf.HelveticaBold, 9, inner, slip.SchoolName))
+	lineY += 4.5 * pdf.MM
+	page.SetFillColor(110, 110, 110)
+	page.Text(x+pad, lineY, pdf.Helvetica, 7, "Parent app link code")
+
+	lineY += 7 * pdf.MM
+	page.SetFillColor(20, 20, 20)
+	page.Text(x+pad, lineY, pdf.HelveticaBold, 10, pdf.Truncate(pdf.HelveticaBold, 10, inner, slip.StudentName))
+	lineY += 4.5 * pdf.MM
+	class := slip.ClassLabel
+	if slip.RollNumber > 0 {
+		class += " / Roll " + strconv.Itoa(slip.RollNumber)
+	}
+	page.Text(x+pad, lineY, pdf.Helvetica, 8, pdf.Truncate(pdf.Helvetica, 8, inner, class))
+
+	lineY += 4 * pdf.MM
+	boxHeight := 10 * pdf.MM
+	page.SetFillColor(240, 244, 250)
+	page.SetStrokeColor(27, 54, 93)
+	page.SetLineWidth(0.6)
+	page.Rect(x+pad, lineY, inner, boxHeight, true, true)
+	page.SetFillColor(20, 20, 20)
+	codeSize := 15.0
+	codeWidth := pdf.TextWidth(pdf.HelveticaBold, codeSize, slip.Code)
+	page.Text(x+pad+(inner-codeWidth)/2, lineY+boxHeight/2+codeSize*0.35, pdf.HelveticaBold, codeSize, slip.Code)
+
+	lineY += boxHeight + 4.5*pdf.MM
+	page.SetFillColor(90, 90, 90)
+	notes := []string{
+		"Enter this code in the parent app to link to your child.",
+		"It works once and expires on " + slip.ExpiresAt.Format("02 Jan 2006") + ". Keep it private.",
+	}
+	for _, note := range notes {
+		page.Text(x+pad, lineY, pdf.Helvetica, 6.5, pdf.Truncate(pdf.Helvetica, 6.5, inner, note))
+		lineY += 3.5 * pdf.MM
+	}
+}
@@ -9,9 +9,15 @@ const (
 	RoleAdmin      Role = "admin"
 	RoleStaff      Role = "staff"
 	RoleTeacher    Role = "teacher"
+	RoleNurse      Role = "nurse"
 	RoleParent     Role = "parent"
 )
 
+const (
+	StudentStatusActive = "active"
+	StudentStatusAlumni = "alumni"
+)
+
 type School struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
@@ -31,58 +37,147 @@ type User struct {
 }
 
 type Student struct {
-	ID            string    `json:"id"`
-	SchoolID      string    `json:"school_id"`
-	FullName      string    `json:"full_name"`
-	ClassLabel    string    `json:"class_label"`
-	RollNumber    int       `json:"roll_number"`
-	DateOfBirth   time.Time `json:"date_of_birth"`
-	House         string    `json:"house"`
-	ParentPhone   string    `json:"parent_phone"`
-	AdmissionYear int       `json:"admission_year"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID             string     `json:"id"`
+	SchoolID       string     `json:"school_id"`
+	FullName       string     `json:"full_name"`
+	ClassLabel     string     `json:"class_label"`
+	RollNumber     int        `json:"roll_number"`
+	DateOfBirth    time.Time  `json:"date_of_birth"`
+	House          string     `json:"house"`
+	ParentPhone    string     `json:"parent_phone"`
+	AdmissionYear  int        `json:"admission_year"`
+	AdmissionNo    string     `json:"admission_no"`
+	APAARID        string     `json:"apaar_id"`
+	PEN            string     `json:"pen"`
+	Status         string     `json:"status"`
+	GraduationYear *int       `json:"graduation_year,omitempty"`
+	ArchivedAt     *time.Time `json:"archived_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
+type Guardian struct {
+	ID           string    `json:"id"`
+	StudentID    string    `json:"student_id"`
+	FullName     string    `json:"full_name"`
+	Relationship string    `json:"relationship"`
+	Phone        string    `json:"phone"`
+	Email        string    `json:"email"`
+	IsPrimary    bool      `json:"is_primary"`
+	CanPickUp    bool      `json:"can_pick_up"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Parent link statuses.
+const (
+	ParentLinkPending  = "pending"
+	ParentLinkApproved = "approved"
+	ParentLinkRejected = "rejected"
+	ParentLinkRevoked  = "revoked"
+)
+
 type ParentLink struct {
-	ID        string    `json:"id"`
-	ParentID  string    `json:"parent_id"`
-	StudentID string    `json:"student_id"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string     `json:"id"`
+	ParentID  string     `json:"parent_id"`
+	StudentID string     `json:"student_id"`
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 type ParentLinkApprovalItem struct {
-	ID          string    `json:"id"`
-	ParentID    string    `json:"parent_id"`
-	ParentName  string    `json:"parent_name"`
-	ParentPhone string    `json:"parent_phone"`
-	StudentID   string    `json:"student_id"`
-	StudentName string    `json:"student_name"`
-	ClassLabel  string    `json:"class_label"`
-	RollNumber  int       `json:"roll_number"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID                   string     `json:"id"`
+	ParentID             string     `json:"parent_id"`
+	ParentName           string     `json:"parent_name"`
+	ParentPhone          string     `json:"parent_phone"`
+	StudentID            string     `json:"student_id"`
+	StudentName          string     `json:"student_name"`
+	ClassLabel           string     `json:"class_label"`
+	RollNumber           int        `json:"roll_number"`
+	Status               string     `json:"status"`
+	GuardianRelationship string     `json:"guardian_relationship"`
+	PhoneMatch           string     `json:"phone_match,omitempty"`
+	Reason               string     `json:"reason,omitempty"`
+	AgeDays              int        `json:"age_days"`
+	RemindedAt           *time.Time `json:"reminded_at,omitempty"`
+	EscalatedAt          *time.Time `json:"escalated_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+}
+
+// PendingLinkDigest summarises the overdue link requests of one school that
+// a reminder or escalation covers.
+type PendingLinkDigest struct {
+	SchoolID   string
+	SchoolName string
+	Count      int
+	OldestAt   time.Time
 }
 
+type ParentLinkMetrics struct {
+	SchoolID            string     `json:"school_id"`
+	SchoolName          string     `json:"school_name"`
+	Approved            int        `json:"approved"`
+	MedianApprovalHours *float64   `json:"median_approval_hours"`
+	Pending             int        `json:"pending"`
+	Escalated           int        `json:"escalated"`
+	OldestPendingAt     *time.Time `json:"oldest_pending_at,omitempty"`
+}
+
+// Subject is an entry in a class's subject catalogue. Locked subjects cannot
+// be renamed, re-aliased or deleted, and their scores cannot change.
 type Subject struct {
 	ID        string    `json:"id"`
 	SchoolID  string    `json:"school_id"`
 	Class     string    `json:"class"`
 	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
 	Locked    bool      `json:"locked"`
+	ExamCount int       `json:"exam_count"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Exam statuses. Scores become visible to parents once results are
+// published; a locked exam accepts no further changes.
+const (
+	ExamDraft            = "draft"
+	ExamScoresEntered    = "scores_entered"
+	ExamResultsPublished = "results_published"
+	ExamLocked           = "locked"
+)
+
 type Exam struct {
-	ID        string    `json:"id"`
-	SchoolID  string    `json:"school_id"`
-	Class     string    `json:"class"`
-	Title     string    `json:"title"`
-	Term      string    `json:"term"`
-	Date      time.Time `json:"date"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          string     `json:"id"`
+	SchoolID    string     `json:"school_id"`
+	Class       string     `json:"class"`
+	Title       string     `json:"title"`
+	Term        string     `json:"term"`
+	Date        time.Time  `json:"date"`
+	Status      string     `json:"status"`
+	ScoreCount  int        `json:"score_count"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// Subjects the exam declares, in order. Only filled for a single exam.
+	Subjects []ExamSubject `json:"subjects,omitempty"`
+}
+
+type ExamSubject struct {
+	SubjectID string  `json:"subject_id"`
+	Name      string  `json:"name"`
+	MaxScore  float32 `json:"max_score"`
 }
 
+// Score statuses. Only present scores carry marks and a grade; the others
+// record why the student has none and are stored with a score of 0.
+const (
+	ScorePresent      = "present"
+	ScoreAbsent       = "absent"
+	ScoreMedicalLeave = "medical_leave"
+	ScoreExempt       = "exempt"
+)
+
 type Score struct {
 	ID        string    `json:"id"`
 	ExamID    string    `json:"exam_id"`
@@ -91,9 +186,70 @@ type Score struct {
 	Score     float32   `json:"score"`
 	MaxScore  float32   `json:"max_score"`
 	Grade     string    `json:"grade"`
+	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type ScoreRevision struct {
+	ID               string    `json:"id"`
+	ExamID           string    `json:"exam_id"`
+	StudentID        string    `json:"student_id"`
+	Subject          string    `json:"subject"`
+	Action           string    `json:"action"`
+	PreviousScore    *float32  `json:"previous_score"`
+	PreviousMaxScore *float32  `json:"previous_max_score"`
+	PreviousGrade    *string   `json:"previous_grade"`
+	Score            *float32  `json:"score"`
+	MaxScore         *float32  `json:"max_score"`
+	Grade            *string   `json:"grade"`
+	PreviousStatus   *string   `json:"previous_status"`
+	Status           *string   `json:"status"`
+	Reason           string    `json:"reason"`
+	ChangedBy        string    `json:"changed_by,omitempty"`
+	ChangedByName    string    `json:"changed_by_name,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// GradeBand awards Grade to scores of at least MinPercent.
+type GradeBand struct {
+	Grade      string  `json:"grade"`
+	MinPercent float64 `json:"min_percent"`
+}
+
+type GradingScheme struct {
+	ID        string      `json:"id"`
+	SchoolID  string      `json:"school_id"`
+	Class     string      `json:"class"`
+	Name      string      `json:"name"`
+	Bands     []GradeBand `json:"bands"`
+	UpdatedBy string      `json:"updated_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ReportCardTemplate is a school's report card layout. The zero value of
+// each Hide flag shows that section.
+type ReportCardTemplate struct {
+	Title          string   `json:"title"`
+	Subtitle       string   `json:"subtitle"`
+	AccentColor    string   `json:"accent_color"`
+	HideGrades     bool     `json:"hide_grades"`
+	HideAttendance bool     `json:"hide_attendance"`
+	HideRemarks    bool     `json:"hide_remarks"`
+	Signatures     []string `json:"signatures"`
+	Footer         string   `json:"footer"`
+}
+
+type ReportCardRemarks struct {
+	StudentID   string    `json:"student_id"`
+	Term        string    `json:"term"`
+	Remarks     string    `json:"remarks"`
+	DaysPresent *int      `json:"days_present"`
+	WorkingDays *int      `json:"working_days"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 type Announcement struct {
 	ID        string    `json:"id"`
 	SchoolID  string    `json:"school_id"`
@@ -123,12 +279,15 @@ type Event struct {
 	CreatedAt   time.Time  `json:"created_at"`
 }
 
+// DashboardWidget is a tile on the parent app's home screen. A widget with
+// a Metric has its Value computed for the child being viewed.
 type DashboardWidget struct {
-	Key   string `json:"key"`
-	Label string `json:"label"`
-	Value string `json:"value"`
-	Hint  string `json:"hint"`
-	Icon  string `json:"icon"`
+	Key    string `json:"key"`
+	Label  string `json:"label"`
+	Value  string `json:"value"`
+	Hint   string `json:"hint"`
+	Icon   string `json:"icon"`
+	Metric string `json:"metric,omitempty"`
 }
 
 type AppConfig struct {
@@ -158,3 +317,256 @@ type AuditEvent struct {
 	Payload   string    `json:"payload"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type StudentDocument struct {
+	ID              string     `json:"id"`
+	SchoolID        string     `json:"school_id"`
+	StudentID       string     `json:"student_id"`
+	DocType         string     `json:"doc_type"`
+	Status          string     `json:"status"`
+	StorageKey      string     `json:"-"`
+	FileName        string     `json:"file_name"`
+	ContentType     string     `json:"content_type"`
+	SizeBytes       int64      `json:"size_bytes"`
+	SHA256          string     `json:"sha256"`
+	UploadedBy      string     `json:"uploaded_by"`
+	VerifiedBy      string     `json:"verified_by,omitempty"`
+	VerifiedAt      *time.Time `json:"verified_at,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	ExpiresOn       *time.Time `json:"expires_on,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type DocumentChecklistItem struct {
+	StudentID   string   `json:"student_id"`
+	StudentName string   `json:"student_name"`
+	ClassLabel  string   `json:"class_label"`
+	RollNumber  int      `json:"roll_number"`
+	Missing     []string `json:"missing"`
+	Pending     []string `json:"pending"`
+	Expired     []string `json:"expired"`
+	Complete    bool     `json:"complete"`
+}
+
+type HealthProfile struct {
+	StudentID         string    `json:"student_id"`
+	SchoolID          string    `json:"school_id"`
+	BloodGroup        string    `json:"blood_group"`
+	Allergies         string    `json:"allergies"`
+	ChronicConditions string    `json:"chronic_conditions"`
+	Medications       string    `json:"medications"`
+	Notes             string    `json:"notes"`
+	UpdatedBy         string    `json:"updated_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type InfirmaryVisit struct {
+	ID             string     `json:"id"`
+	SchoolID       string     `json:"school_id"`
+	StudentID      string     `json:"student_id"`
+	StudentName    string     `json:"student_name,omitempty"`
+	ClassLabel     string     `json:"class_label,omitempty"`
+	VisitedAt      time.Time  `json:"visited_at"`
+	Complaint      string     `json:"complaint"`
+	Diagnosis      string     `json:"diagnosis"`
+	Treatment      string     `json:"treatment"`
+	Status         string     `json:"status"`
+	DischargedAt   *time.Time `json:"discharged_at,omitempty"`
+	DischargeNotes string     `json:"discharge_notes"`
+	RecordedBy     string     `json:"recorded_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type InfirmarySummary struct {
+	Date          string           `json:"date"`
+	VisitCount    int              `json:"visit_count"`
+	ReferredCount int              `json:"referred_count"`
+	AdmittedCount int              `json:"admitted_count"`
+	Admitted      []InfirmaryVisit `json:"admitted"`
+}
+
+type AlumniContact struct {
+	StudentID    string    `json:"student_id"`
+	Phone        string    `json:"phone"`
+	Email        string    `json:"email"`
+	City         string    `json:"city"`
+	Occupation   string    `json:"occupation"`
+	ShareContact bool      `json:"share_contact"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type AlumniEntry struct {
+	StudentID      string         `json:"student_id"`
+	FullName       string         `json:"full_name"`
+	ClassLabel     string         `json:"class_label"`
+	RollNumber     int            `json:"roll_number"`
+	House          string         `json:"house"`
+	AdmissionYear  int            `json:"admission_year"`
+	GraduationYear int            `json:"graduation_year"`
+	Contact        *AlumniContact `json:"contact,omitempty"`
+}
+
+type ScoreRecord struct {
+	Score
+	ExamTitle string    `json:"exam_title"`
+	ExamTerm  string    `json:"exam_term"`
+	ExamClass string    `json:"exam_class"`
+	ExamDate  time.Time `json:"exam_date"`
+}
+
+type FamilyMember struct {
+	StudentID   string `json:"student_id"`
+	FullName    string `json:"full_name"`
+	ClassLabel  string `json:"class_label"`
+	RollNumber  int    `json:"roll_number"`
+	House       string `json:"house"`
+	ParentPhone string `json:"parent_phone"`
+	Status      string `json:"status"`
+}
+
+type FamilyParent struct {
+	ParentID   string   `json:"parent_id"`
+	FullName   string   `json:"full_name"`
+	Phone      string   `json:"phone"`
+	StudentIDs []string `json:"student_ids"`
+}
+
+type Family struct {
+	ID        string         `json:"id"`
+	SchoolID  string         `json:"school_id"`
+	PhoneKey  string         `json:"phone_key"`
+	CreatedBy string         `json:"created_by"`
+	Members   []FamilyMember `json:"members"`
+	Parents   []FamilyParent `json:"parents,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// FamilySuggestion groups active students sharing a parent phone. FamilyID is
+// set when some of them already form a confirmed family.
+type FamilySuggestion struct {
+	PhoneKey string         `json:"phone_key"`
+	FamilyID string         `json:"family_id,omitempty"`
+	Students []FamilyMember `json:"students"`
+}
+
+type SiblingOffer struct {
+	StudentID     string `json:"student_id"`
+	FullName      string `json:"full_name"`
+	ClassLabel    string `json:"class_label"`
+	RollNumber    int    `json:"roll_number"`
+	FamilyID      string `json:"family_id"`
+	LinkedStudent string `json:"linked_student_id"`
+}
+
+type ExamSummary struct {
+	ExamID     string    `json:"exam_id"`
+	Title      string    `json:"title"`
+	Term       string    `json:"term"`
+	Date       time.Time `json:"date"`
+	Subjects   int       `json:"subjects"`
+	TotalScore float32   `json:"total_score"`
+	TotalMax   float32   `json:"total_max"`
+	Percentage float64   `json:"percentage"`
+}
+
+// TrendPoint is a percentage in one exam, for charts across exams.
+type TrendPoint struct {
+	ExamID     string    `json:"exam_id"`
+	Title      string    `json:"title"`
+	Date       time.Time `json:"date"`
+	Percentage float64   `json:"percentage"`
+}
+
+type SubjectTrend struct {
+	Subject    string       `json:"subject"`
+	Percentage float64      `json:"percentage"`
+	Points     []TrendPoint `json:"points"`
+	// Change is the difference from the previous exam, in percentage points.
+	Change *float64 `json:"change"`
+}
+
+// StudentAnalytics is a student's standing in their class over a set of
+// exams. Percentage, rank and percentile are nil or zero when the student
+// has no scores in them.
+type StudentAnalytics struct {
+	StudentID  string         `json:"student_id"`
+	Class      string         `json:"class"`
+	Term       string         `json:"term,omitempty"`
+	Exams      int            `json:"exams"`
+	Percentage *float64       `json:"percentage"`
+	Grade      string         `json:"grade,omitempty"`
+	Rank       int            `json:"rank,omitempty"`
+	ClassSize  int            `json:"class_size"`
+	Percentile *float64       `json:"percentile"`
+	Trend      []TrendPoint   `json:"trend"`
+	Change     *float64       `json:"change"`
+	Subjects   []SubjectTrend `json:"subjects"`
+}
+
+type Topper struct {
+	StudentID  string  `json:"student_id"`
+	Name       string  `json:"name"`
+	RollNumber int     `json:"roll_number"`
+	Rank       int     `json:"rank"`
+	Percentage float64 `json:"percentage"`
+}
+
+type SubjectStats struct {
+	Subject  string   `json:"subject"`
+	Students int      `json:"students"`
+	Average  float64  `json:"average"`
+	Highest  float64  `json:"highest"`
+	Lowest   float64  `json:"lowest"`
+	Toppers  []Topper `json:"toppers"`
+}
+
+// ClassAnalytics summarises a class over a set of exams for staff.
+type ClassAnalytics struct {
+	Class    string         `json:"class"`
+	Term     string         `json:"term,omitempty"`
+	Exams    int            `json:"exams"`
+	Students int            `json:"students"`
+	Average  *float64       `json:"average"`
+	Toppers  []Topper       `json:"toppers"`
+	Subjects []SubjectStats `json:"subjects"`
+	Trend    []TrendPoint   `json:"trend"`
+}
+
+// Import job statuses. A rejected job found errors in the sheet and imported
+// nothing; a failed job stopped on an error of its own.
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobSucceeded = "succeeded"
+	ImportJobRejected  = "rejected"
+	ImportJobFailed    = "failed"
+	ImportJobCancelled = "cancelled"
+)
+
+// ImportJob is an uploaded sheet waiting for, or processed by, an import
+// worker. Result holds the same summary the upload used to return inline,
+// including the row errors of a rejected sheet.
+type ImportJob struct {
+	ID              string                 `json:"id"`
+	SchoolID        string                 `json:"school_id"`
+	Kind            string                 `json:"kind"`
+	Status          string                 `json:"status"`
+	Params          map[string]string      `json:"params"`
+	FileName        string                 `json:"file_name"`
+	UploadKey       string                 `json:"upload_key"`
+	CreatedBy       string                 `json:"created_by"`
+	ProgressDone    int                    `json:"progress_done"`
+	ProgressTotal   int                    `json:"progress_total"`
+	Result          map[string]interface{} `json:"result,omitempty"`
+	Error           string                 `json:"error,omitempty"`
+	Attempts        int                    `json:"attempts"`
+	CancelRequested bool                   `json:"cancel_requested"`
+	CreatedAt       time.Time              `json:"created_at"`
+	StartedAt       *time.Time             `json:"started_at,omitempty"`
+	FinishedAt      *time.Time             `json:"finished_at,omitempty"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
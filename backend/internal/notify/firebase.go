@@ -44,6 +44,43 @@ func (s *FirebaseSender) SendToSchoolParents(ctx context.Context, schoolID, titl
 	if err != nil {
 		return err
 	}
+	return s.sendMulticast(ctx, tokens, title, body, data)
+}
+
+func (s *FirebaseSender) SendToStudentGuardians(ctx context.Context, studentID, title, body string, data map[string]string) error {
+	if s == nil || s.client == nil || s.store == nil {
+		return nil
+	}
+	tokens, err := s.store.ListDeviceTokensByStudentGuardians(ctx, studentID)
+	if err != nil {
+		return err
+	}
+	return s.sendMulticast(ctx, tokens, title, body, data)
+}
+
+func (s *FirebaseSender) SendToClassGuardians(ctx context.Context, schoolID, classLabel, title, body string, data map[string]string) error {
+	if s == nil || s.client == nil || s.store == nil {
+		return nil
+	}
+	tokens, err := s.store.ListDeviceTokensByClassGuardians(ctx, schoolID, classLabel)
+	if err != nil {
+		return err
+	}
+	return s.sendMulticast(ctx, tokens, title, body, data)
+}
+
+func (s *FirebaseSender) SendToUser(ctx context.Context, userID, title, body string, data map[string]string) error {
+	if s == nil || s.client == nil || s.store == nil {
+		return nil
+	}
+	tokens, err := s.store.ListDeviceTokensByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.sendMulticast(ctx, tokens, title, body, data)
+}
+
+func (s *FirebaseSender) sendMulticast(ctx context.Context, tokens []string, title, body string, data map[string]string) error {
 	if len(tokens) == 0 {
 		return nil
 	}
@@ -55,7 +92,10 @@ func (s *FirebaseSender) SendToSchoolParents(ctx context.Context, schoolID, titl
 		},
 		Data: data,
 	}
-	var lastErr error
+	var (
+		lastErr error
+		err     error
+	)
 	backoff := 300 * time.Millisecond
 	for attempt := 1; attempt <= 3; attempt++ {
 		_, err = s.client.SendEachForMulticast(ctx, msg)
@@ -7,3 +7,15 @@ type NoopSender struct{}
 func (NoopSender) SendToSchoolParents(_ context.Context, _ string, _ string, _ string, _ map[string]string) error {
 	return nil
 }
+
+func (NoopSender) SendToStudentGuardians(_ context.Context, _ string, _ string, _ string, _ map[string]string) error {
+	return nil
+}
+
+func (NoopSender) SendToClassGuardians(_ context.Context, _ string, _ string, _ string, _ string, _ map[string]string) error {
+	return nil
+}
+
+func (NoopSender) SendToUser(_ context.Context, _ string, _ string, _ string, _ map[string]string) error {
+	return nil
+}
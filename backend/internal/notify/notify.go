@@ -4,4 +4,7 @@ import "context"
 
 type Sender interface {
 	SendToSchoolParents(ctx context.Context, schoolID, title, body string, data map[string]string) error
+	SendToStudentGuardians(ctx context.Context, studentID, title, body string, data map[string]string) error
+	SendToClassGuardians(ctx context.Context, schoolID, classLabel, title, body string, data map[string]string) error
+	SendToUser(ctx context.Context, userID, title, body string, data map[string]string) error
 }
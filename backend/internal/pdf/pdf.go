@@ -0,0 +1,289 @@
+// Package pdf writes simple PDF documents: text in the standard Helvetica
+// faces, filled and stroked rectangles, lines and raster images. Coordinates
+// are in points with the origin at the top-left corner of the page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strings"
+)
+
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+	MM       = 72 / 25.4
+)
+
+var ErrUnsupportedImage = errors.New("pdf: unsupported image format")
+
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold"}
+
+type Document struct {
+	pages  []*Page
+	images []*Image
+}
+
+type Page struct {
+	width   float64
+	height  float64
+	content bytes.Buffer
+}
+
+type Image struct {
+	name   string
+	width  int
+	height int
+	filter string
+	color  string
+	data   []byte
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{width: width, height: height}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// AddJPEG embeds JPEG data as-is.
+func (d *Document) AddJPEG(data []byte) (*Image, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	colorSpace := "/DeviceRGB"
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		colorSpace = "/DeviceGray"
+	case color.CMYKModel:
+		return nil, ErrUnsupportedImage
+	}
+	img := &Image{width: cfg.Width, height: cfg.Height, filter: "/DCTDecode", color: colorSpace, data: data}
+	d.addImage(img)
+	return img, nil
+}
+
+// AddImage embeds any decoded image as deflated RGB samples.
+func (d *Document) AddImage(src image.Image) *Image {
+	bounds := src.Bounds()
+	raw := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := src.At(x, y).RGBA()
+			// Composite over white so transparent areas do not print black.
+			white := 0xffff - a
+			raw = append(raw, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+	}
+	img := &Image{width: bounds.Dx(), height: bounds.Dy(), filter: "/FlateDecode", color: "/DeviceRGB", data: deflate(raw)}
+	d.addImage(img)
+	return img
+}
+
+func (d *Document) addImage(img *Image) {
+	img.name = fmt.Sprintf("Im%d", len(d.images)+1)
+	d.images = append(d.images, img)
+}
+
+func (img *Image) Size() (int, int) {
+	return img.width, img.height
+}
+
+func (p *Page) SetFillColor(r, g, b uint8) {
+	fmt.Fprintf(&p.content, "%s %s %s rg\n", colorComponent(r), colorComponent(g), colorComponent(b))
+}
+
+func (p *Page) SetStrokeColor(r, g, b uint8) {
+	fmt.Fprintf(&p.content, "%s %s %s RG\n", colorComponent(r), colorComponent(g), colorComponent(b))
+}
+
+func (p *Page) SetLineWidth(width float64) {
+	fmt.Fprintf(&p.content, "%s w\n", num(width))
+}
+
+// Rect draws a rectangle whose top-left corner is at (x, y).
+func (p *Page) Rect(x, y, w, h float64, fill, stroke bool) {
+	op := "S"
+	switch {
+	case fill && stroke:
+		op = "B"
+	case fill:
+		op = "f"
+	}
+	fmt.Fprintf(&p.content, "%s %s %s %s re %s\n", num(x), num(p.height-y-h), num(w), num(h), op)
+}
+
+func (p *Page) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "%s %s m %s %s l S\n", num(x1), num(p.height-y1), num(x2), num(p.height-y2))
+}
+
+// Text draws a single line of text with its baseline at y. Characters
+// outside Windows-1252 are replaced with '?'.
+func (p *Page) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font+1, num(size), num(x), num(p.height-y), escapeText(text))
+}
+
+// Image draws img scaled into the box whose top-left corner is at (x, y).
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n", num(w), num(h), num(x), num(p.height-y-h), img.name)
+}
+
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write serialises the document. Object numbers are fixed as: 1 catalog,
+// 2 page tree, then fonts, images and one page/content pair per page.
+func (d *Document) Write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+
+	fontBase := 3
+	imageBase := fontBase + len(fontNames)
+	pageBase := imageBase + len(d.images)
+
+	var resources strings.Builder
+	resources.WriteString("<< /Font <<")
+	for i := range fontNames {
+		fmt.Fprintf(&resources, " /F%d %d 0 R", i+1, fontBase+i)
+	}
+	resources.WriteString(" >>")
+	if len(d.images) > 0 {
+		resources.WriteString(" /XObject <<")
+		for i, img := range d.images {
+			fmt.Fprintf(&resources, " /%s %d 0 R", img.name, imageBase+i)
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageBase+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+	for _, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name), nil)
+	}
+	for _, img := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter %s /Length %d >>",
+			img.width, img.height, img.color, img.filter, len(img.data)), img.data)
+	}
+	for i, page := range d.pages {
+		content := deflate(page.content.Bytes())
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			num(page.width), num(page.height), resources.String(), pageBase+2*i+1), nil)
+		object(fmt.Sprintf("<< /Filter /FlateDecode /Length %d >>", len(content)), content)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+func colorComponent(v uint8) string {
+	return num(float64(v) / 255)
+}
+
+func escapeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// winAnsi maps a rune to its Windows-1252 byte. Only Latin-1 and a few
+// common punctuation marks are supported.
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+		return byte(r), true
+	case r == '–':
+		return 0x96, true
+	case r == '—':
+		return 0x97, true
+	case r == '‘':
+		return 0x91, true
+	case r == '’':
+		return 0x92, true
+	case r == '“':
+		return 0x93, true
+	case r == '”':
+		return 0x94, true
+	case r == '•':
+		return 0x95, true
+	}
+	return 0, false
+}
@@ -0,0 +1,205 @@
+// Package qrcode encodes short byte strings as QR Code symbols (model 2,
+// byte mode, error correction level M, versions 1 to 10).
+package qrcode
+
+import "errors"
+
+var ErrTooLong = errors.New("qrcode: data too long")
+
+type Code struct {
+	Size    int
+	modules [][]bool
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+type versionInfo struct {
+	ecPerBlock int
+	groups     [][2]int // {block count, data codewords per block}
+	alignment  []int
+}
+
+// Level M block layout from ISO/IEC 18004 table 9.
+var versions = []versionInfo{
+	{},
+	{10, [][2]int{{1, 16}}, nil},
+	{16, [][2]int{{1, 28}}, []int{6, 18}},
+	{26, [][2]int{{1, 44}}, []int{6, 22}},
+	{18, [][2]int{{2, 32}}, []int{6, 26}},
+	{24, [][2]int{{2, 43}}, []int{6, 30}},
+	{16, [][2]int{{4, 27}}, []int{6, 34}},
+	{18, [][2]int{{4, 31}}, []int{6, 22, 38}},
+	{22, [][2]int{{2, 38}, {2, 39}}, []int{6, 24, 42}},
+	{22, [][2]int{{3, 36}, {2, 37}}, []int{6, 26, 46}},
+	{26, [][2]int{{4, 43}, {1, 44}}, []int{6, 28, 50}},
+}
+
+func (v versionInfo) dataCodewords() int {
+	total := 0
+	for _, group := range v.groups {
+		total += group[0] * group[1]
+	}
+	return total
+}
+
+func Encode(data []byte) (*Code, error) {
+	return encode(data, -1)
+}
+
+// encode draws the symbol with the given mask pattern, or with the one of
+// lowest penalty when mask is -1. Encoders score masks differently, so
+// comparing symbols from two of them needs the mask fixed.
+func encode(data []byte, mask int) (*Code, error) {
+	version := 0
+	for v := 1; v < len(versions); v++ {
+		if 4+countBits(v)+8*len(data) <= versions[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+	info := versions[version]
+
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := info.dataCodewords() * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := interleave(bits.bytes(), info)
+	c := newMatrix(version, info)
+	c.drawCodewords(codewords)
+
+	if mask < 0 {
+		bestPenalty := -1
+		for candidate := 0; candidate < 8; candidate++ {
+			c.applyMask(candidate)
+			c.drawFormatBits(candidate)
+			penalty := c.penalty()
+			if bestPenalty < 0 || penalty < bestPenalty {
+				mask, bestPenalty = candidate, penalty
+			}
+			c.applyMask(candidate)
+		}
+	}
+	c.applyMask(mask)
+	c.drawFormatBits(mask)
+
+	return &Code{Size: c.size, modules: c.modules}, nil
+}
+
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+func interleave(data []byte, info versionInfo) []byte {
+	divisor := rsDivisor(info.ecPerBlock)
+	var blocks, ecBlocks [][]byte
+	offset := 0
+	for _, group := range info.groups {
+		for i := 0; i < group[0]; i++ {
+			block := data[offset : offset+group[1]]
+			offset += group[1]
+			blocks = append(blocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+		}
+	}
+
+	var out []byte
+	for i := 0; ; i++ {
+		added := false
+		for _, block := range blocks {
+			if i < len(block) {
+				out = append(out, block[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for i := 0; i < info.ecPerBlock; i++ {
+		for _, ec := range ecBlocks {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
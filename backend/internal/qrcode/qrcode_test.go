@@ -0,0 +1,87 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The testdata symbols were produced by github.com/skip2/go-qrcode at level
+// M, without the quiet zone. Each file starts with "# <data>",
+// "# version <n>" and "# mask <n>", the mask pattern that encoder chose,
+// followed by one line per row with '#' for dark modules.
+func TestEncodeKnownVectors(t *testing.T) {
+	for _, name := range []string{"version1.txt", "version7.txt", "version10.txt"} {
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+			data := strings.TrimPrefix(lines[0], "# ")
+			version, err := strconv.Atoi(strings.TrimPrefix(lines[1], "# version "))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mask, err := strconv.Atoi(strings.TrimPrefix(lines[2], "# mask "))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := lines[3:]
+
+			code, err := encode([]byte(data), mask)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code.Size != 17+4*version || len(want) != code.Size {
+				t.Fatalf("size = %d, want %d (version %d)", code.Size, len(want), version)
+			}
+			for y, row := range want {
+				var got bytes.Buffer
+				for x := 0; x < code.Size; x++ {
+					if code.Dark(x, y) {
+						got.WriteByte('#')
+					} else {
+						got.WriteByte('.')
+					}
+				}
+				if got.String() != row {
+					t.Errorf("row %d:\n got %s\nwant %s", y, got.String(), row)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeCapacity(t *testing.T) {
+	tests := []struct {
+		length  int
+		version int
+		err     error
+	}{
+		{14, 1, nil},
+		{15, 2, nil},
+		{122, 7, nil},
+		{213, 10, nil},
+		{214, 0, ErrTooLong},
+		{1000, 0, ErrTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.length), func(t *testing.T) {
+			code, err := Encode(bytes.Repeat([]byte("a"), tt.length))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Encode error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if code.Size != 17+4*tt.version {
+				t.Errorf("size = %d, want version %d", code.Size, tt.version)
+			}
+		})
+	}
+}
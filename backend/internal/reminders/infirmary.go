@@ -0,0 +1,111 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/notify"
+	"jnv/backend/internal/store"
+)
+
+// ist is the schools' time zone; the summary day runs midnight to midnight
+// there.
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+// InfirmarySummary sends each school's nurses and admins a daily push with
+// the students currently admitted and the day's visits, once the school day
+// reaches Hour. Schools with nobody admitted and no visits get none.
+type InfirmarySummary struct {
+	Store    *store.Store
+	Notifier notify.Sender
+	Hour     int
+	Interval time.Duration
+}
+
+// Run checks once immediately and then every Interval until ctx is done.
+func (j InfirmarySummary) Run(ctx context.Context) {
+	interval := j.Interval
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := j.RunOnce(ctx, time.Now()); err != nil {
+			log.Printf("infirmary summary: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the summaries due at now. A school whose summary fails is
+// logged and tried again on the next run.
+func (j InfirmarySummary) RunOnce(ctx context.Context, now time.Time) error {
+	now = now.In(ist)
+	if now.Hour() < j.Hour {
+		return nil
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, ist)
+	schoolIDs, err := j.Store.ListInfirmarySummarySchools(ctx, day, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return fmt.Errorf("list schools: %w", err)
+	}
+	for _, schoolID := range schoolIDs {
+		if err := j.sendSchool(ctx, schoolID, day); err != nil {
+			log.Printf("infirmary summary: school %s: %v", schoolID, err)
+		}
+	}
+	return nil
+}
+
+func (j InfirmarySummary) sendSchool(ctx context.Context, schoolID string, day time.Time) error {
+	admitted, err := j.Store.ListAdmittedStudents(ctx, schoolID)
+	if err != nil {
+		return fmt.Errorf("list admitted: %w", err)
+	}
+	visits, referred, err := j.Store.CountInfirmaryVisits(ctx, schoolID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return fmt.Errorf("count visits: %w", err)
+	}
+	var userIDs []string
+	for _, role := range []models.Role{models.RoleNurse, models.RoleAdmin} {
+		ids, err := j.Store.ListUserIDsByRole(ctx, schoolID, role)
+		if err != nil {
+			return fmt.Errorf("list %s users: %w", role, err)
+		}
+		userIDs = append(userIDs, ids...)
+	}
+	claimed, err := j.Store.ClaimInfirmarySummary(ctx, schoolID, day)
+	if err != nil || !claimed {
+		return err
+	}
+
+	body := fmt.Sprintf("%s currently admitted. Today: %s, %d referred to hospital.",
+		countOf(len(admitted), "student"), countOf(visits, "visit"), referred)
+	data := map[string]string{
+		"type":      "infirmary_summary",
+		"school_id": schoolID,
+		"date":      day.Format("2006-01-02"),
+		"admitted":  fmt.Sprint(len(admitted)),
+	}
+	for _, userID := range userIDs {
+		if err := j.Notifier.SendToUser(ctx, userID, "Infirmary daily summary", body, data); err != nil {
+			log.Printf("infirmary summary: notify %s: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func countOf(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,110 @@
+// Package reminders runs the background jobs that chase up work nobody has
+// acted on.
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/notify"
+	"jnv/backend/internal/store"
+)
+
+// ParentLinkSLA reminds school admins about link requests that have been
+// pending for RemindAfter, and escalates them to super admins once they have
+// been pending for EscalateAfter. A zero duration turns that step off.
+type ParentLinkSLA struct {
+	Store         *store.Store
+	Notifier      notify.Sender
+	RemindAfter   time.Duration
+	EscalateAfter time.Duration
+	Interval      time.Duration
+}
+
+// Run checks once immediately and then every Interval until ctx is done.
+func (j ParentLinkSLA) Run(ctx context.Context) {
+	interval := j.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := j.RunOnce(ctx); err != nil {
+			log.Printf("parent link sla: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the reminders and escalations that are due. A school whose
+// admins cannot be listed has its claim released, so its reminder goes out
+// on the next run rather than never.
+func (j ParentLinkSLA) RunOnce(ctx context.Context) error {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if j.RemindAfter > 0 {
+		digests, err := j.Store.ClaimParentLinkReminders(ctx, now.Add(-j.RemindAfter), now)
+		if err != nil {
+			return fmt.Errorf("claim reminders: %w", err)
+		}
+		for _, digest := range digests {
+			adminIDs, err := j.Store.ListUserIDsByRole(ctx, digest.SchoolID, models.RoleAdmin)
+			if err != nil {
+				log.Printf("parent link sla: list admins of %s: %v", digest.SchoolID, err)
+				if err := j.Store.ReleaseParentLinkReminders(ctx, digest.SchoolID, now); err != nil {
+					log.Printf("parent link sla: release reminders of %s: %v", digest.SchoolID, err)
+				}
+				continue
+			}
+			body := fmt.Sprintf("%s waiting for approval, the oldest since %s.",
+				pendingRequests(digest.Count), digest.OldestAt.Format("2 Jan"))
+			j.send(ctx, adminIDs, "Parent link requests waiting", body, digest, "reminder")
+		}
+	}
+	if j.EscalateAfter > 0 {
+		// Recipients are listed before anything is claimed, so a failure
+		// leaves the escalations for the next run.
+		superAdminIDs, err := j.Store.ListUserIDsByRole(ctx, "", models.RoleSuperAdmin)
+		if err != nil {
+			return fmt.Errorf("list super admins: %w", err)
+		}
+		digests, err := j.Store.ClaimParentLinkEscalations(ctx, now.Add(-j.EscalateAfter), now)
+		if err != nil {
+			return fmt.Errorf("claim escalations: %w", err)
+		}
+		for _, digest := range digests {
+			body := fmt.Sprintf("%s: %s still undecided after %d days.",
+				digest.SchoolName, pendingRequests(digest.Count), int(j.EscalateAfter.Hours()/24))
+			j.send(ctx, superAdminIDs, "Parent link requests overdue", body, digest, "escalation")
+		}
+	}
+	return nil
+}
+
+func (j ParentLinkSLA) send(ctx context.Context, userIDs []string, title, body string, digest models.PendingLinkDigest, kind string) {
+	data := map[string]string{
+		"type":      "parent_link_sla",
+		"kind":      kind,
+		"school_id": digest.SchoolID,
+		"count":     fmt.Sprint(digest.Count),
+	}
+	for _, userID := range userIDs {
+		if err := j.Notifier.SendToUser(ctx, userID, title, body, data); err != nil {
+			log.Printf("parent link sla: notify %s: %v", userID, err)
+		}
+	}
+}
+
+func pendingRequests(count int) string {
+	if count == 1 {
+		return "1 link request"
+	}
+	return fmt.Sprintf("%d link requests", count)
+}
@@ -0,0 +1,355 @@
+package reportcard
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/pdf"
+)
+
+const (
+	margin       = 15 * pdf.MM
+	headerHeight = 26 * pdf.MM
+	rowHeight    = 7 * pdf.MM
+	subjectWidth = 45 * pdf.MM
+	totalWidth   = 24 * pdf.MM
+	gradeWidth   = 16 * pdf.MM
+	// footerSpace keeps the table clear of the signature lines.
+	footerSpace = 30 * pdf.MM
+)
+
+// Render adds the card to doc, on as many A4 pages as the subject table
+// needs.
+func Render(doc *pdf.Document, tpl models.ReportCardTemplate, card Card) {
+	r, g, b, ok := parseColor(tpl.AccentColor)
+	if !ok {
+		r, g, b, _ = parseColor(DefaultTemplate().AccentColor)
+	}
+	accent := [3]uint8{r, g, b}
+	graded := card.Graded && !tpl.HideGrades
+
+	page := doc.AddPage(pdf.A4Width, pdf.A4Height)
+	y := drawHeader(page, tpl, card, accent)
+	y = drawDetails(page, card, y)
+
+	columns := tableColumns(len(card.Exams), graded)
+	y = drawTableHeader(page, card, columns, y, accent)
+	bottom := pdf.A4Height - margin - footerSpace
+	for i, row := range card.Subjects {
+		if y+rowHeight > bottom {
+			drawFooter(page, tpl)
+			page = doc.AddPage(pdf.A4Width, pdf.A4Height)
+			y = drawTableHeader(page, card, columns, margin, accent)
+		}
+		cells := []string{row.Name}
+		for _, marks := range row.Marks {
+			cells = append(cells, formatMarksPtr(marks))
+		}
+		cells = append(cells, formatTotal(row.Total))
+		if graded {
+			cells = append(cells, row.Grade)
+		}
+		if i%2 == 1 {
+			page.SetFillColor(246, 247, 249)
+			page.Rect(margin, y, pdf.A4Width-2*margin, rowHeight, true, false)
+		}
+		drawRow(page, columns, y, pdf.Helvetica, cells)
+		y += rowHeight
+	}
+	if len(card.Subjects) == 0 {
+		page.SetFillColor(110, 110, 110)
+		page.Text(margin+2*pdf.MM, y+4.8*pdf.MM, pdf.Helvetica, 8.5, "No marks recorded for this term.")
+		y += rowHeight
+	}
+
+	totals := []string{"Total"}
+	for _, marks := range card.Totals {
+		totals = append(totals, formatTotal(marks))
+	}
+	totals = append(totals, formatTotal(card.Total))
+	if graded {
+		totals = append(totals, card.Grade)
+	}
+	page.SetLineWidth(0.6)
+	page.SetStrokeColor(accent[0], accent[1], accent[2])
+	page.Line(margin, y, pdf.A4Width-margin, y)
+	drawRow(page, columns, y, pdf.HelveticaBold, totals)
+	y += rowHeight
+	if legend := codeLegend(card); legend != "" {
+		page.SetFillColor(110, 110, 110)
+		page.Text(margin+2*pdf.MM, y+4*pdf.MM, pdf.Helvetica, 7.5, legend)
+		y += 5 * pdf.MM
+	}
+	y += 5 * pdf.MM
+
+	summaryHeight := 14 * pdf.MM
+	if y+summaryHeight+remarksHeight(tpl, card) > bottom {
+		drawFooter(page, tpl)
+		page = doc.AddPage(pdf.A4Width, pdf.A4Height)
+		y = margin
+	}
+	y = drawSummary(page, tpl, card, graded, y)
+	drawRemarks(page, tpl, card, y)
+	drawFooter(page, tpl)
+}
+
+func drawHeader(page *pdf.Page, tpl models.ReportCardTemplate, card Card, accent [3]uint8) float64 {
+	width := pdf.A4Width - 2*margin
+	page.SetFillColor(accent[0], accent[1], accent[2])
+	page.Rect(0, 0, pdf.A4Width, headerHeight, true, false)
+	page.SetFillColor(255, 255, 255)
+	page.Text(margin, 10*pdf.MM, pdf.HelveticaBold, 15, pdf.Truncate(pdf.HelveticaBold, 15, width, card.SchoolName))
+	if tpl.Subtitle != "" {
+		page.Text(margin, 15*pdf.MM, pdf.Helvetica, 8.5, pdf.Truncate(pdf.Helvetica, 8.5, width, tpl.Subtitle))
+	}
+	title := strings.ToUpper(tpl.Title)
+	if card.Term != "" {
+		title += " - " + card.Term
+	}
+	page.Text(margin, 21.5*pdf.MM, pdf.HelveticaBold, 10, pdf.Truncate(pdf.HelveticaBold, 10, width, title))
+	return headerHeight + 8*pdf.MM
+}
+
+func drawDetails(page *pdf.Page, card Card, y float64) float64 {
+	class := card.ClassLabel
+	if card.RollNumber > 0 {
+		class += " / Roll " + strconv.Itoa(card.RollNumber)
+	}
+	left := [][2]string{
+		{"Student", card.StudentName},
+		{"Class", class},
+		{"Admission No", card.AdmissionNo},
+	}
+	right := [][2]string{
+		{"Date of birth", formatDate(card.DateOfBirth)},
+		{"Term", card.Term},
+		{"Issued", formatDate(card.GeneratedAt)},
+	}
+	half := (pdf.A4Width - 2*margin) / 2
+	labelWidth := 24 * pdf.MM
+	for i := range left {
+		for col, field := range [][2]string{left[i], right[i]} {
+			if field[1] == "" {
+				continue
+			}
+			x := margin + float64(col)*half
+			page.SetFillColor(110, 110, 110)
+			page.Text(x, y, pdf.Helvetica, 8, field[0])
+			page.SetFillColor(20, 20, 20)
+			page.Text(x+labelWidth, y, pdf.HelveticaBold, 9, pdf.Truncate(pdf.HelveticaBold, 9, half-labelWidth-2*pdf.MM, field[1]))
+		}
+		y += 5.5 * pdf.MM
+	}
+	return y + 4*pdf.MM
+}
+
+// tableColumns returns the x position of each column edge: subject, one
+// per exam, total and, when graded, grade.
+func tableColumns(exams int, graded bool) []float64 {
+	right := pdf.A4Width - margin
+	fixed := subjectWidth + totalWidth
+	if graded {
+		fixed += gradeWidth
+	}
+	examWidth := 0.0
+	if exams > 0 {
+		examWidth = (right - margin - fixed) / float64(exams)
+	}
+	edges := []float64{margin, margin + subjectWidth}
+	for i := 0; i < exams; i++ {
+		edges = append(edges, edges[len(edges)-1]+examWidth)
+	}
+	edges = append(edges, edges[len(edges)-1]+totalWidth)
+	if graded {
+		edges = append(edges, edges[len(edges)-1]+gradeWidth)
+	}
+	return edges
+}
+
+func drawTableHeader(page *pdf.Page, card Card, columns []float64, y float64, accent [3]uint8) float64 {
+	page.SetFillColor(accent[0], accent[1], accent[2])
+	page.Rect(margin, y, pdf.A4Width-2*margin, rowHeight+1*pdf.MM, true, false)
+	cells := []string{"Subject"}
+	for _, exam := range card.Exams {
+		cells = append(cells, exam.Title)
+	}
+	cells = append(cells, "Total")
+	if len(columns) > len(card.Exams)+3 {
+		cells = append(cells, "Grade")
+	}
+	page.SetFillColor(255, 255, 255)
+	drawCells(page, columns, y+0.5*pdf.MM, pdf.HelveticaBold, 8, cells)
+	return y + rowHeight + 1*pdf.MM
+}
+
+func drawRow(page *pdf.Page, columns []float64, y float64, font pdf.Font, cells []string) {
+	page.SetFillColor(20, 20, 20)
+	drawCells(page, columns, y, font, 8.5, cells)
+}
+
+// drawCells writes one line of cells: the first left-aligned, the rest
+// centred in their columns.
+func drawCells(page *pdf.Page, columns []float64, y float64, font pdf.Font, size float64, cells []string) {
+	baseline := y + 4.8*pdf.MM
+	for i, cell := range cells {
+		if i+1 >= len(columns) {
+			break
+		}
+		width := columns[i+1] - columns[i] - 2*pdf.MM
+		text := pdf.Truncate(font, size, width, cell)
+		x := columns[i] + 1*pdf.MM
+		if i == 0 {
+			x += 1 * pdf.MM
+		} else {
+			x += (width - pdf.TextWidth(font, size, text)) / 2
+		}
+		page.Text(x, baseline, font, size, text)
+	}
+}
+
+func drawSummary(page *pdf.Page, tpl models.ReportCardTemplate, card Card, graded bool, y float64) float64 {
+	items := [][2]string{
+		{"Total", formatTotal(card.Total)},
+		{"Percentage", formatPercent(card.Total.Percent(), card.Total.Max > 0)},
+	}
+	if graded && card.Grade != "" {
+		items = append(items, [2]string{"Grade", card.Grade})
+	}
+	if !tpl.HideAttendance && card.Attendance != nil {
+		attendance := strconv.Itoa(card.Attendance.Present) + " / " + strconv.Itoa(card.Attendance.Working) + " days"
+		if card.Attendance.Working > 0 {
+			attendance += " (" + formatPercent(float64(card.Attendance.Present)/float64(card.Attendance.Working)*100, true) + ")"
+		}
+		items = append(items, [2]string{"Attendance", attendance})
+	}
+	x := margin
+	for _, item := range items {
+		page.SetFillColor(110, 110, 110)
+		page.Text(x, y, pdf.Helvetica, 8, item[0])
+		page.SetFillColor(20, 20, 20)
+		page.Text(x, y+5*pdf.MM, pdf.HelveticaBold, 11, item[1])
+		x += max(32*pdf.MM, pdf.TextWidth(pdf.HelveticaBold, 11, item[1])+8*pdf.MM)
+	}
+	return y + 14*pdf.MM
+}
+
+func remarksHeight(tpl models.ReportCardTemplate, card Card) float64 {
+	if tpl.HideRemarks || card.Remarks == "" {
+		return 0
+	}
+	lines := pdf.Wrap(pdf.Helvetica, 9, pdf.A4Width-2*margin-8*pdf.MM, card.Remarks)
+	return 10*pdf.MM + float64(len(lines))*4.5*pdf.MM
+}
+
+func drawRemarks(page *pdf.Page, tpl models.ReportCardTemplate, card Card, y float64) {
+	height := remarksHeight(tpl, card)
+	if height == 0 {
+		return
+	}
+	width := pdf.A4Width - 2*margin
+	page.SetLineWidth(0.5)
+	page.SetStrokeColor(200, 200, 200)
+	page.Rect(margin, y, width, height, false, true)
+	page.SetFillColor(110, 110, 110)
+	page.Text(margin+4*pdf.MM, y+5.5*pdf.MM, pdf.HelveticaBold, 8, "Teacher's remarks")
+	page.SetFillColor(20, 20, 20)
+	lineY := y + 10.5*pdf.MM
+	for _, line := range pdf.Wrap(pdf.Helvetica, 9, width-8*pdf.MM, card.Remarks) {
+		page.Text(margin+4*pdf.MM, lineY, pdf.Helvetica, 9, line)
+		lineY += 4.5 * pdf.MM
+	}
+}
+
+func drawFooter(page *pdf.Page, tpl models.ReportCardTemplate) {
+	lineY := pdf.A4Height - margin - 12*pdf.MM
+	if n := len(tpl.Signatures); n > 0 {
+		slot := (pdf.A4Width - 2*margin) / float64(n)
+		page.SetLineWidth(0.5)
+		page.SetStrokeColor(120, 120, 120)
+		page.SetFillColor(90, 90, 90)
+		for i, label := range tpl.Signatures {
+			x := margin + float64(i)*slot
+			page.Line(x+4*pdf.MM, lineY, x+slot-4*pdf.MM, lineY)
+			text := pdf.Truncate(pdf.Helvetica, 8, slot-8*pdf.MM, label)
+			page.Text(x+(slot-pdf.TextWidth(pdf.Helvetica, 8, text))/2, lineY+4*pdf.MM, pdf.Helvetica, 8, text)
+		}
+	}
+	if tpl.Footer != "" {
+		page.SetFillColor(130, 130, 130)
+		text := pdf.Truncate(pdf.Helvetica, 7, pdf.A4Width-2*margin, tpl.Footer)
+		page.Text((pdf.A4Width-pdf.TextWidth(pdf.Helvetica, 7, text))/2, pdf.A4Height-margin, pdf.Helvetica, 7, text)
+	}
+}
+
+// codeLegend explains the status codes the card shows, or returns "" when
+// it shows none.
+func codeLegend(card Card) string {
+	used := map[string]bool{}
+	for _, row := range card.Subjects {
+		for _, marks := range row.Marks {
+			if marks != nil && marks.Code != "" {
+				used[marks.Code] = true
+			}
+		}
+	}
+	var parts []string
+	for _, code := range []struct{ code, meaning string }{
+		{"AB", "absent"},
+		{"ML", "medical leave"},
+		{"EX", "exempt"},
+	} {
+		if used[code.code] {
+			parts = append(parts, code.code+": "+code.meaning)
+		}
+	}
+	return strings.Join(parts, "   ")
+}
+
+func formatMarksPtr(marks *Marks) string {
+	if marks == nil {
+		return "-"
+	}
+	if marks.Code != "" {
+		return marks.Code
+	}
+	return formatTotal(*marks)
+}
+
+func formatTotal(marks Marks) string {
+	if marks.Max <= 0 {
+		return "-"
+	}
+	return formatNumber(marks.Score) + "/" + formatNumber(marks.Max)
+}
+
+func formatNumber(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
+
+func formatPercent(value float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + "%"
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("02 Jan 2006")
+}
+
+// parseColor reads a #rrggbb colour.
+func parseColor(value string) (uint8, uint8, uint8, bool) {
+	if len(value) != 7 || value[0] != '#' {
+		return 0, 0, 0, false
+	}
+	n, err := strconv.ParseUint(value[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return uint8(n >> 16), uint8(n >> 8), uint8(n), true
+}
@@ -0,0 +1,133 @@
+package reportcard
+
+import (
+	"bytes"
+	"compress/zlib"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/models"
+	"jnv/backend/internal/pdf"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestRenderGolden(t *testing.T) {
+	generatedAt := time.Date(2026, 3, 31, 10, 0, 0, 0, time.UTC)
+	school := models.School{Name: "PM SHRI Jawahar Navodaya Vidyalaya, Test District"}
+	student := models.Student{
+		ID:          "s1",
+		FullName:    "Asha Kumari",
+		ClassLabel:  "8A",
+		RollNumber:  12,
+		AdmissionNo: "JNV/2021/0042",
+		DateOfBirth: time.Date(2013, 7, 14, 0, 0, 0, 0, time.UTC),
+	}
+	exams := []models.Exam{
+		{ID: "e2", Title: "Half Yearly", Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC)},
+		{ID: "e1", Title: "Periodic Test 1", Date: time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)},
+	}
+	scores := []models.Score{
+		{ExamID: "e1", StudentID: "s1", Subject: "English", Score: 18, MaxScore: 20},
+		{ExamID: "e2", StudentID: "s1", Subject: "English", Score: 71, MaxScore: 80},
+		{ExamID: "e1", StudentID: "s1", Subject: "Mathematics", Score: 15.5, MaxScore: 20},
+		{ExamID: "e2", StudentID: "s1", Subject: "Mathematics", Score: 64, MaxScore: 80},
+		{ExamID: "e1", StudentID: "s1", Subject: "Science", Score: 12, MaxScore: 20},
+		{ExamID: "e2", StudentID: "s1", Subject: "Science", Score: 58, MaxScore: 80},
+		{ExamID: "e2", StudentID: "s1", Subject: "Hindi", Score: 66, MaxScore: 80},
+	}
+	missed := []models.Score{
+		{ExamID: "e1", StudentID: "s1", Subject: "English", Score: 18, MaxScore: 20},
+		{ExamID: "e2", StudentID: "s1", Subject: "English", Status: models.ScoreAbsent, MaxScore: 80},
+		{ExamID: "e1", StudentID: "s1", Subject: "Mathematics", Status: models.ScoreMedicalLeave, MaxScore: 20},
+		{ExamID: "e2", StudentID: "s1", Subject: "Mathematics", Score: 64, MaxScore: 80},
+		{ExamID: "e2", StudentID: "s1", Subject: "Sanskrit", Status: models.ScoreExempt, MaxScore: 80},
+	}
+	present, working := 172, 180
+	remarks := &models.ReportCardRemarks{
+		Remarks:     "Consistent effort. Should read more outside class.",
+		DaysPresent: &present,
+		WorkingDays: &working,
+	}
+	custom := models.ReportCardTemplate{
+		Title:          "Term Report",
+		Subtitle:       "Academic Session 2025-26",
+		AccentColor:    "#7a1f2b",
+		HideAttendance: true,
+		Signatures:     []string{"Class Teacher", "Principal"},
+		Footer:         "This report is computer generated and needs no seal.",
+	}
+
+	tests := []struct {
+		name   string
+		tpl    models.ReportCardTemplate
+		scores []models.Score
+		bands  []models.GradeBand
+	}{
+		{"default_template", DefaultTemplate(), scores, grading.CBSE8},
+		{"custom_template", custom, scores, grading.CBSE8},
+		{"missed_papers", DefaultTemplate(), missed, grading.CBSE8},
+		{"no_grading_scheme", DefaultTemplate(), scores, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := Build(school, student, "Term 1", exams, tt.scores, tt.bands, remarks, generatedAt)
+			doc := pdf.New()
+			Render(doc, tt.tpl, card)
+			data, err := doc.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := inflateStreams(t, data)
+
+			path := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from the rendered card; run go test -update to see the change", path)
+			}
+		})
+	}
+}
+
+// inflateStreams returns the PDF with its content streams decompressed, so
+// the golden files show the drawing operators and diff readably.
+func inflateStreams(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	for {
+		start := bytes.Index(data, []byte("\nstream\n"))
+		if start < 0 {
+			out.Write(data)
+			return out.Bytes()
+		}
+		start += len("\nstream\n")
+		end := bytes.Index(data[start:], []byte("\nendstream"))
+		if end < 0 {
+			t.Fatal("unterminated stream")
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data[start : start+end]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(data[:start])
+		out.Write(content)
+		data = data[start+end:]
+	}
+}
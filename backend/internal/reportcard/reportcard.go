@@ -0,0 +1,185 @@
+// Package reportcard assembles a student's marks for a term into a report
+// card and lays it out as a PDF page.
+package reportcard
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/models"
+)
+
+// DefaultTemplate is used by schools that have not saved their own.
+func DefaultTemplate() models.ReportCardTemplate {
+	return models.ReportCardTemplate{
+		Title:       "Progress Report",
+		AccentColor: "#1b365d",
+		Signatures:  []string{"Class Teacher", "Principal", "Parent"},
+	}
+}
+
+// NormalizeTemplate trims a template and checks its limits, filling the
+// title and colour from the default when they are empty.
+func NormalizeTemplate(tpl models.ReportCardTemplate) (models.ReportCardTemplate, error) {
+	def := DefaultTemplate()
+	tpl.Title = strings.TrimSpace(tpl.Title)
+	tpl.Subtitle = strings.TrimSpace(tpl.Subtitle)
+	tpl.AccentColor = strings.TrimSpace(tpl.AccentColor)
+	tpl.Footer = strings.TrimSpace(tpl.Footer)
+	if tpl.Title == "" {
+		tpl.Title = def.Title
+	}
+	if tpl.AccentColor == "" {
+		tpl.AccentColor = def.AccentColor
+	}
+	if _, _, _, ok := parseColor(tpl.AccentColor); !ok {
+		return tpl, errors.New("accent_color must be #rrggbb")
+	}
+	if len(tpl.Title) > 80 || len(tpl.Subtitle) > 120 || len(tpl.Footer) > 200 {
+		return tpl, errors.New("title, subtitle or footer is too long")
+	}
+	if len(tpl.Signatures) > 4 {
+		return tpl, errors.New("at most 4 signatures are allowed")
+	}
+	signatures := []string{}
+	for _, label := range tpl.Signatures {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		if len(label) > 40 {
+			return tpl, errors.New("signature labels must be at most 40 characters")
+		}
+		signatures = append(signatures, label)
+	}
+	tpl.Signatures = signatures
+	return tpl, nil
+}
+
+// Card is everything printed on one report card.
+type Card struct {
+	SchoolName  string
+	StudentName string
+	ClassLabel  string
+	RollNumber  int
+	AdmissionNo string
+	DateOfBirth time.Time
+	Term        string
+	Exams       []Exam
+	Subjects    []SubjectRow
+	Totals      []Marks // per exam, in the order of Exams
+	Total       Marks
+	Grade       string
+	Graded      bool // a grading scheme applies, so grade columns are shown
+	Attendance  *Attendance
+	Remarks     string
+	GeneratedAt time.Time
+}
+
+type Exam struct {
+	Title string
+	Date  time.Time
+}
+
+// Marks is a score out of a maximum. Max is zero when there are none. Code
+// is set instead, as AB, ML or EX, when the student did not sit the paper.
+type Marks struct {
+	Score float32
+	Max   float32
+	Code  string
+}
+
+func (m Marks) Percent() float64 {
+	if m.Max <= 0 {
+		return 0
+	}
+	return float64(m.Score) / float64(m.Max) * 100
+}
+
+type SubjectRow struct {
+	Name  string
+	Marks []*Marks // per exam; nil where the subject was not examined
+	Total Marks
+	Grade string
+}
+
+type Attendance struct {
+	Present int
+	Working int
+}
+
+// Build lays the student's scores out by subject and exam. Exams are shown
+// in date order and subjects by name; totals and grades come from the
+// marks, using bands when the class has a grading scheme. An absent paper
+// adds zero out of its maximum to the totals; medical leave and exemptions
+// add nothing.
+func Build(school models.School, student models.Student, term string, exams []models.Exam, scores []models.Score,
+	bands []models.GradeBand, remarks *models.ReportCardRemarks, now time.Time) Card {
+	card := Card{
+		SchoolName:  school.Name,
+		StudentName: student.FullName,
+		ClassLabel:  student.ClassLabel,
+		RollNumber:  student.RollNumber,
+		AdmissionNo: student.AdmissionNo,
+		DateOfBirth: student.DateOfBirth,
+		Term:        term,
+		Graded:      len(bands) > 0,
+		GeneratedAt: now,
+	}
+
+	ordered := append([]models.Exam(nil), exams...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Date.Before(ordered[j].Date) })
+	column := make(map[string]int, len(ordered))
+	for i, exam := range ordered {
+		column[exam.ID] = i
+		card.Exams = append(card.Exams, Exam{Title: exam.Title, Date: exam.Date})
+	}
+	card.Totals = make([]Marks, len(ordered))
+
+	rows := map[string]*SubjectRow{}
+	for _, score := range scores {
+		col, ok := column[score.ExamID]
+		if !ok || score.StudentID != student.ID {
+			continue
+		}
+		key := strings.ToLower(score.Subject)
+		row := rows[key]
+		if row == nil {
+			row = &SubjectRow{Name: score.Subject, Marks: make([]*Marks, len(ordered))}
+			rows[key] = row
+		}
+		if !grading.HasMarks(score.Status) {
+			row.Marks[col] = &Marks{Code: grading.StatusCode(score.Status)}
+		} else {
+			row.Marks[col] = &Marks{Score: score.Score, Max: score.MaxScore}
+		}
+		if !grading.CountsInTotal(score.Status) {
+			continue
+		}
+		row.Total.Score += score.Score
+		row.Total.Max += score.MaxScore
+		card.Totals[col].Score += score.Score
+		card.Totals[col].Max += score.MaxScore
+		card.Total.Score += score.Score
+		card.Total.Max += score.MaxScore
+	}
+	for _, row := range rows {
+		row.Grade = grading.Grade(bands, row.Total.Score, row.Total.Max)
+		card.Subjects = append(card.Subjects, *row)
+	}
+	sort.Slice(card.Subjects, func(i, j int) bool {
+		return strings.ToLower(card.Subjects[i].Name) < strings.ToLower(card.Subjects[j].Name)
+	})
+	card.Grade = grading.Grade(bands, card.Total.Score, card.Total.Max)
+
+	if remarks != nil {
+		card.Remarks = strings.TrimSpace(remarks.Remarks)
+		if remarks.DaysPresent != nil && remarks.WorkingDays != nil {
+			card.Attendance = &Attendance{Present: *remarks.DaysPresent, Working: *remarks.WorkingDays}
+		}
+	}
+	return card
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound         = errors.New("storage: object not found")
+	ErrTooLarge         = errors.New("storage: object exceeds size limit")
+	ErrContentType      = errors.New("storage: content type not allowed")
+	ErrInvalidKey       = errors.New("storage: invalid key")
+	ErrInvalidSignedURL = errors.New("storage: invalid or expired signed url")
+)
+
+const (
+	ContentTypeCSV  = "text/csv"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypePDF  = "application/pdf"
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+)
+
+type Object struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Bucket is a flat key/value blob store. Keys are slash-separated paths
+// without a leading slash.
+type Bucket interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) (*Object, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// SignedURLVerifier is implemented by backends whose signed URLs are served
+// by this API rather than by the storage service itself.
+type SignedURLVerifier interface {
+	VerifySignedURL(key string, query url.Values) error
+}
+
+// Policy restricts what may be stored for a given kind of upload.
+type Policy struct {
+	MaxBytes     int64
+	AllowedTypes []string
+}
+
+func (p Policy) Check(contentType string, size int64) error {
+	if p.MaxBytes > 0 && size > p.MaxBytes {
+		return ErrTooLarge
+	}
+	if len(p.AllowedTypes) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedTypes {
+		if allowed == contentType {
+			return nil
+		}
+	}
+	return ErrContentType
+}
+
+// DetectContentType sniffs the first bytes of a file and reconciles the
+// result with its extension, since both CSV and XLSX sniff as generic types.
+func DetectContentType(filename string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	base := strings.TrimSpace(strings.SplitN(sniffed, ";", 2)[0])
+	ext := strings.ToLower(path.Ext(filename))
+	switch {
+	case base == "application/zip" && ext == ".xlsx":
+		return ContentTypeXLSX
+	case base == "text/plain" && ext == ".csv":
+		return ContentTypeCSV
+	case base == "application/octet-stream" && ext != "":
+		if byExt := mime.TypeByExtension(ext); byExt != "" {
+			return strings.SplitN(byExt, ";", 2)[0]
+		}
+	}
+	return base
+}
+
+// CleanKey validates a caller-supplied key and returns it in canonical form.
+func CleanKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+// UploadKey builds the archive key for an uploaded file.
+func UploadKey(schoolID, kind, id, filename string, at time.Time) string {
+	ext := strings.ToLower(path.Ext(filename))
+	return fmt.Sprintf("uploads/%s/%s/%04d/%02d/%s%s", schoolID, kind, at.Year(), int(at.Month()), id, ext)
+}
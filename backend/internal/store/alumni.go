@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"jnv/backend/internal/models"
+)
+
+// GraduateStudents moves the active students of a class to alumni. When
+// studentIDs is empty the whole class graduates. It returns the number of
+// students archived. Their unredeemed parent link codes are deleted.
+func (s *Store) GraduateStudents(ctx context.Context, schoolID, classLabel string, year int, studentIDs []string) (int, error) {
+	var archived int
+	err := s.db.QueryRowContext(ctx, `
+		WITH archived AS (
+			UPDATE students
+			SET status = 'alumni', graduation_year = $3, archived_at = now()
+			WHERE school_id = $1 AND class_label = $2 AND status = 'active'
+				AND (cardinality($4::text[]) = 0 OR id::text = ANY($4))
+			RETURNING id
+		), dropped AS (
+			DELETE FROM parent_link_codes c
+			USING archived a
+			WHERE c.student_id = a.id AND c.redeemed_at IS NULL
+		)
+		SELECT count(*) FROM archived
+	`, schoolID, classLabel, year, nonNil(studentIDs)).Scan(&archived)
+	return archived, err
+}
+
+// ArchivedStudentIDs returns those of the given students that are no longer
+// active. Their academic records are read-only.
+func (s *Store) ArchivedStudentIDs(ctx context.Context, studentIDs []string) ([]string, error) {
+	if len(studentIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id::text
+		FROM students
+		WHERE id::text = ANY($1) AND status <> 'active'
+	`, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListAlumni returns a school's alumni, newest batch first. Contact details
+// are included only for alumni who opted in to sharing them.
+func (s *Store) ListAlumni(ctx context.Context, schoolID string, graduationYear int, search string, limit int) ([]models.AlumniEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT st.id, st.full_name, st.class_label, st.roll_number, st.house, st.admission_year,
+			coalesce(st.graduation_year, 0), coalesce(ac.share_contact, false),
+			coalesce(ac.phone, ''), coalesce(ac.email, ''), coalesce(ac.city, ''), coalesce(ac.occupation, ''),
+			ac.updated_at
+		FROM students st
+		LEFT JOIN alumni_contacts ac ON ac.student_id = st.id
+		WHERE st.school_id = $1 AND st.status = 'alumni'
+			AND ($2 = 0 OR st.graduation_year = $2)
+			AND ($3 = '' OR st.full_name ILIKE '%' || $3 || '%')
+		ORDER BY st.graduation_year DESC, st.full_name ASC
+		LIMIT $4
+	`, schoolID, graduationYear, search, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.AlumniEntry{}
+	for rows.Next() {
+		var entry models.AlumniEntry
+		var contact models.AlumniContact
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&entry.StudentID, &entry.FullName, &entry.ClassLabel, &entry.RollNumber, &entry.House,
+			&entry.AdmissionYear, &entry.GraduationYear, &contact.ShareContact, &contact.Phone, &contact.Email,
+			&contact.City, &contact.Occupation, &updatedAt); err != nil {
+			return nil, err
+		}
+		if contact.ShareContact {
+			contact.StudentID = entry.StudentID
+			contact.UpdatedAt = updatedAt.Time
+			entry.Contact = &contact
+		}
+		items = append(items, entry)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) GetAlumniContact(ctx context.Context, studentID string) (*models.AlumniContact, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT student_id, phone, email, city, occupation, share_contact, updated_at
+		FROM alumni_contacts
+		WHERE student_id = $1
+	`, studentID)
+	var contact models.AlumniContact
+	if err := row.Scan(&contact.StudentID, &contact.Phone, &contact.Email, &contact.City, &contact.Occupation,
+		&contact.ShareContact, &contact.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func (s *Store) UpsertAlumniContact(ctx context.Context, contact models.AlumniContact, updatedBy string) (*models.AlumniContact, error) {
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO alumni_contacts (student_id, phone, email, city, occupation, share_contact, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+		ON CONFLICT (student_id) DO UPDATE
+		SET phone = EXCLUDED.phone,
+			email = EXCLUDED.email,
+			city = EXCLUDED.city,
+			occupation = EXCLUDED.occupation,
+			share_contact = EXCLUDED.share_contact,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = now()
+		RETURNING updated_at
+	`, contact.StudentID, contact.Phone, contact.Email, contact.City, contact.Occupation, contact.ShareContact, updatedBy)
+	if err := row.Scan(&contact.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// ListScoreRecordsByStudent returns every score of a student with the exam
+// it belongs to, oldest exam first, for transcripts and exports.
+func (s *Store) ListScoreRecordsByStudent(ctx context.Context, studentID string) ([]models.ScoreRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sc.id, sc.exam_id, sc.student_id, sc.subject, sc.score, sc.max_score, sc.grade, sc.status, sc.created_at,
+			e.title, e.term, e.class, e.exam_date
+		FROM scores sc
+		JOIN exams e ON e.id = sc.exam_id
+		WHERE sc.student_id = $1
+		ORDER BY e.exam_date ASC, e.title ASC, sc.subject ASC
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ScoreRecord{}
+	for rows.Next() {
+		var item models.ScoreRecord
+		if err := rows.Scan(&item.ID, &item.ExamID, &item.StudentID, &item.Subject, &item.Score, &item.MaxScore,
+			&item.Grade, &item.Status, &item.CreatedAt, &item.ExamTitle, &item.ExamTerm, &item.ExamClass, &item.ExamDate); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
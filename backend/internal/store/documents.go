@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+const studentDocumentColumns = `
+	id, school_id, student_id, doc_type, status, storage_key, file_name, content_type, size_bytes, sha256,
+	uploaded_by, coalesce(verified_by::text, ''), verified_at, rejection_reason, expires_on, created_at, updated_at
+`
+
+func scanStudentDocument(row interface{ Scan(...any) error }, doc *models.StudentDocument) error {
+	return row.Scan(&doc.ID, &doc.SchoolID, &doc.StudentID, &doc.DocType, &doc.Status, &doc.StorageKey, &doc.FileName,
+		&doc.ContentType, &doc.SizeBytes, &doc.SHA256, &doc.UploadedBy, &doc.VerifiedBy, &doc.VerifiedAt,
+		&doc.RejectionReason, &doc.ExpiresOn, &doc.CreatedAt, &doc.UpdatedAt)
+}
+
+func (s *Store) CreateStudentDocument(ctx context.Context, doc models.StudentDocument) (*models.StudentDocument, error) {
+	if doc.ID == "" {
+		doc.ID = uuid.NewString()
+	}
+	now := time.Now()
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = now
+	}
+	doc.UpdatedAt = now
+	if doc.Status == "" {
+		doc.Status = "submitted"
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO student_documents (
+			id, school_id, student_id, doc_type, status, storage_key, file_name, content_type,
+			size_bytes, sha256, uploaded_by, expires_on, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, doc.ID, doc.SchoolID, doc.StudentID, doc.DocType, doc.Status, doc.StorageKey, doc.FileName, doc.ContentType,
+		doc.SizeBytes, doc.SHA256, doc.UploadedBy, doc.ExpiresOn, doc.CreatedAt, doc.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (s *Store) GetStudentDocument(ctx context.Context, documentID string) (*models.StudentDocument, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+studentDocumentColumns+` FROM student_documents WHERE id = $1`, documentID)
+	var doc models.StudentDocument
+	if err := scanStudentDocument(row, &doc); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (s *Store) ListStudentDocuments(ctx context.Context, studentID string) ([]models.StudentDocument, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+studentDocumentColumns+`
+		FROM student_documents
+		WHERE student_id = $1
+		ORDER BY doc_type ASC, created_at DESC
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.StudentDocument{}
+	for rows.Next() {
+		var doc models.StudentDocument
+		if err := scanStudentDocument(rows, &doc); err != nil {
+			return nil, err
+		}
+		items = append(items, doc)
+	}
+	return items, rows.Err()
+}
+
+// LatestStudentDocument returns the newest non-rejected document of a type,
+// preferring verified ones.
+func (s *Store) LatestStudentDocument(ctx context.Context, studentID, docType string) (*models.StudentDocument, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+studentDocumentColumns+`
+		FROM student_documents
+		WHERE student_id = $1 AND doc_type = $2 AND status <> 'rejected'
+		ORDER BY (status = 'verified') DESC, created_at DESC
+		LIMIT 1
+	`, studentID, docType)
+	var doc models.StudentDocument
+	if err := scanStudentDocument(row, &doc); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// LatestStudentDocuments is LatestStudentDocument for many students at once,
+// keyed by student id. Students without such a document are left out.
+func (s *Store) LatestStudentDocuments(ctx context.Context, studentIDs []string, docType string) (map[string]models.StudentDocument, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (student_id) `+studentDocumentColumns+`
+		FROM student_documents
+		WHERE student_id::text = ANY($1) AND doc_type = $2 AND status <> 'rejected'
+		ORDER BY student_id, (status = 'verified') DESC, created_at DESC
+	`, nonNil(studentIDs), docType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := map[string]models.StudentDocument{}
+	for rows.Next() {
+		var doc models.StudentDocument
+		if err := scanStudentDocument(rows, &doc); err != nil {
+			return nil, err
+		}
+		docs[doc.StudentID] = doc
+	}
+	return docs, rows.Err()
+}
+
+func (s *Store) SetStudentDocumentStatus(ctx context.Context, documentID, studentID, status, verifierID, reason string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE student_documents
+		SET status = $3, verified_by = $4, verified_at = now(), rejection_reason = $5, updated_at = now()
+		WHERE id = $1 AND student_id = $2
+	`, documentID, studentID, status, verifierID, reason)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *Store) ListDocumentRequirements(ctx context.Context, schoolID, classLabel string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT doc_type
+		FROM document_requirements
+		WHERE school_id = $1 AND class = $2
+		ORDER BY doc_type ASC
+	`, schoolID, classLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []string{}
+	for rows.Next() {
+		var docType string
+		if err := rows.Scan(&docType); err != nil {
+			return nil, err
+		}
+		items = append(items, docType)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) ReplaceDocumentRequirements(ctx context.Context, schoolID, classLabel string, docTypes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM document_requirements
+		WHERE school_id = $1 AND class = $2
+	`, schoolID, classLabel); err != nil {
+		return err
+	}
+	for _, docType := range docTypes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO document_requirements (id, school_id, class, doc_type, created_at)
+			VALUES ($1, $2, $3, $4, now())
+			ON CONFLICT (school_id, class, doc_type) DO NOTHING
+		`, uuid.NewString(), schoolID, classLabel, docType); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DocumentChecklist reports, for every student of a class, which of the
+// class's required documents are missing, awaiting verification or expired.
+func (s *Store) DocumentChecklist(ctx context.Context, schoolID, classLabel string, asOf time.Time) ([]models.DocumentChecklistItem, error) {
+	required, err := s.ListDocumentRequirements(ctx, schoolID, classLabel)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.ListStudentsBySchool(ctx, schoolID, classLabel, 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	type docState struct {
+		status    string
+		expiresOn *time.Time
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (d.student_id, d.doc_type) d.student_id, d.doc_type, d.status, d.expires_on
+		FROM student_documents d
+		JOIN students st ON st.id = d.student_id
+		WHERE st.school_id = $1 AND st.class_label = $2 AND d.status <> 'rejected'
+		ORDER BY d.student_id, d.doc_type, (d.status = 'verified') DESC, d.created_at DESC
+	`, schoolID, classLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := map[string]map[string]docState{}
+	for rows.Next() {
+		var studentID, docType string
+		var state docState
+		if err := rows.Scan(&studentID, &docType, &state.status, &state.expiresOn); err != nil {
+			return nil, err
+		}
+		if states[studentID] == nil {
+			states[studentID] = map[string]docState{}
+		}
+		states[studentID][docType] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.DocumentChecklistItem, 0, len(students))
+	for _, student := range students {
+		item := models.DocumentChecklistItem{
+			StudentID:   student.ID,
+			StudentName: student.FullName,
+			ClassLabel:  student.ClassLabel,
+			RollNumber:  student.RollNumber,
+			Missing:     []string{},
+			Pending:     []string{},
+			Expired:     []string{},
+		}
+		for _, docType := range required {
+			state, ok := states[student.ID][docType]
+			switch {
+			case !ok:
+				item.Missing = append(item.Missing, docType)
+			case state.expiresOn != nil && state.expiresOn.Before(asOf):
+				item.Expired = append(item.Expired, docType)
+			case state.status != "verified":
+				item.Pending = append(item.Pending, docType)
+			}
+		}
+		item.Complete = len(item.Missing) == 0 && len(item.Pending) == 0 && len(item.Expired) == 0
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"jnv/backend/internal/models"
+)
+
+const examColumns = `
+	e.id, e.school_id, e.class, e.title, e.term, e.exam_date, e.status,
+	(SELECT count(*) FROM scores sc WHERE sc.exam_id = e.id), e.published_at, e.locked_at, e.created_at
+`
+
+func scanExam(row interface{ Scan(...any) error }, exam *models.Exam) error {
+	return row.Scan(&exam.ID, &exam.SchoolID, &exam.Class, &exam.Title, &exam.Term, &exam.Date, &exam.Status,
+		&exam.ScoreCount, &exam.PublishedAt, &exam.LockedAt, &exam.CreatedAt)
+}
+
+// ExamFilter narrows ListExams. Empty fields match everything.
+type ExamFilter struct {
+	Class  string
+	Term   string
+	Status string
+}
+
+// ListExams returns a school's exams, most recent first.
+func (s *Store) ListExams(ctx context.Context, schoolID string, filter ExamFilter) ([]models.Exam, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+examColumns+`
+		FROM exams e
+		WHERE e.school_id = $1
+			AND ($2 = '' OR e.class = $2)
+			AND ($3 = '' OR e.term = $3)
+			AND ($4 = '' OR e.status = $4)
+		ORDER BY e.exam_date DESC, e.created_at DESC
+	`, schoolID, filter.Class, filter.Term, filter.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.Exam{}
+	for rows.Next() {
+		var exam models.Exam
+		if err := scanExam(rows, &exam); err != nil {
+			return nil, err
+		}
+		items = append(items, exam)
+	}
+	return items, rows.Err()
+}
+
+// UpdateExam saves the editable fields of an exam, and its subject list
+// when Subjects is not nil. Locked exams are left untouched and
+// sql.ErrNoRows is returned.
+func (s *Store) UpdateExam(ctx context.Context, exam models.Exam) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE exams
+		SET class = $3, title = $4, term = $5, exam_date = $6, updated_at = now()
+		WHERE id = $1 AND school_id = $2 AND status <> 'locked'
+	`, exam.ID, exam.SchoolID, exam.Class, exam.Title, exam.Term, exam.Date)
+	if err := expectAffected(res, err); err != nil {
+		return err
+	}
+	if exam.Subjects != nil {
+		if err := replaceExamSubjects(ctx, tx, exam.ID, exam.Subjects); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteExam removes an exam and its scores as long as its results have not
+// been published. It returns sql.ErrNoRows otherwise.
+func (s *Store) DeleteExam(ctx context.Context, examID, schoolID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM exams WHERE id = $1 AND school_id = $2 FOR UPDATE
+	`, examID, schoolID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != models.ExamDraft && status != models.ExamScoresEntered {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scores WHERE exam_id = $1`, examID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM exams WHERE id = $1`, examID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetExamStatus moves an exam to status if it is currently in one of from.
+// It returns sql.ErrNoRows when the exam is missing or in another state.
+func (s *Store) SetExamStatus(ctx context.Context, examID, schoolID string, from []string, status string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE exams
+		SET status = $3,
+			published_at = CASE
+				WHEN $3 = 'results_published' THEN now()
+				WHEN $3 = 'locked' THEN published_at
+			END,
+			locked_at = CASE WHEN $3 = 'locked' THEN now() ELSE NULL END,
+			updated_at = now()
+		WHERE id = $1 AND school_id = $2 AND status = ANY($4)
+	`, examID, schoolID, status, from)
+	return expectAffected(res, err)
+}
+
+// MarkExamScoresEntered moves a draft exam to scores_entered once it has
+// scores, and reports whether it did.
+func (s *Store) MarkExamScoresEntered(ctx context.Context, examID string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE exams
+		SET status = 'scores_entered', updated_at = now()
+		WHERE id = $1 AND status = 'draft'
+	`, examID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,426 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+// studentPhoneKey is the SQL form of the handlers' phoneKey: the last ten
+// digits of a phone number.
+const studentPhoneKey = `right(regexp_replace(st.parent_phone, '\D', '', 'g'), 10)`
+
+const familyMemberColumns = `
+	st.id, st.full_name, st.class_label, st.roll_number, st.house, st.parent_phone, st.status
+`
+
+func scanFamilyMember(row interface{ Scan(...any) error }, member *models.FamilyMember) error {
+	return row.Scan(&member.StudentID, &member.FullName, &member.ClassLabel, &member.RollNumber, &member.House,
+		&member.ParentPhone, &member.Status)
+}
+
+// ListFamilySuggestions groups a school's active students by parent phone.
+// Groups already fully inside one family, and phones whose suggestion was
+// dismissed, are left out.
+func (s *Store) ListFamilySuggestions(ctx context.Context, schoolID string) ([]models.FamilySuggestion, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH keyed AS (
+			SELECT st.*, `+studentPhoneKey+` AS phone_key
+			FROM students st
+			WHERE st.school_id = $1 AND st.status = 'active'
+		), shared AS (
+			SELECT phone_key
+			FROM keyed
+			WHERE length(phone_key) = 10
+			GROUP BY phone_key
+			HAVING count(*) > 1
+		)
+		SELECT st.phone_key, `+familyMemberColumns+`, coalesce(fm.family_id::text, '')
+		FROM keyed st
+		JOIN shared USING (phone_key)
+		LEFT JOIN family_members fm ON fm.student_id = st.id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM families f
+			WHERE f.school_id = $1 AND f.phone_key = st.phone_key AND f.status = 'dismissed'
+		)
+		ORDER BY st.phone_key, st.class_label, st.roll_number
+	`, schoolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.FamilySuggestion{}
+	var current *models.FamilySuggestion
+	var ungrouped bool
+	flush := func() {
+		if current != nil && ungrouped {
+			items = append(items, *current)
+		}
+	}
+	for rows.Next() {
+		var phoneKey, familyID string
+		var member models.FamilyMember
+		if err := rows.Scan(&phoneKey, &member.StudentID, &member.FullName, &member.ClassLabel, &member.RollNumber,
+			&member.House, &member.ParentPhone, &member.Status, &familyID); err != nil {
+			return nil, err
+		}
+		if current == nil || current.PhoneKey != phoneKey {
+			flush()
+			current = &models.FamilySuggestion{PhoneKey: phoneKey}
+			ungrouped = false
+		}
+		switch {
+		case familyID == "":
+			ungrouped = true
+		case current.FamilyID == "":
+			current.FamilyID = familyID
+		case current.FamilyID != familyID:
+			ungrouped = true
+		}
+		current.Students = append(current.Students, member)
+	}
+	flush()
+	return items, rows.Err()
+}
+
+// CreateFamily confirms a family of the given students. A dismissed
+// suggestion for the same phone is replaced.
+func (s *Store) CreateFamily(ctx context.Context, schoolID, phoneKey, createdBy string, studentIDs []string) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if phoneKey != "" {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM families
+			WHERE school_id = $1 AND phone_key = $2 AND status = 'dismissed'
+		`, schoolID, phoneKey); err != nil {
+			return "", err
+		}
+	}
+	familyID := uuid.NewString()
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO families (id, school_id, phone_key, status, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, 'confirmed', $4, $5, $5)
+	`, familyID, schoolID, phoneKey, createdBy, now); err != nil {
+		return "", err
+	}
+	if err := insertFamilyMembers(ctx, tx, familyID, createdBy, studentIDs); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return familyID, nil
+}
+
+func (s *Store) AddFamilyMembers(ctx context.Context, familyID, addedBy string, studentIDs []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertFamilyMembers(ctx, tx, familyID, addedBy, studentIDs); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE families SET updated_at = now() WHERE id = $1`, familyID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertFamilyMembers(ctx context.Context, tx *sql.Tx, familyID, addedBy string, studentIDs []string) error {
+	for _, studentID := range studentIDs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO family_members (family_id, student_id, added_by, added_at)
+			VALUES ($1, $2, $3, now())
+		`, familyID, studentID, addedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveFamilyMember takes a student out of a family. A family left with
+// fewer than two students is dissolved, and dissolved reports whether that
+// happened. It returns sql.ErrNoRows when the student is not a member.
+func (s *Store) RemoveFamilyMember(ctx context.Context, familyID, studentID string) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM family_members
+		WHERE family_id = $1 AND student_id = $2
+	`, familyID, studentID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, sql.ErrNoRows
+	}
+
+	var remaining int
+	if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM family_members WHERE family_id = $1`, familyID).Scan(&remaining); err != nil {
+		return false, err
+	}
+	dissolved := remaining < 2
+	if dissolved {
+		_, err = tx.ExecContext(ctx, `DELETE FROM families WHERE id = $1`, familyID)
+	} else {
+		_, err = tx.ExecContext(ctx, `UPDATE families SET updated_at = now() WHERE id = $1`, familyID)
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return dissolved, nil
+}
+
+// DismissFamilySuggestion stops the phone from being suggested again.
+func (s *Store) DismissFamilySuggestion(ctx context.Context, schoolID, phoneKey, userID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO families (id, school_id, phone_key, status, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, 'dismissed', $4, now(), now())
+		ON CONFLICT (school_id, phone_key) WHERE phone_key <> '' DO NOTHING
+	`, uuid.NewString(), schoolID, phoneKey, userID)
+	return err
+}
+
+// GetFamily returns a confirmed family with its members.
+func (s *Store) GetFamily(ctx context.Context, familyID string) (*models.Family, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, school_id, phone_key, coalesce(created_by::text, ''), created_at, updated_at
+		FROM families
+		WHERE id = $1 AND status = 'confirmed'
+	`, familyID)
+	var family models.Family
+	if err := row.Scan(&family.ID, &family.SchoolID, &family.PhoneKey, &family.CreatedBy,
+		&family.CreatedAt, &family.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+familyMemberColumns+`
+		FROM family_members fm
+		JOIN students st ON st.id = fm.student_id
+		WHERE fm.family_id = $1
+		ORDER BY st.class_label, st.roll_number
+	`, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	family.Members = []models.FamilyMember{}
+	for rows.Next() {
+		var member models.FamilyMember
+		if err := scanFamilyMember(rows, &member); err != nil {
+			return nil, err
+		}
+		family.Members = append(family.Members, member)
+	}
+	return &family, rows.Err()
+}
+
+// ListFamilies returns a school's confirmed families, largest first.
+func (s *Store) ListFamilies(ctx context.Context, schoolID string, limit int) ([]models.Family, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH page AS (
+			SELECT f.id, f.phone_key, coalesce(f.created_by::text, '') AS created_by, f.created_at, f.updated_at,
+				(SELECT count(*) FROM family_members c WHERE c.family_id = f.id) AS size
+			FROM families f
+			WHERE f.school_id = $1 AND f.status = 'confirmed'
+			ORDER BY size DESC, f.created_at DESC
+			LIMIT $2
+		)
+		SELECT p.id, p.phone_key, p.created_by, p.created_at, p.updated_at, `+familyMemberColumns+`
+		FROM page p
+		JOIN family_members fm ON fm.family_id = p.id
+		JOIN students st ON st.id = fm.student_id
+		ORDER BY p.size DESC, p.created_at DESC, p.id, st.class_label, st.roll_number
+	`, schoolID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.Family{}
+	for rows.Next() {
+		var family models.Family
+		var member models.FamilyMember
+		if err := rows.Scan(&family.ID, &family.PhoneKey, &family.CreatedBy, &family.CreatedAt, &family.UpdatedAt,
+			&member.StudentID, &member.FullName, &member.ClassLabel, &member.RollNumber, &member.House,
+			&member.ParentPhone, &member.Status); err != nil {
+			return nil, err
+		}
+		if n := len(items); n == 0 || items[n-1].ID != family.ID {
+			family.SchoolID = schoolID
+			items = append(items, family)
+		}
+		last := &items[len(items)-1]
+		last.Members = append(last.Members, member)
+	}
+	return items, rows.Err()
+}
+
+// FamilyIDsByStudent maps each of the given students that already belongs to
+// a family to that family's id.
+func (s *Store) FamilyIDsByStudent(ctx context.Context, studentIDs []string) (map[string]string, error) {
+	ids := map[string]string{}
+	if len(studentIDs) == 0 {
+		return ids, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT student_id::text, family_id::text
+		FROM family_members
+		WHERE student_id::text = ANY($1)
+	`, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var studentID, familyID string
+		if err := rows.Scan(&studentID, &familyID); err != nil {
+			return nil, err
+		}
+		ids[studentID] = familyID
+	}
+	return ids, rows.Err()
+}
+
+// ListFamilyParents returns the parent accounts with an approved link to any
+// member of the family, with the members each one is linked to.
+func (s *Store) ListFamilyParents(ctx context.Context, familyID string) ([]models.FamilyParent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id, u.full_name, coalesce(u.phone, ''), string_agg(DISTINCT pl.student_id::text, ',')
+		FROM family_members fm
+		JOIN parent_links pl ON pl.student_id = fm.student_id AND pl.status = 'approved'
+		JOIN users u ON u.id = pl.parent_id
+		WHERE fm.family_id = $1
+		GROUP BY u.id, u.full_name, u.phone
+		ORDER BY u.full_name
+	`, familyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.FamilyParent{}
+	for rows.Next() {
+		var item models.FamilyParent
+		var studentIDs string
+		if err := rows.Scan(&item.ParentID, &item.FullName, &item.Phone, &studentIDs); err != nil {
+			return nil, err
+		}
+		item.StudentIDs = strings.Split(studentIDs, ",")
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ListSiblingOffers returns the active siblings of the students a parent is
+// approved for, in confirmed families, that the parent has no link request
+// for yet. Rejected and revoked siblings are not offered again.
+func (s *Store) ListSiblingOffers(ctx context.Context, parentID string) ([]models.SiblingOffer, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (st.id) st.id, st.full_name, st.class_label, st.roll_number, fm.family_id, pl.student_id
+		FROM parent_links pl
+		JOIN family_members fm ON fm.student_id = pl.student_id
+		JOIN family_members sib ON sib.family_id = fm.family_id AND sib.student_id <> fm.student_id
+		JOIN students st ON st.id = sib.student_id AND st.status = 'active'
+		WHERE pl.parent_id = $1 AND pl.status = 'approved'
+			AND NOT EXISTS (
+				SELECT 1 FROM parent_links x
+				WHERE x.parent_id = $1 AND x.student_id = st.id
+			)
+		ORDER BY st.id, pl.created_at
+	`, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.SiblingOffer{}
+	for rows.Next() {
+		var item models.SiblingOffer
+		if err := rows.Scan(&item.StudentID, &item.FullName, &item.ClassLabel, &item.RollNumber, &item.FamilyID,
+			&item.LinkedStudent); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// CreateApprovedParentLinks links a parent to several students at once,
+// already approved. Callers must have established that the parent is
+// entitled to each link.
+func (s *Store) CreateApprovedParentLinks(ctx context.Context, parentID string, studentIDs []string) ([]models.ParentLink, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	links := make([]models.ParentLink, 0, len(studentIDs))
+	for _, studentID := range studentIDs {
+		link := models.ParentLink{
+			ID:        uuid.NewString(),
+			ParentID:  parentID,
+			StudentID: studentID,
+			Status:    "approved",
+			CreatedAt: time.Now(),
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO parent_links (id, parent_id, student_id, status, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, link.ID, link.ParentID, link.StudentID, link.Status, link.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// FindFamilyByPhone returns the id of the school's confirmed family for the
+// phone, or "" when there is none.
+func (s *Store) FindFamilyByPhone(ctx context.Context, schoolID, phoneKey string) (string, error) {
+	var familyID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id::text
+		FROM families
+		WHERE school_id = $1 AND phone_key = $2 AND status = 'confirmed'
+	`, schoolID, phoneKey).Scan(&familyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return familyID, err
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/grading"
+	"jnv/backend/internal/models"
+)
+
+const gradingSchemeColumns = `id, school_id, class, name, bands, coalesce(updated_by::text, ''), created_at, updated_at`
+
+func scanGradingScheme(row interface{ Scan(...any) error }, scheme *models.GradingScheme) error {
+	var bands []byte
+	if err := row.Scan(&scheme.ID, &scheme.SchoolID, &scheme.Class, &scheme.Name, &bands,
+		&scheme.UpdatedBy, &scheme.CreatedAt, &scheme.UpdatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(bands, &scheme.Bands)
+}
+
+func (s *Store) ListGradingSchemes(ctx context.Context, schoolID string) ([]models.GradingScheme, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+gradingSchemeColumns+`
+		FROM grading_schemes
+		WHERE school_id = $1
+		ORDER BY class ASC
+	`, schoolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.GradingScheme{}
+	for rows.Next() {
+		var scheme models.GradingScheme
+		if err := scanGradingScheme(rows, &scheme); err != nil {
+			return nil, err
+		}
+		items = append(items, scheme)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) GetGradingScheme(ctx context.Context, schemeID, schoolID string) (*models.GradingScheme, error) {
+	var scheme models.GradingScheme
+	err := scanGradingScheme(s.db.QueryRowContext(ctx, `
+		SELECT `+gradingSchemeColumns+`
+		FROM grading_schemes
+		WHERE id = $1 AND school_id = $2
+	`, schemeID, schoolID), &scheme)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scheme, nil
+}
+
+// GradingSchemeForClass returns the scheme that applies to a class: its own
+// if it has one, otherwise the school default. It returns nil when neither
+// exists.
+func (s *Store) GradingSchemeForClass(ctx context.Context, schoolID, class string) (*models.GradingScheme, error) {
+	var scheme models.GradingScheme
+	err := scanGradingScheme(s.db.QueryRowContext(ctx, `
+		SELECT `+gradingSchemeColumns+`
+		FROM grading_schemes
+		WHERE school_id = $1 AND class IN ($2, '')
+		ORDER BY class = $2 DESC
+		LIMIT 1
+	`, schoolID, class), &scheme)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scheme, nil
+}
+
+// SaveGradingScheme creates or replaces the scheme of a class, or the school
+// default when Class is empty.
+func (s *Store) SaveGradingScheme(ctx context.Context, scheme models.GradingScheme) (*models.GradingScheme, error) {
+	bands, err := json.Marshal(scheme.Bands)
+	if err != nil {
+		return nil, err
+	}
+	var saved models.GradingScheme
+	err = scanGradingScheme(s.db.QueryRowContext(ctx, `
+		INSERT INTO grading_schemes (id, school_id, class, name, bands, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6, now(), now())
+		ON CONFLICT (school_id, class) DO UPDATE
+		SET name = excluded.name,
+		    bands = excluded.bands,
+		    updated_by = excluded.updated_by,
+		    updated_at = now()
+		RETURNING `+gradingSchemeColumns+`
+	`, uuid.NewString(), scheme.SchoolID, scheme.Class, scheme.Name, string(bands), nullString(scheme.UpdatedBy)), &saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (s *Store) DeleteGradingScheme(ctx context.Context, schemeID, schoolID string) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM grading_schemes
+		WHERE id = $1 AND school_id = $2
+	`, schemeID, schoolID)
+	return expectAffected(res, err)
+}
+
+// RegradeScores recomputes the grades of every score the scheme for class
+// covers: that class, or with an empty class every class without a scheme of
+// its own. Locked exams and locked subjects keep their grades, and scores
+// without marks have none. Each changed grade gets a score revision; the number of changed
+// scores is returned.
+func (s *Store) RegradeScores(ctx context.Context, schoolID, class string, bands []models.GradeBand, write ScoreWrite) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT sc.id, sc.exam_id, sc.student_id, sc.subject, sc.score, sc.max_score, sc.grade, sc.status
+		FROM scores sc
+		JOIN exams e ON e.id = sc.exam_id
+		WHERE e.school_id = $1 AND e.status <> 'locked' AND sc.status = 'present'
+			AND NOT EXISTS (
+				SELECT 1 FROM subjects s
+				WHERE s.school_id = e.school_id AND s.class = e.class
+					AND lower(s.name) = lower(sc.subject) AND s.locked
+			)
+			AND CASE
+				WHEN $2 <> '' THEN e.class = $2
+				ELSE NOT EXISTS (
+					SELECT 1 FROM grading_schemes g
+					WHERE g.school_id = e.school_id AND g.class = e.class
+				)
+			END
+		ORDER BY sc.exam_id
+		FOR UPDATE OF sc
+	`, schoolID, class)
+	if err != nil {
+		return 0, err
+	}
+	type change struct{ previous, current []models.Score }
+	changes := map[string]*change{}
+	var examIDs []string
+	for rows.Next() {
+		var score models.Score
+		if err := rows.Scan(&score.ID, &score.ExamID, &score.StudentID, &score.Subject, &score.Score,
+			&score.MaxScore, &score.Grade, &score.Status); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		grade := grading.Grade(bands, score.Score, score.MaxScore)
+		if grade == "" || grade == score.Grade {
+			continue
+		}
+		c := changes[score.ExamID]
+		if c == nil {
+			c = &change{}
+			changes[score.ExamID] = c
+			examIDs = append(examIDs, score.ExamID)
+		}
+		c.previous = append(c.previous, score)
+		score.Grade = grade
+		c.current = append(c.current, score)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, examID := range examIDs {
+		c := changes[examID]
+		if err := updateScores(ctx, tx, c.current, write); err != nil {
+			return 0, err
+		}
+		if err := insertScoreRevisions(ctx, tx, examID, "updated", c.previous, c.current, write); err != nil {
+			return 0, err
+		}
+		changed += len(c.current)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,348 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+func (s *Store) CreateStudentWithGuardians(ctx context.Context, student models.Student, guardians []models.Guardian) (*models.Student, error) {
+	if student.ID == "" {
+		student.ID = uuid.NewString()
+	}
+	if student.CreatedAt.IsZero() {
+		student.CreatedAt = time.Now()
+	}
+	student.Status = models.StudentStatusActive
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO students (
+			id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year,
+			admission_no, apaar_id, pen, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, student.ID, student.SchoolID, student.FullName, student.ClassLabel, student.RollNumber,
+		student.DateOfBirth, student.House, student.ParentPhone, student.AdmissionYear,
+		nullString(student.AdmissionNo), nullString(student.APAARID), nullString(student.PEN), student.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, guardian := range guardians {
+		guardian.StudentID = student.ID
+		if err := insertGuardian(ctx, tx, &guardian); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+func (s *Store) ListGuardiansByStudent(ctx context.Context, studentID string) ([]models.Guardian, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, student_id, full_name, relationship, phone, email, is_primary, can_pick_up, created_at, updated_at
+		FROM guardians
+		WHERE student_id = $1
+		ORDER BY is_primary DESC, created_at ASC
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.Guardian{}
+	for rows.Next() {
+		var item models.Guardian
+		if err := rows.Scan(&item.ID, &item.StudentID, &item.FullName, &item.Relationship, &item.Phone, &item.Email,
+			&item.IsPrimary, &item.CanPickUp, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// PrimaryGuardianPhones returns the phone of each student's primary
+// guardian, keyed by student id, for students whose primary guardian has one.
+func (s *Store) PrimaryGuardianPhones(ctx context.Context, studentIDs []string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (student_id) student_id, phone
+		FROM guardians
+		WHERE student_id::text = ANY($1) AND is_primary AND phone <> ''
+		ORDER BY student_id, created_at ASC
+	`, nonNil(studentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	phones := map[string]string{}
+	for rows.Next() {
+		var studentID, phone string
+		if err := rows.Scan(&studentID, &phone); err != nil {
+			return nil, err
+		}
+		phones[studentID] = phone
+	}
+	return phones, rows.Err()
+}
+
+func (s *Store) GetGuardian(ctx context.Context, guardianID string) (*models.Guardian, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, student_id, full_name, relationship, phone, email, is_primary, can_pick_up, created_at, updated_at
+		FROM guardians
+		WHERE id = $1
+	`, guardianID)
+
+	var item models.Guardian
+	if err := row.Scan(&item.ID, &item.StudentID, &item.FullName, &item.Relationship, &item.Phone, &item.Email,
+		&item.IsPrimary, &item.CanPickUp, &item.CreatedAt, &item.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *Store) CreateGuardian(ctx context.Context, guardian models.Guardian) (*models.Guardian, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := insertGuardian(ctx, tx, &guardian); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &guardian, nil
+}
+
+func (s *Store) UpdateGuardian(ctx context.Context, guardian models.Guardian) (*models.Guardian, error) {
+	guardian.UpdatedAt = time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if guardian.IsPrimary {
+		if err := clearPrimaryGuardian(ctx, tx, guardian.StudentID, guardian.ID); err != nil {
+			return nil, err
+		}
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE guardians
+		SET full_name = $3, relationship = $4, phone = $5, email = $6, is_primary = $7, can_pick_up = $8, updated_at = $9
+		WHERE id = $1 AND student_id = $2
+	`, guardian.ID, guardian.StudentID, guardian.FullName, guardian.Relationship, guardian.Phone, guardian.Email,
+		guardian.IsPrimary, guardian.CanPickUp, guardian.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+	if err := syncStudentParentPhone(ctx, tx, guardian.StudentID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &guardian, nil
+}
+
+func (s *Store) DeleteGuardian(ctx context.Context, guardianID, studentID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM guardians
+		WHERE id = $1 AND student_id = $2
+	`, guardianID, studentID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	// Promote the longest-standing remaining guardian if the primary was removed.
+	_, err = tx.ExecContext(ctx, `
+		UPDATE guardians
+		SET is_primary = true, updated_at = now()
+		WHERE id = (
+			SELECT id FROM guardians WHERE student_id = $1 ORDER BY created_at ASC LIMIT 1
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM guardians WHERE student_id = $1 AND is_primary
+		)
+	`, studentID)
+	if err != nil {
+		return err
+	}
+	if err := syncStudentParentPhone(ctx, tx, studentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindGuardianByPhone returns the guardian of the student whose phone matches
+// the given principal on its last ten digits, preferring the primary contact.
+func (s *Store) FindGuardianByPhone(ctx context.Context, studentID, phone string) (*models.Guardian, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, student_id, full_name, relationship, phone, email, is_primary, can_pick_up, created_at, updated_at
+		FROM guardians
+		WHERE student_id = $1
+		  AND phone <> ''
+		  AND right(regexp_replace(phone, '\D', '', 'g'), 10) = right(regexp_replace($2, '\D', '', 'g'), 10)
+		ORDER BY is_primary DESC, created_at ASC
+		LIMIT 1
+	`, studentID, phone)
+
+	var item models.Guardian
+	if err := row.Scan(&item.ID, &item.StudentID, &item.FullName, &item.Relationship, &item.Phone, &item.Email,
+		&item.IsPrimary, &item.CanPickUp, &item.CreatedAt, &item.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListDeviceTokensByStudentGuardians returns the device tokens of the
+// student's approved parents, and of parents who have asked to link to the
+// student and sign in with one of the student's guardian phone numbers.
+// Accounts signed in by email or uid have no verified phone and only match
+// through an approved link.
+func (s *Store) ListDeviceTokensByStudentGuardians(ctx context.Context, studentID string) ([]string, error) {
+	return s.listGuardianDeviceTokens(ctx, `SELECT id FROM students WHERE id = $1`, studentID)
+}
+
+// ListDeviceTokensByClassGuardians is ListDeviceTokensByStudentGuardians for
+// every active student of a class.
+func (s *Store) ListDeviceTokensByClassGuardians(ctx context.Context, schoolID, classLabel string) ([]string, error) {
+	return s.listGuardianDeviceTokens(ctx, `
+		SELECT id FROM students WHERE school_id = $1 AND class_label = $2 AND status = 'active'
+	`, schoolID, classLabel)
+}
+
+// listGuardianDeviceTokens returns the tokens of the guardians of the
+// students the targets query selects.
+func (s *Store) listGuardianDeviceTokens(ctx context.Context, targets string, args ...any) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH targets AS (`+targets+`)
+		SELECT DISTINCT dt.token
+		FROM device_tokens dt
+		JOIN users u ON u.id = dt.user_id
+		WHERE u.role = 'parent' AND (
+			EXISTS (
+				SELECT 1
+				FROM parent_links pl
+				JOIN targets t ON t.id = pl.student_id
+				WHERE pl.parent_id = u.id AND pl.status = 'approved'
+			)
+			OR (u.phone NOT LIKE 'email:%' AND u.phone NOT LIKE 'uid:%'
+				AND EXISTS (
+					SELECT 1
+					FROM guardians g
+					JOIN targets t ON t.id = g.student_id
+					JOIN parent_links rl ON rl.student_id = g.student_id
+						AND rl.parent_id = u.id AND rl.status = 'pending'
+					WHERE length(regexp_replace(g.phone, '\D', '', 'g')) >= 10
+						AND right(regexp_replace(g.phone, '\D', '', 'g'), 10)
+							= right(regexp_replace(u.phone, '\D', '', 'g'), 10)
+				))
+		)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func insertGuardian(ctx context.Context, tx *sql.Tx, guardian *models.Guardian) error {
+	if guardian.ID == "" {
+		guardian.ID = uuid.NewString()
+	}
+	now := time.Now()
+	if guardian.CreatedAt.IsZero() {
+		guardian.CreatedAt = now
+	}
+	guardian.UpdatedAt = now
+
+	if guardian.IsPrimary {
+		if err := clearPrimaryGuardian(ctx, tx, guardian.StudentID, guardian.ID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO guardians (id, student_id, full_name, relationship, phone, email, is_primary, can_pick_up, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, guardian.ID, guardian.StudentID, guardian.FullName, guardian.Relationship, guardian.Phone, guardian.Email,
+		guardian.IsPrimary, guardian.CanPickUp, guardian.CreatedAt, guardian.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return syncStudentParentPhone(ctx, tx, guardian.StudentID)
+}
+
+func clearPrimaryGuardian(ctx context.Context, tx *sql.Tx, studentID, keepID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE guardians
+		SET is_primary = false, updated_at = now()
+		WHERE student_id = $1 AND id <> $2 AND is_primary
+	`, studentID, keepID)
+	return err
+}
+
+// syncStudentParentPhone keeps students.parent_phone pointing at the primary
+// guardian so older readers of that column stay correct. A primary guardian
+// without a phone leaves the column alone rather than clearing a number that
+// link approval and notifications still use.
+func syncStudentParentPhone(ctx context.Context, tx *sql.Tx, studentID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE students s
+		SET parent_phone = g.phone
+		FROM guardians g
+		WHERE s.id = $1 AND g.student_id = s.id AND g.is_primary AND g.phone <> ''
+	`, studentID)
+	return err
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+func (s *Store) GetHealthProfile(ctx context.Context, studentID string) (*models.HealthProfile, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT student_id, school_id, blood_group, allergies, chronic_conditions, medications, notes,
+			coalesce(updated_by::text, ''), created_at, updated_at
+		FROM student_health_profiles
+		WHERE student_id = $1
+	`, studentID)
+
+	var profile models.HealthProfile
+	if err := row.Scan(&profile.StudentID, &profile.SchoolID, &profile.BloodGroup, &profile.Allergies,
+		&profile.ChronicConditions, &profile.Medications, &profile.Notes, &profile.UpdatedBy,
+		&profile.CreatedAt, &profile.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (s *Store) UpsertHealthProfile(ctx context.Context, profile models.HealthProfile) (*models.HealthProfile, error) {
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO student_health_profiles (
+			student_id, school_id, blood_group, allergies, chronic_conditions, medications, notes, updated_by, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now(), now())
+		ON CONFLICT (student_id) DO UPDATE
+		SET blood_group = EXCLUDED.blood_group,
+			allergies = EXCLUDED.allergies,
+			chronic_conditions = EXCLUDED.chronic_conditions,
+			medications = EXCLUDED.medications,
+			notes = EXCLUDED.notes,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = now()
+		RETURNING created_at, updated_at
+	`, profile.StudentID, profile.SchoolID, profile.BloodGroup, profile.Allergies, profile.ChronicConditions,
+		profile.Medications, profile.Notes, profile.UpdatedBy)
+	if err := row.Scan(&profile.CreatedAt, &profile.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+const infirmaryVisitColumns = `
+	v.id, v.school_id, v.student_id, st.full_name, st.class_label, v.visited_at, v.complaint, v.diagnosis,
+	v.treatment, v.status, v.discharged_at, v.discharge_notes, v.recorded_by, v.created_at, v.updated_at
+`
+
+func scanInfirmaryVisit(row interface{ Scan(...any) error }, visit *models.InfirmaryVisit) error {
+	return row.Scan(&visit.ID, &visit.SchoolID, &visit.StudentID, &visit.StudentName, &visit.ClassLabel,
+		&visit.VisitedAt, &visit.Complaint, &visit.Diagnosis, &visit.Treatment, &visit.Status,
+		&visit.DischargedAt, &visit.DischargeNotes, &visit.RecordedBy, &visit.CreatedAt, &visit.UpdatedAt)
+}
+
+func (s *Store) CreateInfirmaryVisit(ctx context.Context, visit models.InfirmaryVisit) (*models.InfirmaryVisit, error) {
+	if visit.ID == "" {
+		visit.ID = uuid.NewString()
+	}
+	now := time.Now()
+	if visit.VisitedAt.IsZero() {
+		visit.VisitedAt = now
+	}
+	visit.CreatedAt = now
+	visit.UpdatedAt = now
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO infirmary_visits (
+			id, school_id, student_id, visited_at, complaint, diagnosis, treatment, status, recorded_by, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, visit.ID, visit.SchoolID, visit.StudentID, visit.VisitedAt, visit.Complaint, visit.Diagnosis, visit.Treatment,
+		visit.Status, visit.RecordedBy, visit.CreatedAt, visit.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &visit, nil
+}
+
+func (s *Store) GetInfirmaryVisit(ctx context.Context, visitID string) (*models.InfirmaryVisit, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+infirmaryVisitColumns+`
+		FROM infirmary_visits v
+		JOIN students st ON st.id = v.student_id
+		WHERE v.id = $1
+	`, visitID)
+	var visit models.InfirmaryVisit
+	if err := scanInfirmaryVisit(row, &visit); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &visit, nil
+}
+
+func (s *Store) ListInfirmaryVisitsByStudent(ctx context.Context, studentID string, limit int) ([]models.InfirmaryVisit, error) {
+	return s.queryInfirmaryVisits(ctx, `
+		SELECT `+infirmaryVisitColumns+`
+		FROM infirmary_visits v
+		JOIN students st ON st.id = v.student_id
+		WHERE v.student_id = $1
+		ORDER BY v.visited_at DESC
+		LIMIT $2
+	`, studentID, limit)
+}
+
+// ListAdmittedStudents returns the open admissions of a school, oldest first.
+func (s *Store) ListAdmittedStudents(ctx context.Context, schoolID string) ([]models.InfirmaryVisit, error) {
+	return s.queryInfirmaryVisits(ctx, `
+		SELECT `+infirmaryVisitColumns+`
+		FROM infirmary_visits v
+		JOIN students st ON st.id = v.student_id
+		WHERE v.school_id = $1 AND v.status = 'admitted'
+		ORDER BY v.visited_at ASC
+	`, schoolID)
+}
+
+// ListInfirmarySummarySchools returns the schools that have students admitted
+// or visits in [from, to) and have not had their summary for day yet.
+func (s *Store) ListInfirmarySummarySchools(ctx context.Context, day, from, to time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT v.school_id::text
+		FROM infirmary_visits v
+		WHERE (v.status = 'admitted' OR (v.visited_at >= $2 AND v.visited_at < $3))
+			AND NOT EXISTS (
+				SELECT 1 FROM infirmary_summaries sm
+				WHERE sm.school_id = v.school_id AND sm.day = $1::date
+			)
+	`, day.Format("2006-01-02"), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClaimInfirmarySummary records that the school's summary for day is being
+// sent and reports whether this call made the claim, so only one instance
+// sends it.
+func (s *Store) ClaimInfirmarySummary(ctx context.Context, schoolID string, day time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO infirmary_summaries (school_id, day)
+		VALUES ($1, $2::date)
+		ON CONFLICT (school_id, day) DO NOTHING
+	`, schoolID, day.Format("2006-01-02"))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// CountInfirmaryVisits counts a school's visits in [from, to) and how many of
+// them were referred to a hospital.
+func (s *Store) CountInfirmaryVisits(ctx context.Context, schoolID string, from, to time.Time) (int, int, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT count(*), count(*) FILTER (WHERE status = 'referred')
+		FROM infirmary_visits
+		WHERE school_id = $1 AND visited_at >= $2 AND visited_at < $3
+	`, schoolID, from, to)
+	var total, referred int
+	if err := row.Scan(&total, &referred); err != nil {
+		return 0, 0, err
+	}
+	return total, referred, nil
+}
+
+// DischargeInfirmaryVisit closes an admission. It returns sql.ErrNoRows when
+// the visit does not exist or is not currently admitted.
+func (s *Store) DischargeInfirmaryVisit(ctx context.Context, visitID, notes string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE infirmary_visits
+		SET status = 'discharged', discharged_at = now(), discharge_notes = $2, updated_at = now()
+		WHERE id = $1 AND status = 'admitted'
+	`, visitID, notes)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *Store) queryInfirmaryVisits(ctx context.Context, query string, args ...any) ([]models.InfirmaryVisit, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.InfirmaryVisit{}
+	for rows.Next() {
+		var visit models.InfirmaryVisit
+		if err := scanInfirmaryVisit(rows, &visit); err != nil {
+			return nil, err
+		}
+		items = append(items, visit)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"jnv/backend/internal/models"
+)
+
+const importJobColumns = `
+	id, school_id, kind, status, params, file_name, upload_key, created_by,
+	progress_done, progress_total, result, error, attempts, cancel_requested,
+	created_at, started_at, finished_at, updated_at
+`
+
+func scanImportJob(row interface{ Scan(...any) error }, job *models.ImportJob) error {
+	var params, result []byte
+	if err := row.Scan(&job.ID, &job.SchoolID, &job.Kind, &job.Status, &params, &job.FileName, &job.UploadKey,
+		&job.CreatedBy, &job.ProgressDone, &job.ProgressTotal, &result, &job.Error, &job.Attempts,
+		&job.CancelRequested, &job.CreatedAt, &job.StartedAt, &job.FinishedAt, &job.UpdatedAt); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(params, &job.Params); err != nil {
+		return err
+	}
+	if result != nil {
+		return json.Unmarshal(result, &job.Result)
+	}
+	return nil
+}
+
+// CreateImportJob queues a job for an archived upload.
+func (s *Store) CreateImportJob(ctx context.Context, job models.ImportJob) (*models.ImportJob, error) {
+	if job.Params == nil {
+		job.Params = map[string]string{}
+	}
+	params, err := json.Marshal(job.Params)
+	if err != nil {
+		return nil, err
+	}
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO import_jobs (school_id, kind, params, file_name, upload_key, created_by)
+		VALUES ($1, $2, $3::jsonb, $4, $5, $6)
+		RETURNING `+importJobColumns,
+		job.SchoolID, job.Kind, string(params), job.FileName, job.UploadKey, job.CreatedBy)
+	var created models.ImportJob
+	if err := scanImportJob(row, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *Store) GetImportJob(ctx context.Context, id string) (*models.ImportJob, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1`, id)
+	var job models.ImportJob
+	if err := scanImportJob(row, &job); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListImportJobs returns a school's most recent jobs, newest first. A
+// non-empty createdBy limits them to one uploader.
+func (s *Store) ListImportJobs(ctx context.Context, schoolID, createdBy string, limit int) ([]models.ImportJob, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+importJobColumns+`
+		FROM import_jobs
+		WHERE school_id = $1 AND ($2::uuid IS NULL OR created_by = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, schoolID, nullString(createdBy), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ImportJob{}
+	for rows.Next() {
+		var job models.ImportJob
+		if err := scanImportJob(rows, &job); err != nil {
+			return nil, err
+		}
+		items = append(items, job)
+	}
+	return items, rows.Err()
+}
+
+// ClaimImportJob marks the oldest runnable job as running under a lease and
+// returns it, or nil when there is nothing to do. Runnable jobs are queued
+// ones and running ones whose lease expired, as long as they have attempts
+// left; expired jobs without attempts left are failed, and expired jobs that
+// were asked to stop are cancelled.
+func (s *Store) ClaimImportJob(ctx context.Context, kinds []string, lease time.Duration, maxAttempts int) (*models.ImportJob, error) {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE import_jobs
+		SET status = CASE WHEN cancel_requested THEN 'cancelled' ELSE 'failed' END,
+			error = CASE WHEN cancel_requested THEN '' ELSE 'import stopped before finishing' END,
+			finished_at = now(), updated_at = now(), lease_until = NULL
+		WHERE status = 'running' AND lease_until < now()
+			AND (cancel_requested OR attempts >= $1)
+	`, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE import_jobs
+		SET status = 'running', attempts = attempts + 1, lease_until = now() + $3 * interval '1 second',
+			started_at = COALESCE(started_at, now()), updated_at = now()
+		WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE kind = ANY($1) AND attempts < $2
+				AND (status = 'queued' OR (status = 'running' AND lease_until < now()))
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+importJobColumns,
+		nonNil(kinds), maxAttempts, lease.Seconds())
+	var job models.ImportJob
+	if err := scanImportJob(row, &job); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RenewImportJob extends the lease of a running job's claim and records its
+// progress. attempt is the job's Attempts as ClaimImportJob returned it. It
+// reports whether someone asked for the job to stop, and returns
+// sql.ErrNoRows once the job is no longer running under that claim.
+func (s *Store) RenewImportJob(ctx context.Context, id string, attempt int, lease time.Duration, done, total int) (bool, error) {
+	var cancelRequested bool
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE import_jobs
+		SET lease_until = now() + $3 * interval '1 second', progress_done = $4, progress_total = $5, updated_at = now()
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+		RETURNING cancel_requested
+	`, id, attempt, lease.Seconds(), done, total).Scan(&cancelRequested)
+	return cancelRequested, err
+}
+
+// FinishImportJob records the outcome of a running job. It returns
+// sql.ErrNoRows when the job was claimed again since job.Attempts, so a
+// worker that lost its lease cannot overwrite the new run.
+func (s *Store) FinishImportJob(ctx context.Context, job models.ImportJob) error {
+	var result any
+	if job.Result != nil {
+		raw, err := json.Marshal(job.Result)
+		if err != nil {
+			return err
+		}
+		result = string(raw)
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE import_jobs
+		SET status = $2, result = $3::jsonb, error = $4, progress_done = $5, progress_total = $6,
+			lease_until = NULL, finished_at = now(), updated_at = now()
+		WHERE id = $1 AND attempts = $7 AND status = 'running'
+	`, job.ID, job.Status, result, job.Error, job.ProgressDone, job.ProgressTotal, job.Attempts)
+	return expectAffected(res, err)
+}
+
+// CancelImportJob cancels a queued job straight away and asks the worker of
+// a running one to stop. It returns nil when the job has already finished.
+func (s *Store) CancelImportJob(ctx context.Context, id string) (*models.ImportJob, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE import_jobs
+		SET cancel_requested = true,
+			status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+			finished_at = CASE WHEN status = 'queued' THEN now() ELSE finished_at END,
+			updated_at = now()
+		WHERE id = $1 AND status IN ('queued', 'running')
+		RETURNING `+importJobColumns, id)
+	var job models.ImportJob
+	if err := scanImportJob(row, &job); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
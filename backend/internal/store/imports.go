@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+// StudentKeys are the values that must be unique among students: class and
+// roll (as ClassRollKey) among a school's active students, admission numbers
+// within a school, and APAAR IDs and PENs nationally.
+type StudentKeys struct {
+	ClassRolls   []string
+	AdmissionNos []string
+	APAARIDs     []string
+	PENs         []string
+}
+
+func ClassRollKey(classLabel string, rollNumber int) string {
+	return classLabel + "/" + strconv.Itoa(rollNumber)
+}
+
+// ExistingStudentKeys returns the subset of keys already held by students,
+// in one query.
+func (s *Store) ExistingStudentKeys(ctx context.Context, schoolID string, keys StudentKeys) (StudentKeys, error) {
+	var existing StudentKeys
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT class_label || '/' || roll_number, status, school_id::text,
+			coalesce(admission_no, ''), coalesce(apaar_id, ''), coalesce(pen, '')
+		FROM students
+		WHERE (school_id = $1 AND status = 'active' AND class_label || '/' || roll_number = ANY($2))
+			OR (school_id = $1 AND admission_no = ANY($3))
+			OR apaar_id = ANY($4)
+			OR pen = ANY($5)
+	`, schoolID, nonNil(keys.ClassRolls), nonNil(keys.AdmissionNos), nonNil(keys.APAARIDs), nonNil(keys.PENs))
+	if err != nil {
+		return existing, err
+	}
+	defer rows.Close()
+
+	wanted := func(values []string) map[string]bool {
+		set := make(map[string]bool, len(values))
+		for _, value := range values {
+			set[value] = true
+		}
+		return set
+	}
+	classRolls, admissionNos := wanted(keys.ClassRolls), wanted(keys.AdmissionNos)
+	apaarIDs, pens := wanted(keys.APAARIDs), wanted(keys.PENs)
+	for rows.Next() {
+		var classRoll, status, studentSchoolID, admissionNo, apaarID, pen string
+		if err := rows.Scan(&classRoll, &status, &studentSchoolID, &admissionNo, &apaarID, &pen); err != nil {
+			return existing, err
+		}
+		if studentSchoolID == schoolID && status == models.StudentStatusActive && classRolls[classRoll] {
+			existing.ClassRolls = append(existing.ClassRolls, classRoll)
+		}
+		if studentSchoolID == schoolID && admissionNos[admissionNo] {
+			existing.AdmissionNos = append(existing.AdmissionNos, admissionNo)
+		}
+		if apaarIDs[apaarID] {
+			existing.APAARIDs = append(existing.APAARIDs, apaarID)
+		}
+		if pens[pen] {
+			existing.PENs = append(existing.PENs, pen)
+		}
+	}
+	return existing, rows.Err()
+}
+
+// StudentImport is one row of a student sheet.
+type StudentImport struct {
+	Student   models.Student
+	Guardians []models.Guardian
+}
+
+// ImportStudents inserts students and their guardians in one transaction
+// with a single statement per table, so either every row is imported or
+// none is.
+func (s *Store) ImportStudents(ctx context.Context, schoolID string, items []StudentImport) error {
+	if len(items) == 0 {
+		return nil
+	}
+	var (
+		ids, names, classes, houses, phones      []string
+		admissionNos, apaarIDs, pens             []string
+		rolls, admissionYears                    []int32
+		birthDates                               []time.Time
+		guardianIDs, guardianStudents, fullNames []string
+		relationships, guardianPhones, emails    []string
+		primaries, pickUps                       []bool
+	)
+	for _, item := range items {
+		student := item.Student
+		id := uuid.NewString()
+		ids = append(ids, id)
+		names = append(names, student.FullName)
+		classes = append(classes, student.ClassLabel)
+		rolls = append(rolls, int32(student.RollNumber))
+		birthDates = append(birthDates, student.DateOfBirth)
+		houses = append(houses, student.House)
+		phones = append(phones, student.ParentPhone)
+		admissionYears = append(admissionYears, int32(student.AdmissionYear))
+		admissionNos = append(admissionNos, student.AdmissionNo)
+		apaarIDs = append(apaarIDs, student.APAARID)
+		pens = append(pens, student.PEN)
+		for _, guardian := range item.Guardians {
+			guardianIDs = append(guardianIDs, uuid.NewString())
+			guardianStudents = append(guardianStudents, id)
+			fullNames = append(fullNames, guardian.FullName)
+			relationships = append(relationships, guardian.Relationship)
+			guardianPhones = append(guardianPhones, guardian.Phone)
+			emails = append(emails, guardian.Email)
+			primaries = append(primaries, guardian.IsPrimary)
+			pickUps = append(pickUps, guardian.CanPickUp)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO students (
+			id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year,
+			admission_no, apaar_id, pen, created_at
+		)
+		SELECT t.id, $1, t.full_name, t.class_label, t.roll_number, t.date_of_birth, t.house, t.parent_phone,
+			t.admission_year, nullif(t.admission_no, ''), nullif(t.apaar_id, ''), nullif(t.pen, ''), now()
+		FROM unnest($2::uuid[], $3::text[], $4::text[], $5::int[], $6::date[], $7::text[], $8::text[], $9::int[],
+			$10::text[], $11::text[], $12::text[])
+			AS t(id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year,
+				admission_no, apaar_id, pen)
+	`, schoolID, ids, names, classes, rolls, birthDates, houses, phones, admissionYears,
+		admissionNos, apaarIDs, pens); err != nil {
+		return err
+	}
+	if len(guardianIDs) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO guardians (id, student_id, full_name, relationship, phone, email, is_primary, can_pick_up, created_at, updated_at)
+			SELECT t.id, t.student_id, t.full_name, t.relationship, t.phone, t.email, t.is_primary, t.can_pick_up, now(), now()
+			FROM unnest($1::uuid[], $2::uuid[], $3::text[], $4::text[], $5::text[], $6::text[], $7::bool[], $8::bool[])
+				AS t(id, student_id, full_name, relationship, phone, email, is_primary, can_pick_up)
+		`, guardianIDs, guardianStudents, fullNames, relationships, guardianPhones, emails, primaries, pickUps); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListStudentsByAdmissionNos returns the school's students, in any class or
+// status, holding one of the admission numbers.
+func (s *Store) ListStudentsByAdmissionNos(ctx context.Context, schoolID string, admissionNos []string) ([]models.Student, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+studentColumns+`
+		FROM students
+		WHERE school_id = $1 AND admission_no = ANY($2)
+	`, schoolID, nonNil(admissionNos))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []models.Student
+	for rows.Next() {
+		var student models.Student
+		if err := scanStudent(rows, &student); err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+	return students, rows.Err()
+}
+
+// ListStudentsByIDs returns the school's students with the given ids, in any
+// class or status.
+func (s *Store) ListStudentsByIDs(ctx context.Context, schoolID string, ids []string) ([]models.Student, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+studentColumns+`
+		FROM students
+		WHERE school_id = $1 AND id = ANY($2)
+	`, schoolID, nonNil(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []models.Student
+	for rows.Next() {
+		var student models.Student
+		if err := scanStudent(rows, &student); err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+	return students, rows.Err()
+}
+
+// nonNil makes a nil slice go to Postgres as an empty array rather than NULL.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
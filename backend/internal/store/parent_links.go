@@ -0,0 +1,454 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+// Auto-approval policies for parent link requests.
+const (
+	ParentLinkPolicyOff         = "off"
+	ParentLinkPolicyParentPhone = "parent_phone"
+	ParentLinkPolicyGuardians   = "guardians"
+)
+
+func (s *Store) GetParentLinkPolicy(ctx context.Context, schoolID string) (string, error) {
+	var policy string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT parent_link_auto_approve
+		FROM schools
+		WHERE id = $1
+	`, schoolID).Scan(&policy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ParentLinkPolicyOff, nil
+	}
+	return policy, err
+}
+
+func (s *Store) SetParentLinkPolicy(ctx context.Context, schoolID, policy string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE schools
+		SET parent_link_auto_approve = $2
+		WHERE id = $1
+	`, schoolID, policy)
+	return err
+}
+
+// MatchStudentPhone reports where a phone (as its last ten digits) appears on
+// a student's record: "parent_phone", "guardian", or "" when it does not.
+func (s *Store) MatchStudentPhone(ctx context.Context, studentID, phoneKey string) (string, error) {
+	var match string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT CASE
+			WHEN right(regexp_replace(st.parent_phone, '\D', '', 'g'), 10) = $2 THEN 'parent_phone'
+			WHEN EXISTS (
+				SELECT 1 FROM guardians g
+				WHERE g.student_id = st.id AND g.phone <> ''
+				  AND right(regexp_replace(g.phone, '\D', '', 'g'), 10) = $2
+			) THEN 'guardian'
+			ELSE ''
+		END
+		FROM students st
+		WHERE st.id = $1
+	`, studentID, phoneKey).Scan(&match)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return match, err
+}
+
+// GetParentLinkForSchool returns a link whose student belongs to the school,
+// or nil.
+func (s *Store) GetParentLinkForSchool(ctx context.Context, linkID, schoolID string) (*models.ParentLink, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT pl.id, pl.parent_id, pl.student_id, pl.status, pl.reason, pl.decided_at, pl.created_at
+		FROM parent_links pl
+		JOIN students st ON st.id = pl.student_id
+		WHERE pl.id = $1 AND st.school_id = $2
+	`, linkID, schoolID)
+	var link models.ParentLink
+	if err := scanParentLink(row, &link); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *Store) ListParentLinksByStudent(ctx context.Context, studentID string) ([]models.ParentLinkApprovalItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pl.id, pl.parent_id, coalesce(u.full_name, ''), coalesce(u.phone, ''), pl.student_id, st.full_name,
+			st.class_label, st.roll_number, pl.status, pl.reason, pl.created_at
+		FROM parent_links pl
+		JOIN students st ON st.id = pl.student_id
+		LEFT JOIN users u ON u.id = pl.parent_id
+		WHERE pl.student_id = $1
+		ORDER BY pl.created_at DESC
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ParentLinkApprovalItem{}
+	for rows.Next() {
+		var item models.ParentLinkApprovalItem
+		if err := rows.Scan(&item.ID, &item.ParentID, &item.ParentName, &item.ParentPhone, &item.StudentID,
+			&item.StudentName, &item.ClassLabel, &item.RollNumber, &item.Status, &item.Reason, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// RejectParentLink declines a pending request. It returns sql.ErrNoRows when
+// the link is not pending.
+func (s *Store) RejectParentLink(ctx context.Context, linkID, decidedBy, reason string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE parent_links
+		SET status = 'rejected', reason = $3, decided_by = $2, decided_at = now()
+		WHERE id = $1 AND status = 'pending'
+	`, linkID, decidedBy, reason)
+	return expectAffected(res, err)
+}
+
+// RevokeParentLink withdraws an approved link. Every approved link between the
+// same parent and student is revoked with it, so access ends at once. It
+// returns sql.ErrNoRows when the link is not approved.
+func (s *Store) RevokeParentLink(ctx context.Context, linkID, decidedBy, reason string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE parent_links pl
+		SET status = 'revoked', reason = $3, decided_by = $2, decided_at = now()
+		FROM parent_links target
+		WHERE target.id = $1 AND target.status = 'approved'
+			AND pl.parent_id = target.parent_id AND pl.student_id = target.student_id AND pl.status = 'approved'
+	`, linkID, decidedBy, reason)
+	return expectAffected(res, err)
+}
+
+func expectAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// StartParentLinkAttempt records an identity challenge before it is checked,
+// as failed until FinishParentLinkAttempt says otherwise, and returns its ID.
+// It returns "" when the parent already has limit failed attempts since the
+// given time. The count and the insert hold a per-parent lock, so parallel
+// requests cannot all slip under the limit.
+func (s *Store) StartParentLinkAttempt(ctx context.Context, parentID string, since time.Time, limit int) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "parent_link_attempts:"+parentID); err != nil {
+		return "", err
+	}
+	var failed int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT count(*)
+		FROM parent_link_attempts
+		WHERE parent_id = $1 AND NOT succeeded AND created_at >= $2
+	`, parentID, since).Scan(&failed); err != nil {
+		return "", err
+	}
+	if failed >= limit {
+		return "", nil
+	}
+	id := uuid.NewString()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO parent_link_attempts (id, parent_id, succeeded, created_at)
+		VALUES ($1, $2, false, now())
+	`, id, parentID); err != nil {
+		return "", err
+	}
+	return id, tx.Commit()
+}
+
+// FinishParentLinkAttempt records the outcome of a started attempt.
+// studentID is empty when the details matched no student.
+func (s *Store) FinishParentLinkAttempt(ctx context.Context, attemptID, studentID string, succeeded bool) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE parent_link_attempts
+		SET student_id = $2, succeeded = $3
+		WHERE id = $1
+	`, attemptID, nullString(studentID), succeeded)
+	return err
+}
+
+// CountFailedLinkAttemptsByStudent counts failed challenges against a
+// student, from any parent, since the given time.
+func (s *Store) CountFailedLinkAttemptsByStudent(ctx context.Context, studentID string, since time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT count(*)
+		FROM parent_link_attempts
+		WHERE student_id = $1 AND NOT succeeded AND created_at >= $2
+	`, studentID, since).Scan(&count)
+	return count, err
+}
+
+// ReplaceParentLinkCodes stores new codes, keyed by student, discarding any
+// unredeemed codes those students had.
+func (s *Store) ReplaceParentLinkCodes(ctx context.Context, schoolID, createdBy string, codeHashes map[string]string, expiresAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for studentID, codeHash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM parent_link_codes
+			WHERE student_id = $1 AND redeemed_at IS NULL
+		`, studentID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO parent_link_codes (id, school_id, student_id, code_hash, expires_at, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, now())
+		`, uuid.NewString(), schoolID, studentID, codeHash, expiresAt, createdBy); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ErrParentLinkRevoked is returned when a parent redeems a code for a student
+// the school has revoked their link to; only the school can restore it.
+var ErrParentLinkRevoked = errors.New("parent link was revoked")
+
+// RedeemParentLinkCode spends an open, unexpired code and gives the parent an
+// approved link to its student, approving any pending request they had. It
+// returns the link, or nil when the code is unknown, used or expired or its
+// student is no longer active. A parent whose link to the student was revoked
+// gets ErrParentLinkRevoked and the code stays open.
+func (s *Store) RedeemParentLinkCode(ctx context.Context, codeHash, parentID string) (*models.ParentLink, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var codeID, studentID, schoolID string
+	err = tx.QueryRowContext(ctx, `
+		SELECT c.id, c.student_id, c.school_id
+		FROM parent_link_codes c
+		JOIN students st ON st.id = c.student_id
+		WHERE c.code_hash = $1 AND c.redeemed_at IS NULL AND c.expires_at > now()
+			AND st.status = 'active'
+		FOR UPDATE OF c
+	`, codeHash).Scan(&codeID, &studentID, &schoolID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var revoked bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM parent_links
+			WHERE parent_id = $1 AND student_id = $2 AND status = 'revoked'
+		)
+	`, parentID, studentID).Scan(&revoked); err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrParentLinkRevoked
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE parent_link_codes
+		SET redeemed_by = $2, redeemed_at = now()
+		WHERE id = $1
+	`, codeID, parentID); err != nil {
+		return nil, err
+	}
+
+	var link models.ParentLink
+	err = scanParentLink(tx.QueryRowContext(ctx, `
+		UPDATE parent_links
+		SET status = 'approved', reason = '', decided_by = NULL, decided_at = now()
+		WHERE id = (
+			SELECT id FROM parent_links
+			WHERE parent_id = $1 AND student_id = $2 AND status IN ('pending', 'approved')
+			ORDER BY status = 'approved' DESC, created_at DESC
+			LIMIT 1
+		)
+		RETURNING `+parentLinkColumns+`
+	`, parentID, studentID), &link)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = scanParentLink(tx.QueryRowContext(ctx, `
+			INSERT INTO parent_links (id, parent_id, student_id, status, decided_at, created_at)
+			VALUES ($1, $2, $3, 'approved', now(), now())
+			RETURNING `+parentLinkColumns+`
+		`, uuid.NewString(), parentID, studentID), &link)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET school_id = $1 WHERE id = $2
+	`, schoolID, parentID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ClaimParentLinkReminders stamps pending requests created before cutoff that
+// have not been reminded about yet with at, and returns them grouped by
+// school. Each request is claimed once, so running the job on several
+// instances does not send duplicate reminders.
+func (s *Store) ClaimParentLinkReminders(ctx context.Context, cutoff, at time.Time) ([]models.PendingLinkDigest, error) {
+	return s.claimPendingLinks(ctx, `
+		WITH claimed AS (
+			UPDATE parent_links pl
+			SET reminded_at = $2
+			FROM students st
+			WHERE st.id = pl.student_id AND pl.status = 'pending'
+				AND pl.reminded_at IS NULL AND pl.created_at < $1
+			RETURNING st.school_id, pl.created_at
+		)
+		SELECT sc.id::text, sc.name, count(*), min(c.created_at)
+		FROM claimed c
+		JOIN schools sc ON sc.id = c.school_id
+		GROUP BY sc.id, sc.name
+		ORDER BY sc.name ASC
+	`, cutoff, at)
+}
+
+// ReleaseParentLinkReminders undoes a school's reminder claim stamped at, so
+// the next run reminds about those requests again.
+func (s *Store) ReleaseParentLinkReminders(ctx context.Context, schoolID string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE parent_links pl
+		SET reminded_at = NULL
+		FROM students st
+		WHERE st.id = pl.student_id AND st.school_id = $1 AND pl.reminded_at = $2
+	`, schoolID, at)
+	return err
+}
+
+// ClaimParentLinkEscalations is ClaimParentLinkReminders for the later
+// escalation to super admins.
+func (s *Store) ClaimParentLinkEscalations(ctx context.Context, cutoff, at time.Time) ([]models.PendingLinkDigest, error) {
+	return s.claimPendingLinks(ctx, `
+		WITH claimed AS (
+			UPDATE parent_links pl
+			SET escalated_at = $2
+			FROM students st
+			WHERE st.id = pl.student_id AND pl.status = 'pending'
+				AND pl.escalated_at IS NULL AND pl.created_at < $1
+			RETURNING st.school_id, pl.created_at
+		)
+		SELECT sc.id::text, sc.name, count(*), min(c.created_at)
+		FROM claimed c
+		JOIN schools sc ON sc.id = c.school_id
+		GROUP BY sc.id, sc.name
+		ORDER BY sc.name ASC
+	`, cutoff, at)
+}
+
+func (s *Store) claimPendingLinks(ctx context.Context, query string, cutoff, at time.Time) ([]models.PendingLinkDigest, error) {
+	rows, err := s.db.QueryContext(ctx, query, cutoff, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []models.PendingLinkDigest
+	for rows.Next() {
+		var digest models.PendingLinkDigest
+		if err := rows.Scan(&digest.SchoolID, &digest.SchoolName, &digest.Count, &digest.OldestAt); err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+	return digests, rows.Err()
+}
+
+// ListUserIDsByRole returns the users holding role. An empty schoolID matches
+// users of every school, which is how super admins are looked up.
+func (s *Store) ListUserIDsByRole(ctx context.Context, schoolID string, role models.Role) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id::text
+		FROM users
+		WHERE role = $2 AND ($1 = '' OR school_id::text = $1)
+		ORDER BY created_at ASC
+	`, schoolID, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ParentLinkMetrics reports approval turnaround per school. Only approvals an
+// admin made since the given time count towards the median; automatic and
+// code-based approvals have no decided_by and are left out. An empty
+// schoolID reports every school.
+func (s *Store) ParentLinkMetrics(ctx context.Context, schoolID string, since time.Time) ([]models.ParentLinkMetrics, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			sc.id::text,
+			sc.name,
+			count(pl.id) FILTER (WHERE pl.status = 'approved' AND pl.decided_by IS NOT NULL AND pl.decided_at >= $2),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY extract(epoch FROM pl.decided_at - pl.created_at) / 3600)
+				FILTER (WHERE pl.status = 'approved' AND pl.decided_by IS NOT NULL AND pl.decided_at >= $2),
+			count(pl.id) FILTER (WHERE pl.status = 'pending'),
+			count(pl.id) FILTER (WHERE pl.status = 'pending' AND pl.escalated_at IS NOT NULL),
+			min(pl.created_at) FILTER (WHERE pl.status = 'pending')
+		FROM schools sc
+		LEFT JOIN students st ON st.school_id = sc.id
+		LEFT JOIN parent_links pl ON pl.student_id = st.id
+		WHERE $1 = '' OR sc.id::text = $1
+		GROUP BY sc.id, sc.name
+		ORDER BY sc.name ASC
+	`, schoolID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.ParentLinkMetrics
+	for rows.Next() {
+		var item models.ParentLinkMetrics
+		if err := rows.Scan(&item.SchoolID, &item.SchoolName, &item.Approved, &item.MedianApprovalHours,
+			&item.Pending, &item.Escalated, &item.OldestPendingAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
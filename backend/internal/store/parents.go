@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"jnv/backend/internal/models"
+)
+
+// ListParentLinksByParent returns one link per student the parent has asked
+// for: the approved link when there is one, otherwise the latest request.
+func (s *Store) ListParentLinksByParent(ctx context.Context, parentID string) ([]models.ParentLink, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+parentLinkColumns+`
+		FROM (
+			SELECT DISTINCT ON (student_id) *
+			FROM parent_links
+			WHERE parent_id = $1
+			ORDER BY student_id, status = 'approved' DESC, created_at DESC
+		) latest
+		ORDER BY created_at ASC
+	`, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ParentLink{}
+	for rows.Next() {
+		var link models.ParentLink
+		if err := scanParentLink(rows, &link); err != nil {
+			return nil, err
+		}
+		items = append(items, link)
+	}
+	return items, rows.Err()
+}
+
+// LatestExamSummary totals the student's scores in their most recent exam
+// with published results, or returns nil when they have none. Absent papers
+// count as zero; medical leave and exemptions are left out of the total.
+func (s *Store) LatestExamSummary(ctx context.Context, studentID string) (*models.ExamSummary, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT e.id, e.title, e.term, e.exam_date, count(sc.id),
+			coalesce(sum(sc.score), 0), coalesce(sum(sc.max_score) FILTER (WHERE sc.status IN ('present', 'absent')), 0)
+		FROM exams e
+		JOIN scores sc ON sc.exam_id = e.id AND sc.student_id = $1
+		WHERE e.status IN ('results_published', 'locked')
+		GROUP BY e.id, e.title, e.term, e.exam_date, e.created_at
+		ORDER BY e.exam_date DESC, e.created_at DESC
+		LIMIT 1
+	`, studentID)
+	var summary models.ExamSummary
+	if err := row.Scan(&summary.ExamID, &summary.Title, &summary.Term, &summary.Date, &summary.Subjects,
+		&summary.TotalScore, &summary.TotalMax); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if summary.TotalMax > 0 {
+		summary.Percentage = float64(summary.TotalScore) / float64(summary.TotalMax) * 100
+	}
+	return &summary, nil
+}
+
+// ListUpcomingEvents returns a school's published events on or after the
+// given day, soonest first.
+func (s *Store) ListUpcomingEvents(ctx context.Context, schoolID string, from time.Time, limit int) ([]models.Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, school_id, title, description, event_date, start_time, end_time,
+		       location, audience, category, published, published_at, created_by, created_at
+		FROM events
+		WHERE school_id = $1 AND published = true AND event_date >= $2
+		ORDER BY event_date ASC, start_time ASC
+		LIMIT $3
+	`, schoolID, from, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.Event{}
+	for rows.Next() {
+		var item models.Event
+		if err := rows.Scan(
+			&item.ID, &item.SchoolID, &item.Title, &item.Description, &item.EventDate, &item.StartTime,
+			&item.EndTime, &item.Location, &item.Audience, &item.Category, &item.Published,
+			&item.PublishedAt, &item.CreatedBy, &item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ListUnreadAnnouncements returns the newest published announcements of a
+// school created since the given time that the user has not read, along with
+// how many there are in total.
+func (s *Store) ListUnreadAnnouncements(ctx context.Context, schoolID, userID string, since time.Time, limit int) ([]models.Announcement, int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.id, a.school_id, a.title, a.content, a.category, a.priority, a.published, a.created_by, a.created_at,
+			count(*) OVER ()
+		FROM announcements a
+		WHERE a.school_id = $1 AND a.published = true AND a.created_at >= $3
+			AND NOT EXISTS (
+				SELECT 1 FROM announcement_reads ar
+				WHERE ar.announcement_id = a.id AND ar.user_id = $2
+			)
+		ORDER BY a.created_at DESC
+		LIMIT $4
+	`, schoolID, userID, since, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []models.Announcement{}
+	total := 0
+	for rows.Next() {
+		var item models.Announcement
+		if err := rows.Scan(&item.ID, &item.SchoolID, &item.Title, &item.Content, &item.Category,
+			&item.Priority, &item.Published, &item.CreatedBy, &item.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+	return items, total, rows.Err()
+}
+
+// MarkAnnouncementRead records that the user has read a published
+// announcement. It returns sql.ErrNoRows when there is no such announcement.
+func (s *Store) MarkAnnouncementRead(ctx context.Context, announcementID, userID string) error {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM announcements WHERE id = $1 AND published = true)
+	`, announcementID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO announcement_reads (announcement_id, user_id, read_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (announcement_id, user_id) DO NOTHING
+	`, announcementID, userID)
+	return err
+}
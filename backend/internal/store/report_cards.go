@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"jnv/backend/internal/models"
+)
+
+// GetReportCardTemplate returns the school's saved layout, or nil when it
+// uses the default.
+func (s *Store) GetReportCardTemplate(ctx context.Context, schoolID string) (*models.ReportCardTemplate, error) {
+	var settings []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT settings FROM report_card_templates WHERE school_id = $1
+	`, schoolID).Scan(&settings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tpl models.ReportCardTemplate
+	if err := json.Unmarshal(settings, &tpl); err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+func (s *Store) SaveReportCardTemplate(ctx context.Context, schoolID string, tpl models.ReportCardTemplate, updatedBy string) error {
+	settings, err := json.Marshal(tpl)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO report_card_templates (school_id, settings, updated_by, updated_at)
+		VALUES ($1, $2::jsonb, $3, now())
+		ON CONFLICT (school_id) DO UPDATE
+		SET settings = excluded.settings, updated_by = excluded.updated_by, updated_at = now()
+	`, schoolID, string(settings), nullString(updatedBy))
+	return err
+}
+
+// ListScoresForExams returns the scores of the given students in the given
+// exams. A nil studentIDs returns every student's scores.
+func (s *Store) ListScoresForExams(ctx context.Context, examIDs, studentIDs []string) ([]models.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, exam_id, student_id, subject, score, max_score, grade, status, created_at
+		FROM scores
+		WHERE exam_id = ANY($1) AND ($2::uuid[] IS NULL OR student_id = ANY($2))
+		ORDER BY student_id, lower(subject)
+	`, nonNil(examIDs), studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.Score{}
+	for rows.Next() {
+		var item models.Score
+		if err := rows.Scan(&item.ID, &item.ExamID, &item.StudentID, &item.Subject, &item.Score,
+			&item.MaxScore, &item.Grade, &item.Status, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ListReportCardRemarks returns the remarks entered for the students in a
+// term, keyed by student ID.
+func (s *Store) ListReportCardRemarks(ctx context.Context, studentIDs []string, term string) (map[string]models.ReportCardRemarks, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT student_id, term, remarks, days_present, working_days, coalesce(updated_by::text, ''), updated_at
+		FROM report_card_remarks
+		WHERE student_id = ANY($1) AND term = $2
+	`, nonNil(studentIDs), term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := map[string]models.ReportCardRemarks{}
+	for rows.Next() {
+		var item models.ReportCardRemarks
+		if err := rows.Scan(&item.StudentID, &item.Term, &item.Remarks, &item.DaysPresent, &item.WorkingDays,
+			&item.UpdatedBy, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items[item.StudentID] = item
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) SaveReportCardRemarks(ctx context.Context, remarks models.ReportCardRemarks) (*models.ReportCardRemarks, error) {
+	var saved models.ReportCardRemarks
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO report_card_remarks (student_id, term, remarks, days_present, working_days, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (student_id, term) DO UPDATE
+		SET remarks = excluded.remarks,
+		    days_present = excluded.days_present,
+		    working_days = excluded.working_days,
+		    updated_by = excluded.updated_by,
+		    updated_at = now()
+		RETURNING student_id, term, remarks, days_present, working_days, coalesce(updated_by::text, ''), updated_at
+	`, remarks.StudentID, remarks.Term, remarks.Remarks, remarks.DaysPresent, remarks.WorkingDays,
+		nullString(remarks.UpdatedBy)).Scan(&saved.StudentID, &saved.Term, &saved.Remarks, &saved.DaysPresent,
+		&saved.WorkingDays, &saved.UpdatedBy, &saved.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
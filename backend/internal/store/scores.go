@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+// ErrExamLocked is returned when scores are written to a locked exam.
+var ErrExamLocked = errors.New("exam is locked")
+
+// ErrSubjectLocked is returned when scores of a locked subject would change.
+var ErrSubjectLocked = errors.New("subject is locked")
+
+// Score write modes. Merge upserts the given scores and leaves the others
+// alone; replace also deletes the exam's scores that are not in the set.
+const (
+	ScoreModeMerge   = "merge"
+	ScoreModeReplace = "replace"
+)
+
+type ScoreWrite struct {
+	Mode      string
+	ChangedBy string
+	Reason    string
+}
+
+type ScoreWriteResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+}
+
+// SaveScores upserts scores keyed by exam, student and subject and records a
+// revision for every score it creates, changes or deletes. Scores identical
+// to the stored ones are left alone. The exam's current scores are read in
+// one query and each kind of change is written with a single statement, all
+// in one transaction: either the whole set is saved or nothing is. Scores of
+// locked subjects are never changed; replace leaves them in place.
+func (s *Store) SaveScores(ctx context.Context, examID string, scores []models.Score, write ScoreWrite) (ScoreWriteResult, error) {
+	var result ScoreWriteResult
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM exams WHERE id = $1 FOR UPDATE`, examID).Scan(&status); err != nil {
+		return result, err
+	}
+	if status == models.ExamLocked {
+		return result, ErrExamLocked
+	}
+
+	existing, err := examScoresByKey(ctx, tx, examID)
+	if err != nil {
+		return result, err
+	}
+	locked, err := examLockedSubjects(ctx, tx, examID)
+	if err != nil {
+		return result, err
+	}
+	var created, updated, previous, deleted []models.Score
+	kept := make(map[string]bool, len(scores))
+	for _, score := range scores {
+		if score.Status == "" {
+			score.Status = models.ScorePresent
+		}
+		key := scoreKey(score)
+		kept[key] = true
+		old, ok := existing[key]
+		switch {
+		case ok && old.Score == score.Score && old.MaxScore == score.MaxScore && old.Grade == score.Grade && old.Status == score.Status:
+			result.Unchanged++
+		case locked[strings.ToLower(score.Subject)]:
+			return result, ErrSubjectLocked
+		case !ok:
+			created = append(created, score)
+		default:
+			score.ID = old.ID
+			updated = append(updated, score)
+			previous = append(previous, old)
+		}
+	}
+	if write.Mode == ScoreModeReplace {
+		for key, old := range existing {
+			if !kept[key] && !locked[strings.ToLower(old.Subject)] {
+				deleted = append(deleted, old)
+			}
+		}
+	}
+
+	if err := insertScores(ctx, tx, examID, created, write); err != nil {
+		return result, err
+	}
+	if err := updateScores(ctx, tx, updated, write); err != nil {
+		return result, err
+	}
+	if len(deleted) > 0 {
+		ids := make([]string, 0, len(deleted))
+		for _, score := range deleted {
+			ids = append(ids, score.ID)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM scores WHERE id = ANY($1::uuid[])`, ids); err != nil {
+			return result, err
+		}
+	}
+	if err := insertScoreRevisions(ctx, tx, examID, "created", nil, created, write); err != nil {
+		return result, err
+	}
+	if err := insertScoreRevisions(ctx, tx, examID, "updated", previous, updated, write); err != nil {
+		return result, err
+	}
+	if err := insertScoreRevisions(ctx, tx, examID, "deleted", deleted, nil, write); err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	result.Created, result.Updated, result.Deleted = len(created), len(updated), len(deleted)
+	return result, nil
+}
+
+func scoreKey(score models.Score) string {
+	return score.StudentID + "/" + strings.ToLower(score.Subject)
+}
+
+// examLockedSubjects returns the lowercased names of the locked subjects in
+// the catalogue of the exam's class.
+func examLockedSubjects(ctx context.Context, tx *sql.Tx, examID string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT lower(s.name)
+		FROM subjects s
+		JOIN exams e ON e.school_id = s.school_id AND e.class = s.class
+		WHERE e.id = $1 AND s.locked
+	`, examID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locked := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		locked[name] = true
+	}
+	return locked, rows.Err()
+}
+
+func examScoresByKey(ctx context.Context, tx *sql.Tx, examID string) (map[string]models.Score, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, student_id, subject, score, max_score, grade, status
+		FROM scores
+		WHERE exam_id = $1
+	`, examID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scores := map[string]models.Score{}
+	for rows.Next() {
+		var score models.Score
+		if err := rows.Scan(&score.ID, &score.StudentID, &score.Subject, &score.Score, &score.MaxScore, &score.Grade, &score.Status); err != nil {
+			return nil, err
+		}
+		scores[scoreKey(score)] = score
+	}
+	return scores, rows.Err()
+}
+
+func insertScores(ctx context.Context, tx *sql.Tx, examID string, scores []models.Score, write ScoreWrite) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	ids := make([]string, len(scores))
+	studentIDs, subjects, grades := make([]string, len(scores)), make([]string, len(scores)), make([]string, len(scores))
+	values, maxValues, statuses := make([]float32, len(scores)), make([]float32, len(scores)), make([]string, len(scores))
+	for i, score := range scores {
+		ids[i] = uuid.NewString()
+		studentIDs[i], subjects[i], grades[i] = score.StudentID, score.Subject, score.Grade
+		values[i], maxValues[i], statuses[i] = score.Score, score.MaxScore, score.Status
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO scores (id, exam_id, student_id, subject, score, max_score, grade, status, created_at, updated_at, updated_by)
+		SELECT t.id, $1, t.student_id, t.subject, t.score, t.max_score, t.grade, t.status, now(), now(), $2
+		FROM unnest($3::uuid[], $4::uuid[], $5::text[], $6::float4[], $7::float4[], $8::text[], $9::text[])
+			AS t(id, student_id, subject, score, max_score, grade, status)
+	`, examID, nullString(write.ChangedBy), ids, studentIDs, subjects, values, maxValues, grades, statuses)
+	return err
+}
+
+func updateScores(ctx context.Context, tx *sql.Tx, scores []models.Score, write ScoreWrite) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	ids, grades, statuses := make([]string, len(scores)), make([]string, len(scores)), make([]string, len(scores))
+	values, maxValues := make([]float32, len(scores)), make([]float32, len(scores))
+	for i, score := range scores {
+		ids[i], grades[i], statuses[i] = score.ID, score.Grade, score.Status
+		values[i], maxValues[i] = score.Score, score.MaxScore
+	}
+	_, err := tx.ExecContext(ctx, `
+		UPDATE scores sc
+		SET score = t.score, max_score = t.max_score, grade = t.grade, status = t.status,
+			updated_at = now(), updated_by = $1
+		FROM unnest($2::uuid[], $3::float4[], $4::float4[], $5::text[], $6::text[]) AS t(id, score, max_score, grade, status)
+		WHERE sc.id = t.id
+	`, nullString(write.ChangedBy), ids, values, maxValues, grades, statuses)
+	return err
+}
+
+// insertScoreRevisions records one revision per score. previous is nil for
+// created scores and current is nil for deleted ones; otherwise they are
+// parallel slices.
+func insertScoreRevisions(ctx context.Context, tx *sql.Tx, examID, action string, previous, current []models.Score, write ScoreWrite) error {
+	count := len(current)
+	if current == nil {
+		count = len(previous)
+	}
+	if count == 0 {
+		return nil
+	}
+	studentIDs, subjects := make([]string, count), make([]string, count)
+	prevValues, prevMax, values, maxValues := make([]float32, count), make([]float32, count), make([]float32, count), make([]float32, count)
+	prevGrades, grades := make([]string, count), make([]string, count)
+	prevStatuses, statuses := make([]string, count), make([]string, count)
+	for i := 0; i < count; i++ {
+		if previous != nil {
+			studentIDs[i], subjects[i] = previous[i].StudentID, previous[i].Subject
+			prevValues[i], prevMax[i], prevGrades[i] = previous[i].Score, previous[i].MaxScore, previous[i].Grade
+			prevStatuses[i] = previous[i].Status
+		}
+		if current != nil {
+			studentIDs[i], subjects[i] = current[i].StudentID, current[i].Subject
+			values[i], maxValues[i], grades[i] = current[i].Score, current[i].MaxScore, current[i].Grade
+			statuses[i] = current[i].Status
+		}
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO score_revisions (
+			exam_id, student_id, subject, action, previous_score, previous_max_score, previous_grade,
+			previous_status, score, max_score, grade, status, reason, changed_by, created_at
+		)
+		SELECT $1, t.student_id, t.subject, $2,
+			CASE WHEN $3 THEN t.previous_score END,
+			CASE WHEN $3 THEN t.previous_max_score END,
+			CASE WHEN $3 THEN t.previous_grade END,
+			CASE WHEN $3 THEN t.previous_status END,
+			CASE WHEN $4 THEN t.score END,
+			CASE WHEN $4 THEN t.max_score END,
+			CASE WHEN $4 THEN t.grade END,
+			CASE WHEN $4 THEN t.status END,
+			$5, $6, now()
+		FROM unnest($7::uuid[], $8::text[], $9::float4[], $10::float4[], $11::text[], $12::text[],
+			$13::float4[], $14::float4[], $15::text[], $16::text[])
+			AS t(student_id, subject, previous_score, previous_max_score, previous_grade, previous_status,
+				score, max_score, grade, status)
+	`, examID, action, previous != nil, current != nil, write.Reason, nullString(write.ChangedBy),
+		studentIDs, subjects, prevValues, prevMax, prevGrades, prevStatuses, values, maxValues, grades, statuses)
+	return err
+}
+
+// ListScoreRevisions returns the change history of a student's scores in an
+// exam, newest first.
+func (s *Store) ListScoreRevisions(ctx context.Context, examID, studentID string) ([]models.ScoreRevision, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, r.exam_id, r.student_id, r.subject, r.action, r.previous_score, r.previous_max_score,
+			r.previous_grade, r.score, r.max_score, r.grade, r.previous_status, r.status, r.reason, coalesce(r.changed_by::text, ''),
+			coalesce(u.full_name, ''), r.created_at
+		FROM score_revisions r
+		LEFT JOIN users u ON u.id = r.changed_by
+		WHERE r.exam_id = $1 AND r.student_id = $2
+		ORDER BY r.created_at DESC, r.subject ASC
+	`, examID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ScoreRevision{}
+	for rows.Next() {
+		var item models.ScoreRevision
+		if err := rows.Scan(&item.ID, &item.ExamID, &item.StudentID, &item.Subject, &item.Action,
+			&item.PreviousScore, &item.PreviousMaxScore, &item.PreviousGrade, &item.Score, &item.MaxScore,
+			&item.Grade, &item.PreviousStatus, &item.Status, &item.Reason, &item.ChangedBy, &item.ChangedByName,
+			&item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
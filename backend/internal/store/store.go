@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -36,6 +37,23 @@ func (s *Store) FirstSchoolID(ctx context.Context) (string, error) {
 	return schoolID, nil
 }
 
+func (s *Store) GetSchool(ctx context.Context, schoolID string) (*models.School, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id::text, name, state, district, created_at
+		FROM schools
+		WHERE id = $1
+	`, schoolID)
+
+	var school models.School
+	if err := row.Scan(&school.ID, &school.Name, &school.State, &school.District, &school.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &school, nil
+}
+
 func (s *Store) GetSchoolByDistrict(ctx context.Context, district string) (*models.School, error) {
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id::text, name, state, district, created_at
@@ -76,6 +94,25 @@ func (s *Store) ListDistricts(ctx context.Context) ([]string, error) {
 	return districts, rows.Err()
 }
 
+func (s *Store) GetUser(ctx context.Context, id string) (*models.User, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, school_id, role, full_name, phone, email, created_at
+		FROM users
+		WHERE id = $1
+	`, id)
+
+	var user models.User
+	var schoolID sql.NullString
+	if err := row.Scan(&user.ID, &schoolID, &user.Role, &user.FullName, &user.Phone, &user.Email, &user.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	user.SchoolID = schoolID.String
+	return &user, nil
+}
+
 func (s *Store) GetUserByPhone(ctx context.Context, phone string) (*models.User, error) {
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, school_id, role, full_name, phone, email, created_at
@@ -113,16 +150,26 @@ func (s *Store) CreateUser(ctx context.Context, user models.User) (*models.User,
 	return &user, nil
 }
 
+const studentColumns = `
+	id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year,
+	coalesce(admission_no, ''), coalesce(apaar_id, ''), coalesce(pen, ''), status, graduation_year, archived_at, created_at
+`
+
+func scanStudent(row interface{ Scan(...any) error }, student *models.Student) error {
+	return row.Scan(&student.ID, &student.SchoolID, &student.FullName, &student.ClassLabel, &student.RollNumber,
+		&student.DateOfBirth, &student.House, &student.ParentPhone, &student.AdmissionYear, &student.AdmissionNo,
+		&student.APAARID, &student.PEN, &student.Status, &student.GraduationYear, &student.ArchivedAt, &student.CreatedAt)
+}
+
 func (s *Store) GetStudent(ctx context.Context, studentID string) (*models.Student, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year, created_at
+		SELECT `+studentColumns+`
 		FROM students
 		WHERE id = $1
 	`, studentID)
 
 	var student models.Student
-	if err := row.Scan(&student.ID, &student.SchoolID, &student.FullName, &student.ClassLabel, &student.RollNumber,
-		&student.DateOfBirth, &student.House, &student.ParentPhone, &student.AdmissionYear, &student.CreatedAt); err != nil {
+	if err := scanStudent(row, &student); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -138,12 +185,17 @@ func (s *Store) CreateStudent(ctx context.Context, student models.Student) (*mod
 	if student.CreatedAt.IsZero() {
 		student.CreatedAt = time.Now()
 	}
+	student.Status = models.StudentStatusActive
 
 	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO students (id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO students (
+			id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year,
+			admission_no, apaar_id, pen, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`, student.ID, student.SchoolID, student.FullName, student.ClassLabel, student.RollNumber,
-		student.DateOfBirth, student.House, student.ParentPhone, student.AdmissionYear, student.CreatedAt)
+		student.DateOfBirth, student.House, student.ParentPhone, student.AdmissionYear,
+		nullString(student.AdmissionNo), nullString(student.APAARID), nullString(student.PEN), student.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -157,17 +209,17 @@ func (s *Store) ListStudentsBySchool(ctx context.Context, schoolID, classLabel s
 	)
 	if classLabel != "" {
 		rows, err = s.db.QueryContext(ctx, `
-			SELECT id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year, created_at
+			SELECT `+studentColumns+`
 			FROM students
-			WHERE school_id = $1 AND class_label = $2
+			WHERE school_id = $1 AND class_label = $2 AND status = 'active'
 			ORDER BY class_label ASC, roll_number ASC
 			LIMIT $3
 		`, schoolID, classLabel, limit)
 	} else {
 		rows, err = s.db.QueryContext(ctx, `
-			SELECT id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year, created_at
+			SELECT `+studentColumns+`
 			FROM students
-			WHERE school_id = $1
+			WHERE school_id = $1 AND status = 'active'
 			ORDER BY class_label ASC, roll_number ASC
 			LIMIT $2
 		`, schoolID, limit)
@@ -180,8 +232,7 @@ func (s *Store) ListStudentsBySchool(ctx context.Context, schoolID, classLabel s
 	var students []models.Student
 	for rows.Next() {
 		var student models.Student
-		if err := rows.Scan(&student.ID, &student.SchoolID, &student.FullName, &student.ClassLabel, &student.RollNumber,
-			&student.DateOfBirth, &student.House, &student.ParentPhone, &student.AdmissionYear, &student.CreatedAt); err != nil {
+		if err := scanStudent(rows, &student); err != nil {
 			return nil, err
 		}
 		students = append(students, student)
@@ -208,9 +259,15 @@ func (s *Store) CreateParentLink(ctx context.Context, parentID, studentID string
 	return &link, nil
 }
 
+const parentLinkColumns = `id, parent_id, student_id, status, reason, decided_at, created_at`
+
+func scanParentLink(row interface{ Scan(...any) error }, link *models.ParentLink) error {
+	return row.Scan(&link.ID, &link.ParentID, &link.StudentID, &link.Status, &link.Reason, &link.DecidedAt, &link.CreatedAt)
+}
+
 func (s *Store) FindParentLink(ctx context.Context, parentID, studentID string) (*models.ParentLink, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, parent_id, student_id, status, created_at
+		SELECT `+parentLinkColumns+`
 		FROM parent_links
 		WHERE parent_id = $1 AND student_id = $2
 		ORDER BY created_at DESC
@@ -218,7 +275,7 @@ func (s *Store) FindParentLink(ctx context.Context, parentID, studentID string)
 	`, parentID, studentID)
 
 	var link models.ParentLink
-	if err := row.Scan(&link.ID, &link.ParentID, &link.StudentID, &link.Status, &link.CreatedAt); err != nil {
+	if err := scanParentLink(row, &link); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -227,17 +284,20 @@ func (s *Store) FindParentLink(ctx context.Context, parentID, studentID string)
 	return &link, nil
 }
 
+// LatestParentLinkByParent returns the parent's most recent approved link, or
+// failing that their most recent link of any status. A revoked link stops
+// counting as soon as it is revoked.
 func (s *Store) LatestParentLinkByParent(ctx context.Context, parentID string) (*models.ParentLink, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, parent_id, student_id, status, created_at
+		SELECT `+parentLinkColumns+`
 		FROM parent_links
 		WHERE parent_id = $1
-		ORDER BY created_at DESC
+		ORDER BY status = 'approved' DESC, created_at DESC
 		LIMIT 1
 	`, parentID)
 
 	var link models.ParentLink
-	if err := row.Scan(&link.ID, &link.ParentID, &link.StudentID, &link.Status, &link.CreatedAt); err != nil {
+	if err := scanParentLink(row, &link); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -266,7 +326,7 @@ func (s *Store) IsParentLinkedToStudent(ctx context.Context, parentID, studentID
 
 func (s *Store) ListPendingParentLinks(ctx context.Context, schoolID string) ([]models.ParentLink, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT pl.id, pl.parent_id, pl.student_id, pl.status, pl.created_at
+		SELECT pl.id, pl.parent_id, pl.student_id, pl.status, pl.reason, pl.decided_at, pl.created_at
 		FROM parent_links pl
 		JOIN students s ON s.id = pl.student_id
 		WHERE pl.status = 'pending' AND s.school_id = $1
@@ -280,7 +340,7 @@ func (s *Store) ListPendingParentLinks(ctx context.Context, schoolID string) ([]
 	var links []models.ParentLink
 	for rows.Next() {
 		var link models.ParentLink
-		if err := rows.Scan(&link.ID, &link.ParentID, &link.StudentID, &link.Status, &link.CreatedAt); err != nil {
+		if err := scanParentLink(rows, &link); err != nil {
 			return nil, err
 		}
 		links = append(links, link)
@@ -288,6 +348,8 @@ func (s *Store) ListPendingParentLinks(ctx context.Context, schoolID string) ([]
 	return links, rows.Err()
 }
 
+// ListPendingParentLinksDetailed returns the school's open link requests,
+// oldest first, so the ones closest to escalation are at the top.
 func (s *Store) ListPendingParentLinksDetailed(ctx context.Context, schoolID string) ([]models.ParentLinkApprovalItem, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT
@@ -300,12 +362,32 @@ func (s *Store) ListPendingParentLinksDetailed(ctx context.Context, schoolID str
 			COALESCE(st.class_label, ''),
 			COALESCE(st.roll_number, 0),
 			pl.status,
+			COALESCE(g.relationship, ''),
+			CASE
+				WHEN u.phone LIKE 'email:%' OR u.phone LIKE 'uid:%' THEN 'mismatch'
+				WHEN length(right(regexp_replace(u.phone, '\D', '', 'g'), 10)) < 10 THEN 'mismatch'
+				WHEN right(regexp_replace(st.parent_phone, '\D', '', 'g'), 10) = right(regexp_replace(u.phone, '\D', '', 'g'), 10) THEN 'parent_phone'
+				WHEN g.relationship IS NOT NULL THEN 'guardian'
+				ELSE 'mismatch'
+			END,
+			floor(extract(epoch FROM now() - pl.created_at) / 86400)::int,
+			pl.reminded_at,
+			pl.escalated_at,
 			pl.created_at
 		FROM parent_links pl
 		JOIN students st ON st.id = pl.student_id
 		LEFT JOIN users u ON u.id = pl.parent_id
+		LEFT JOIN LATERAL (
+			SELECT gd.relationship
+			FROM guardians gd
+			WHERE gd.student_id = st.id
+			  AND gd.phone <> ''
+			  AND right(regexp_replace(gd.phone, '\D', '', 'g'), 10) = right(regexp_replace(u.phone, '\D', '', 'g'), 10)
+			ORDER BY gd.is_primary DESC, gd.created_at ASC
+			LIMIT 1
+		) g ON TRUE
 		WHERE pl.status = 'pending' AND st.school_id = $1
-		ORDER BY pl.created_at DESC
+		ORDER BY pl.created_at ASC
 	`, schoolID)
 	if err != nil {
 		return nil, err
@@ -325,6 +407,11 @@ func (s *Store) ListPendingParentLinksDetailed(ctx context.Context, schoolID str
 			&item.ClassLabel,
 			&item.RollNumber,
 			&item.Status,
+			&item.GuardianRelationship,
+			&item.PhoneMatch,
+			&item.AgeDays,
+			&item.RemindedAt,
+			&item.EscalatedAt,
 			&item.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -334,24 +421,44 @@ func (s *Store) ListPendingParentLinksDetailed(ctx context.Context, schoolID str
 	return items, rows.Err()
 }
 
-func (s *Store) ApproveParentLink(ctx context.Context, linkID, schoolID string) error {
-	_, err := s.db.ExecContext(ctx, `
-		UPDATE parent_links
-		SET status = 'approved'
-		WHERE id = $1
-	`, linkID)
+// ApproveParentLink approves a pending request for a student of the school.
+// decidedBy is empty for automatic approvals. It returns sql.ErrNoRows when
+// there is no such pending request.
+func (s *Store) ApproveParentLink(ctx context.Context, linkID, schoolID, decidedBy string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	_, err = s.db.ExecContext(ctx, `
+	res, err := tx.ExecContext(ctx, `
+		UPDATE parent_links pl
+		SET status = 'approved', reason = '', decided_by = $3, decided_at = now()
+		FROM students st
+		WHERE pl.id = $1 AND pl.status = 'pending' AND st.id = pl.student_id AND st.school_id = $2
+	`, linkID, schoolID, nullString(decidedBy))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.ExecContext(ctx, `
 		UPDATE users
 		SET school_id = $1
 		WHERE id = (
 			SELECT parent_id FROM parent_links WHERE id = $2
 		)
 	`, schoolID, linkID)
-	return err
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *Store) CreateAnnouncement(ctx context.Context, announcement models.Announcement) (*models.Announcement, error) {
@@ -646,6 +753,27 @@ func (s *Store) ListDeviceTokensBySchoolRole(ctx context.Context, schoolID strin
 	return tokens, rows.Err()
 }
 
+func (s *Store) ListDeviceTokensByUser(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT token
+		FROM device_tokens
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
 func (s *Store) CreateAuditEvent(ctx context.Context, event models.AuditEvent) error {
 	if event.ID == "" {
 		event.ID = uuid.NewString()
@@ -690,60 +818,64 @@ func (s *Store) CreateExam(ctx context.Context, exam models.Exam) (*models.Exam,
 	if exam.CreatedAt.IsZero() {
 		exam.CreatedAt = time.Now()
 	}
+	exam.Status = models.ExamDraft
 
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO exams (id, school_id, class, title, term, exam_date, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, exam.ID, exam.SchoolID, exam.Class, exam.Title, exam.Term, exam.Date, exam.CreatedAt)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO exams (id, school_id, class, title, term, exam_date, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`, exam.ID, exam.SchoolID, exam.Class, exam.Title, exam.Term, exam.Date, exam.Status, exam.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := replaceExamSubjects(ctx, tx, exam.ID, exam.Subjects); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &exam, nil
 }
 
 func (s *Store) GetExam(ctx context.Context, examID string) (*models.Exam, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, school_id, class, title, term, exam_date, created_at
-		FROM exams
-		WHERE id = $1
+		SELECT `+examColumns+`
+		FROM exams e
+		WHERE e.id = $1
 	`, examID)
 
 	var exam models.Exam
-	if err := row.Scan(&exam.ID, &exam.SchoolID, &exam.Class, &exam.Title, &exam.Term, &exam.Date, &exam.CreatedAt); err != nil {
+	if err := scanExam(row, &exam); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &exam, nil
-}
-
-func (s *Store) AddScores(ctx context.Context, examID string, scores []models.Score) error {
-	for _, score := range scores {
-		if score.ID == "" {
-			score.ID = uuid.NewString()
-		}
-		if score.CreatedAt.IsZero() {
-			score.CreatedAt = time.Now()
-		}
-		_, err := s.db.ExecContext(ctx, `
-			INSERT INTO scores (id, exam_id, student_id, subject, score, max_score, grade, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, score.ID, examID, score.StudentID, score.Subject, score.Score, score.MaxScore, score.Grade, score.CreatedAt)
-		if err != nil {
-			return err
-		}
+	subjects, err := s.ListExamSubjects(ctx, exam.ID)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	exam.Subjects = subjects
+	return &exam, nil
 }
 
-func (s *Store) ListScoresByStudent(ctx context.Context, studentID string) ([]models.Score, error) {
+// ListScoresByStudent returns a student's scores, newest first. With
+// publishedOnly set, scores of exams whose results are not yet published are
+// left out; that is what parents see.
+func (s *Store) ListScoresByStudent(ctx context.Context, studentID string, publishedOnly bool) ([]models.Score, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, exam_id, student_id, subject, score, max_score, grade, created_at
-		FROM scores
-		WHERE student_id = $1
-		ORDER BY created_at DESC
-	`, studentID)
+		SELECT sc.id, sc.exam_id, sc.student_id, sc.subject, sc.score, sc.max_score, sc.grade, sc.status, sc.created_at
+		FROM scores sc
+		JOIN exams e ON e.id = sc.exam_id
+		WHERE sc.student_id = $1
+			AND (NOT $2 OR e.status IN ('results_published', 'locked'))
+		ORDER BY sc.created_at DESC
+	`, studentID, publishedOnly)
 	if err != nil {
 		return nil, err
 	}
@@ -753,7 +885,7 @@ func (s *Store) ListScoresByStudent(ctx context.Context, studentID string) ([]mo
 	for rows.Next() {
 		var score models.Score
 		if err := rows.Scan(&score.ID, &score.ExamID, &score.StudentID, &score.Subject,
-			&score.Score, &score.MaxScore, &score.Grade, &score.CreatedAt); err != nil {
+			&score.Score, &score.MaxScore, &score.Grade, &score.Status, &score.CreatedAt); err != nil {
 			return nil, err
 		}
 		items = append(items, score)
@@ -763,14 +895,39 @@ func (s *Store) ListScoresByStudent(ctx context.Context, studentID string) ([]mo
 
 func (s *Store) GetStudentByClassRoll(ctx context.Context, schoolID, classLabel string, rollNumber int) (*models.Student, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year, created_at
+		SELECT `+studentColumns+`
 		FROM students
-		WHERE school_id = $1 AND class_label = $2 AND roll_number = $3
+		WHERE school_id = $1 AND class_label = $2 AND roll_number = $3 AND status = 'active'
 	`, schoolID, classLabel, rollNumber)
 
 	var student models.Student
-	if err := row.Scan(&student.ID, &student.SchoolID, &student.FullName, &student.ClassLabel, &student.RollNumber,
-		&student.DateOfBirth, &student.House, &student.ParentPhone, &student.AdmissionYear, &student.CreatedAt); err != nil {
+	if err := scanStudent(row, &student); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &student, nil
+}
+
+// GetStudentByIdentifier finds a student by one of the stable identifiers:
+// "admission_no" (scoped to the school), "apaar_id" or "pen". Unlike class
+// and roll, these also find alumni.
+func (s *Store) GetStudentByIdentifier(ctx context.Context, schoolID, kind, value string) (*models.Student, error) {
+	var row *sql.Row
+	switch kind {
+	case "admission_no":
+		row = s.db.QueryRowContext(ctx, `SELECT `+studentColumns+` FROM students WHERE school_id = $1 AND admission_no = $2`, schoolID, value)
+	case "apaar_id":
+		row = s.db.QueryRowContext(ctx, `SELECT `+studentColumns+` FROM students WHERE apaar_id = $1`, value)
+	case "pen":
+		row = s.db.QueryRowContext(ctx, `SELECT `+studentColumns+` FROM students WHERE pen = $1`, value)
+	default:
+		return nil, fmt.Errorf("unknown student identifier %q", kind)
+	}
+
+	var student models.Student
+	if err := scanStudent(row, &student); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -779,11 +936,32 @@ func (s *Store) GetStudentByClassRoll(ctx context.Context, schoolID, classLabel
 	return &student, nil
 }
 
+// UpdateStudentIdentifiers replaces a student's admission number, APAAR ID
+// and PEN; empty values clear them.
+func (s *Store) UpdateStudentIdentifiers(ctx context.Context, studentID, admissionNo, apaarID, pen string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE students
+		SET admission_no = $2, apaar_id = $3, pen = $4
+		WHERE id = $1
+	`, studentID, nullString(admissionNo), nullString(apaarID), nullString(pen))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (s *Store) FindStudentByClassRollGlobal(ctx context.Context, classLabel string, rollNumber int) (*models.Student, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, school_id, full_name, class_label, roll_number, date_of_birth, house, parent_phone, admission_year, created_at
+		SELECT `+studentColumns+`
 		FROM students
-		WHERE class_label = $1 AND roll_number = $2
+		WHERE class_label = $1 AND roll_number = $2 AND status = 'active'
 		LIMIT 2
 	`, classLabel, rollNumber)
 	if err != nil {
@@ -794,8 +972,7 @@ func (s *Store) FindStudentByClassRollGlobal(ctx context.Context, classLabel str
 	var students []models.Student
 	for rows.Next() {
 		var student models.Student
-		if err := rows.Scan(&student.ID, &student.SchoolID, &student.FullName, &student.ClassLabel, &student.RollNumber,
-			&student.DateOfBirth, &student.House, &student.ParentPhone, &student.AdmissionYear, &student.CreatedAt); err != nil {
+		if err := scanStudent(rows, &student); err != nil {
 			return nil, err
 		}
 		students = append(students, student)
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"jnv/backend/internal/models"
+)
+
+// ErrSubjectHasScores is returned when an exam's subject list would drop a
+// subject that already has scores, or change its max score.
+var ErrSubjectHasScores = errors.New("subject has scores")
+
+const subjectColumns = `
+	s.id, s.school_id, s.class, s.name, s.aliases, s.locked,
+	(SELECT count(*) FROM exam_subjects es WHERE es.subject_id = s.id), s.created_at, s.updated_at
+`
+
+func scanSubject(row interface{ Scan(...any) error }, subject *models.Subject) error {
+	var aliases []byte
+	if err := row.Scan(&subject.ID, &subject.SchoolID, &subject.Class, &subject.Name, &aliases, &subject.Locked,
+		&subject.ExamCount, &subject.CreatedAt, &subject.UpdatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(aliases, &subject.Aliases)
+}
+
+// ListSubjects returns the catalogue of a class in name order.
+func (s *Store) ListSubjects(ctx context.Context, schoolID, class string) ([]models.Subject, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+subjectColumns+`
+		FROM subjects s
+		WHERE s.school_id = $1 AND s.class = $2
+		ORDER BY lower(s.name)
+	`, schoolID, class)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.Subject{}
+	for rows.Next() {
+		var subject models.Subject
+		if err := scanSubject(rows, &subject); err != nil {
+			return nil, err
+		}
+		items = append(items, subject)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) GetSubject(ctx context.Context, subjectID, schoolID string) (*models.Subject, error) {
+	var subject models.Subject
+	err := scanSubject(s.db.QueryRowContext(ctx, `
+		SELECT `+subjectColumns+`
+		FROM subjects s
+		WHERE s.id = $1 AND s.school_id = $2
+	`, subjectID, schoolID), &subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+func (s *Store) CreateSubject(ctx context.Context, subject models.Subject) (*models.Subject, error) {
+	aliases, err := json.Marshal(nonNil(subject.Aliases))
+	if err != nil {
+		return nil, err
+	}
+	var created models.Subject
+	err = scanSubject(s.db.QueryRowContext(ctx, `
+		WITH s AS (
+			INSERT INTO subjects (id, school_id, class, name, aliases, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5::jsonb, now(), now())
+			RETURNING *
+		)
+		SELECT `+subjectColumns+` FROM s
+	`, uuid.NewString(), subject.SchoolID, subject.Class, subject.Name, string(aliases)), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateSubject renames a subject and replaces its aliases. Scores of the
+// class recorded under the old name are renamed with it so reports keep one
+// row per subject. Locked subjects are left untouched and sql.ErrNoRows is
+// returned.
+func (s *Store) UpdateSubject(ctx context.Context, subject models.Subject, previousName string) error {
+	aliases, err := json.Marshal(nonNil(subject.Aliases))
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE subjects
+		SET name = $3, aliases = $4::jsonb, updated_at = now()
+		WHERE id = $1 AND school_id = $2 AND NOT locked
+	`, subject.ID, subject.SchoolID, subject.Name, string(aliases))
+	if err := expectAffected(res, err); err != nil {
+		return err
+	}
+	if subject.Name != previousName {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE scores sc
+			SET subject = $4
+			FROM exams e
+			WHERE e.id = sc.exam_id AND e.school_id = $1 AND e.class = $2
+				AND lower(sc.subject) = lower($3)
+		`, subject.SchoolID, subject.Class, previousName, subject.Name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) SetSubjectLocked(ctx context.Context, subjectID, schoolID string, locked bool) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE subjects
+		SET locked = $3, updated_at = now()
+		WHERE id = $1 AND school_id = $2
+	`, subjectID, schoolID, locked)
+	return expectAffected(res, err)
+}
+
+// DeleteSubject removes a subject no exam declares. Locked or declared
+// subjects are kept and sql.ErrNoRows is returned.
+func (s *Store) DeleteSubject(ctx context.Context, subjectID, schoolID string) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM subjects s
+		WHERE s.id = $1 AND s.school_id = $2 AND NOT s.locked
+			AND NOT EXISTS (SELECT 1 FROM exam_subjects es WHERE es.subject_id = s.id)
+	`, subjectID, schoolID)
+	return expectAffected(res, err)
+}
+
+// ListExamSubjects returns the subjects an exam declares, in order.
+func (s *Store) ListExamSubjects(ctx context.Context, examID string) ([]models.ExamSubject, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT es.subject_id, s.name, es.max_score
+		FROM exam_subjects es
+		JOIN subjects s ON s.id = es.subject_id
+		WHERE es.exam_id = $1
+		ORDER BY es.position, lower(s.name)
+	`, examID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ExamSubject{}
+	for rows.Next() {
+		var item models.ExamSubject
+		if err := rows.Scan(&item.SubjectID, &item.Name, &item.MaxScore); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// replaceExamSubjects sets the exam's subject list. Scores already entered
+// must still fit it: their subject stays declared with the same max score.
+// An empty list removes the declaration.
+func replaceExamSubjects(ctx context.Context, tx *sql.Tx, examID string, subjects []models.ExamSubject) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM exam_subjects WHERE exam_id = $1`, examID); err != nil {
+		return err
+	}
+	if len(subjects) == 0 {
+		return nil
+	}
+	ids, maxScores := make([]string, len(subjects)), make([]float32, len(subjects))
+	for i, subject := range subjects {
+		ids[i], maxScores[i] = subject.SubjectID, subject.MaxScore
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO exam_subjects (exam_id, subject_id, max_score, position)
+		SELECT $1, v.subject_id, v.max_score, v.position
+		FROM unnest($2::uuid[], $3::float4[]) WITH ORDINALITY AS v(subject_id, max_score, position)
+	`, examID, ids, maxScores); err != nil {
+		return err
+	}
+
+	var (
+		subject    string
+		maxScore   float32
+		undeclared bool
+	)
+	err := tx.QueryRowContext(ctx, `
+		SELECT sc.subject, sc.max_score, es.exam_id IS NULL
+		FROM scores sc
+		LEFT JOIN (exam_subjects es JOIN subjects s ON s.id = es.subject_id)
+			ON es.exam_id = sc.exam_id AND lower(s.name) = lower(sc.subject)
+		WHERE sc.exam_id = $1 AND (es.exam_id IS NULL OR es.max_score <> sc.max_score)
+		LIMIT 1
+	`, examID).Scan(&subject, &maxScore, &undeclared)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if undeclared {
+		return fmt.Errorf("%w: %s has scores and must stay in the exam", ErrSubjectHasScores, subject)
+	}
+	return fmt.Errorf("%w: %s is already scored out of %g", ErrSubjectHasScores, subject, maxScore)
+}
@@ -0,0 +1,93 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isBuiltinDateFormat reports whether a built-in number format id is one of
+// the date and time formats. Ids 27-36 and 50-58 are the East Asian date
+// formats.
+func isBuiltinDateFormat(id int) bool {
+	switch {
+	case id >= 14 && id <= 22, id >= 45 && id <= 47, id >= 27 && id <= 36, id >= 50 && id <= 58:
+		return true
+	}
+	return false
+}
+
+// isDateFormat reports whether a custom format code shows a date or time:
+// once quoted text, escaped characters and bracketed colours or conditions
+// are removed, it has y, m, d, h or s in its first section. Elapsed-time
+// formats such as [h]:mm are durations and stay numbers.
+func isDateFormat(code string) bool {
+	if section, _, found := strings.Cut(code, ";"); found {
+		code = section
+	}
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		switch ch := code[i]; ch {
+		case '"':
+			end := strings.IndexByte(code[i+1:], '"')
+			if end < 0 {
+				i = len(code)
+			} else {
+				i += end + 1
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				i = len(code)
+				break
+			}
+			inner := strings.ToLower(code[i+1 : i+end])
+			if strings.Trim(inner, "hms") == "" {
+				return false
+			}
+			i += end
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "ymdhs")
+}
+
+// serialTime converts a spreadsheet date serial to a time. Serials count
+// days from 30 December 1899, which absorbs Excel's phantom 29 February
+// 1900, or from 1 January 1904 in workbooks using the 1904 date system.
+func serialTime(serial float64, date1904 bool) (time.Time, bool) {
+	if serial < 0 || serial > 2958465 { // 9999-12-31
+		return time.Time{}, false
+	}
+	base := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second), true
+}
+
+// formatTime writes a date as YYYY-MM-DD, adding the time of day when there
+// is one.
+func formatTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// formatNumber writes a number without the binary noise of its stored form,
+// so 0.30000000000000004 reads 0.3, and without exponents, so 1E-3 reads
+// 0.001. Excel itself keeps 15 significant digits.
+func formatNumber(value float64) string {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(value, 'g', 15, 64), 64)
+	if err != nil {
+		rounded = value
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
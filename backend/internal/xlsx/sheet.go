@@ -0,0 +1,136 @@
+package xlsx
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rows returns a sheet's cells as text, one slice per row starting with row
+// 1. Rows and cells the file leaves out come back empty, so indexes match
+// the row numbers and columns shown in Excel. Trailing empty cells are
+// dropped.
+func (wb *Workbook) Rows(sheet Sheet) ([][]string, error) {
+	type cell struct {
+		Ref    string    `xml:"r,attr"`
+		Type   string    `xml:"t,attr"`
+		Style  string    `xml:"s,attr"`
+		Value  *string   `xml:"v"`
+		Inline *richText `xml:"is"`
+	}
+	var doc struct {
+		Rows []struct {
+			Number int    `xml:"r,attr"`
+			Cells  []cell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	found, err := wb.decode(sheet.path, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrSheetNotFound
+	}
+
+	var rows [][]string
+	for _, row := range doc.Rows {
+		index := len(rows)
+		if row.Number > 0 {
+			index = row.Number - 1
+		}
+		for len(rows) <= index {
+			rows = append(rows, []string{})
+		}
+		var values []string
+		col := -1
+		for _, c := range row.Cells {
+			if ref, ok := columnIndex(c.Ref); ok {
+				col = ref
+			} else {
+				col++
+			}
+			value := strings.TrimSpace(wb.cellText(c.Type, c.Style, c.Value, c.Inline))
+			if value == "" {
+				continue
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = value
+		}
+		rows[index] = values
+	}
+	return rows, nil
+}
+
+func (wb *Workbook) cellText(kind, style string, value *string, inline *richText) string {
+	raw := ""
+	if value != nil {
+		raw = *value
+	}
+	switch kind {
+	case "s":
+		idx, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || idx < 0 || idx >= len(wb.shared) {
+			return raw
+		}
+		return wb.shared[idx]
+	case "inlineStr":
+		if inline != nil {
+			return inline.text()
+		}
+		return raw
+	case "str", "e":
+		// Formula text results and errors such as #N/A, as cached by the
+		// application that saved the file.
+		return raw
+	case "b":
+		if strings.TrimSpace(raw) == "1" {
+			return "TRUE"
+		}
+		if strings.TrimSpace(raw) == "0" {
+			return "FALSE"
+		}
+		return raw
+	case "d":
+		if t, err := time.Parse("2006-01-02T15:04:05", strings.TrimSuffix(raw, "Z")); err == nil {
+			return formatTime(t)
+		}
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			return formatTime(t)
+		}
+		return raw
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return raw
+	}
+	if idx, err := strconv.Atoi(style); err == nil && idx >= 0 && idx < len(wb.dateXfs) && wb.dateXfs[idx] {
+		if t, ok := serialTime(number, wb.date1904); ok {
+			if number < 1 {
+				// A time of day without a date.
+				return t.Format("15:04:05")
+			}
+			return formatTime(t)
+		}
+	}
+	return formatNumber(number)
+}
+
+// columnIndex turns the letters of a cell reference such as "AB12" into a
+// zero-based column.
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	for _, ch := range ref {
+		switch {
+		case ch >= 'A' && ch <= 'Z':
+			col = col*26 + int(ch-'A'+1)
+		case ch >= 'a' && ch <= 'z':
+			col = col*26 + int(ch-'a'+1)
+		default:
+			return col - 1, col > 0
+		}
+	}
+	return col - 1, col > 0
+}
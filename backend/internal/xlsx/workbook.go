@@ -0,0 +1,289 @@
+// Package xlsx reads the cell values of .xlsx workbooks as text, the way
+// they are shown in Excel: shared and inline strings are resolved, numbers
+// styled as dates become ISO dates, and formulas give their cached result.
+// It handles the workbooks Excel, LibreOffice and Google Sheets write,
+// including the Strict Open XML variant.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoSheets      = errors.New("xlsx: workbook has no worksheets")
+	ErrSheetNotFound = errors.New("xlsx: sheet not found")
+)
+
+// Sheet is a worksheet listed in the workbook, in tab order.
+type Sheet struct {
+	Name   string
+	Hidden bool
+	path   string
+}
+
+// Workbook is an opened .xlsx file.
+type Workbook struct {
+	Sheets []Sheet
+
+	files    map[string]*zip.File
+	shared   []string
+	dateXfs  []bool
+	date1904 bool
+}
+
+// Open reads the workbook structure, shared strings and styles. Worksheets
+// are only parsed when their rows are asked for.
+func Open(data []byte) (*Workbook, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	wb := &Workbook{files: map[string]*zip.File{}}
+	for _, f := range zr.File {
+		wb.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	workbookPath := "xl/workbook.xml"
+	if rels, err := wb.relationships("_rels/.rels", ""); err == nil {
+		for _, rel := range rels {
+			if relType(rel.Type) == "officeDocument" {
+				workbookPath = rel.target
+				break
+			}
+		}
+	}
+	if wb.files[workbookPath] == nil {
+		return nil, errors.New("xlsx: workbook part missing")
+	}
+
+	relsPath := path.Join(path.Dir(workbookPath), "_rels", path.Base(workbookPath)+".rels")
+	rels, err := wb.relationships(relsPath, path.Dir(workbookPath))
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	var sharedPath, stylesPath string
+	for _, rel := range rels {
+		targets[rel.ID] = rel.target
+		switch relType(rel.Type) {
+		case "sharedStrings":
+			sharedPath = rel.target
+		case "styles":
+			stylesPath = rel.target
+		}
+	}
+
+	if err := wb.readWorkbook(workbookPath, targets); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, ErrNoSheets
+	}
+	if wb.shared, err = wb.readSharedStrings(sharedPath); err != nil {
+		return nil, err
+	}
+	if wb.dateXfs, err = wb.readStyles(stylesPath); err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// Sheet finds a worksheet by name, ignoring case and surrounding spaces.
+func (wb *Workbook) Sheet(name string) (Sheet, error) {
+	name = strings.TrimSpace(name)
+	for _, sheet := range wb.Sheets {
+		if strings.EqualFold(strings.TrimSpace(sheet.Name), name) {
+			return sheet, nil
+		}
+	}
+	return Sheet{}, fmt.Errorf("%w: %q", ErrSheetNotFound, name)
+}
+
+// Visible returns the sheets that are not hidden, in tab order.
+func (wb *Workbook) Visible() []Sheet {
+	var sheets []Sheet
+	for _, sheet := range wb.Sheets {
+		if !sheet.Hidden {
+			sheets = append(sheets, sheet)
+		}
+	}
+	return sheets
+}
+
+type relationship struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+	Mode   string `xml:"TargetMode,attr"`
+	target string
+}
+
+// relationships reads a .rels part and resolves each target against dir.
+// A missing part has no relationships.
+func (wb *Workbook) relationships(name, dir string) ([]relationship, error) {
+	var doc struct {
+		Items []relationship `xml:"Relationship"`
+	}
+	found, err := wb.decode(name, &doc)
+	if err != nil || !found {
+		return nil, err
+	}
+	items := doc.Items[:0]
+	for _, rel := range doc.Items {
+		if strings.EqualFold(rel.Mode, "External") {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			rel.target = path.Clean(strings.TrimPrefix(rel.Target, "/"))
+		} else {
+			rel.target = path.Join(dir, rel.Target)
+		}
+		items = append(items, rel)
+	}
+	return items, nil
+}
+
+// relType returns the last segment of a relationship type, which is the
+// same in the transitional and strict namespaces.
+func relType(value string) string {
+	return value[strings.LastIndex(value, "/")+1:]
+}
+
+func (wb *Workbook) readWorkbook(name string, targets map[string]string) error {
+	var doc struct {
+		Properties struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name  string     `xml:"name,attr"`
+			State string     `xml:"state,attr"`
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if _, err := wb.decode(name, &doc); err != nil {
+		return err
+	}
+	wb.date1904 = doc.Properties.Date1904 == "1" || doc.Properties.Date1904 == "true"
+	for _, sheet := range doc.Sheets {
+		// r:id lives in the relationships namespace, whose URI differs
+		// between transitional and strict workbooks.
+		var id string
+		for _, attr := range sheet.Attrs {
+			if attr.Name.Local == "id" && attr.Name.Space != "" {
+				id = attr.Value
+			}
+		}
+		target, ok := targets[id]
+		if !ok || wb.files[target] == nil {
+			// Chart sheets and dangling entries have no worksheet part.
+			continue
+		}
+		if !strings.Contains(target, "worksheets/") {
+			continue
+		}
+		wb.Sheets = append(wb.Sheets, Sheet{
+			Name:   sheet.Name,
+			Hidden: sheet.State == "hidden" || sheet.State == "veryHidden",
+			path:   target,
+		})
+	}
+	return nil
+}
+
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// text joins a string item's runs. Phonetic runs (rPh) are left out.
+func (rt richText) text() string {
+	if len(rt.Runs) == 0 {
+		return rt.T
+	}
+	var b strings.Builder
+	b.WriteString(rt.T)
+	for _, run := range rt.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+func (wb *Workbook) readSharedStrings(name string) ([]string, error) {
+	if name == "" {
+		name = "xl/sharedStrings.xml"
+	}
+	var doc struct {
+		Items []richText `xml:"si"`
+	}
+	if _, err := wb.decode(name, &doc); err != nil {
+		return nil, err
+	}
+	out := make([]string, len(doc.Items))
+	for i, item := range doc.Items {
+		out[i] = item.text()
+	}
+	return out, nil
+}
+
+// readStyles reports, for each cell style (the s attribute of a cell),
+// whether its number format shows a date or time.
+func (wb *Workbook) readStyles(name string) ([]bool, error) {
+	if name == "" {
+		name = "xl/styles.xml"
+	}
+	var doc struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID string `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if _, err := wb.decode(name, &doc); err != nil {
+		return nil, err
+	}
+	custom := map[int]string{}
+	for _, numFmt := range doc.NumFmts {
+		custom[numFmt.ID] = numFmt.Code
+	}
+	dates := make([]bool, len(doc.CellXfs))
+	for i, xf := range doc.CellXfs {
+		id, err := strconv.Atoi(xf.NumFmtID)
+		if err != nil {
+			continue
+		}
+		if code, ok := custom[id]; ok {
+			dates[i] = isDateFormat(code)
+		} else {
+			dates[i] = isBuiltinDateFormat(id)
+		}
+	}
+	return dates, nil
+}
+
+// decode unmarshals a part, reporting false when the part does not exist.
+func (wb *Workbook) decode(name string, v any) (bool, error) {
+	f := wb.files[name]
+	if f == nil {
+		return false, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return true, fmt.Errorf("xlsx: %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return true, fmt.Errorf("xlsx: %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,207 @@
+package xlsx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The testdata workbooks mimic what each application writes:
+//
+//   - excel.xlsx: Excel, with tabs "Class 8A", "Notes" (hidden) and "Class 8B"
+//     whose workbook rels list the parts in the reverse order, built-in and
+//     custom date styles, a duration style, rich text and formula cells.
+//   - libreoffice.xlsx: LibreOffice Calc, with absolute rel targets, explicit
+//     t="n" cells, aca formulas and a veryHidden sheet.
+//   - date1904.xlsx: Excel for Mac using the 1904 date system.
+//   - inline_strings.xlsx: an export tool's inline strings, with no shared
+//     strings part and some cells without references.
+//   - chart_only.xlsx: a workbook whose only sheet is a chart sheet.
+func openTestdata(t *testing.T, name string) *Workbook {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb, err := Open(data)
+	if err != nil {
+		t.Fatalf("Open(%s): %v", name, err)
+	}
+	return wb
+}
+
+func sheetNames(sheets []Sheet) []string {
+	var names []string
+	for _, sheet := range sheets {
+		name := sheet.Name
+		if sheet.Hidden {
+			name += " (hidden)"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		file   string
+		sheets []string
+		err    error
+	}{
+		{"excel.xlsx", []string{"Class 8A", "Notes (hidden)", "Class 8B"}, nil},
+		{"libreoffice.xlsx", []string{"Scores", "Lookup (hidden)"}, nil},
+		{"date1904.xlsx", []string{"Sheet1"}, nil},
+		{"inline_strings.xlsx", []string{"Export"}, nil},
+		{"chart_only.xlsx", nil, ErrNoSheets},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			wb, err := Open(data)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Open error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sheetNames(wb.Sheets); !reflect.DeepEqual(got, tt.sheets) {
+				t.Errorf("Sheets = %q, want %q", got, tt.sheets)
+			}
+		})
+	}
+
+	t.Run("not a zip", func(t *testing.T) {
+		if _, err := Open([]byte("Roll,Name\n1,Asha\n")); err == nil {
+			t.Fatal("Open accepted a CSV file")
+		}
+	})
+}
+
+func TestSheet(t *testing.T) {
+	wb := openTestdata(t, "excel.xlsx")
+	tests := []struct {
+		name string
+		want string
+		err  error
+	}{
+		{"Class 8B", "Class 8B", nil},
+		{"class 8a", "Class 8A", nil},
+		{"  CLASS 8A ", "Class 8A", nil},
+		{"Notes", "Notes", nil},
+		{"Class 8C", "", ErrSheetNotFound},
+		{"", "", ErrSheetNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet, err := wb.Sheet(tt.name)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Sheet(%q) error = %v, want %v", tt.name, err, tt.err)
+			}
+			if sheet.Name != tt.want {
+				t.Errorf("Sheet(%q) = %q, want %q", tt.name, sheet.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisible(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"excel.xlsx", []string{"Class 8A", "Class 8B"}},
+		{"libreoffice.xlsx", []string{"Scores"}},
+		{"inline_strings.xlsx", []string{"Export"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			wb := openTestdata(t, tt.file)
+			if got := sheetNames(wb.Visible()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Visible() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRows(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		sheet string
+		want  [][]string
+	}{
+		{
+			// Shared and rich strings, number noise, formulas with cached
+			// number, text, error and boolean results, built-in (F2, F5)
+			// and custom (F3) date styles, a [h]:mm duration kept as a
+			// number (D5) and a row the file leaves out (4).
+			name: "excel", file: "excel.xlsx", sheet: "Class 8A",
+			want: [][]string{
+				{"Roll", "Student Name", "English", "Maths", "Total", "Exam Date"},
+				{"1", "Asha Kumari", "71.5", "0.3", "71.8", "2024-01-01"},
+				{"2", "Ravi Singh", "64", "#N/A", "AB", "2024-02-01"},
+				{},
+				{"3", "Meena Das", "", "1.5", "TRUE", "2024-01-01 12:00:00"},
+			},
+		},
+		{
+			name: "excel reordered part", file: "excel.xlsx", sheet: "Class 8B",
+			want: [][]string{
+				{"Class 8B roster"},
+				{"", "", "0.001"},
+			},
+		},
+		{
+			name: "excel hidden sheet", file: "excel.xlsx", sheet: "Notes",
+			want: [][]string{
+				{"Teacher notes", "Do not edit"},
+			},
+		},
+		{
+			name: "libreoffice", file: "libreoffice.xlsx", sheet: "Scores",
+			want: [][]string{
+				{"Roll", "Name", "Date", "Days"},
+				{"1", "Kiran", "2023-03-15", "1"},
+				{"2", "Sunil", "ml", "ML"},
+			},
+		},
+		{
+			name: "date1904", file: "date1904.xlsx", sheet: "Sheet1",
+			want: [][]string{
+				{"Exam date", "2024-01-01", "43830"},
+			},
+		},
+		{
+			name: "inline strings", file: "inline_strings.xlsx", sheet: "Export",
+			want: [][]string{
+				{"Roll", "Student Name", "Science"},
+				{"7", "Priya Nair", "88"},
+				{"8", "", "AB"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wb := openTestdata(t, tt.file)
+			sheet, err := wb.Sheet(tt.sheet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := wb.Rows(sheet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("Rows(%s) =\n%q\nwant\n%q", tt.sheet, rows, tt.want)
+			}
+		})
+	}
+}
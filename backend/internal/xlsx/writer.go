@@ -0,0 +1,325 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Style is the look of a written cell.
+type Style int
+
+const (
+	StyleDefault Style = iota
+	// StyleHeader is bold on a light fill with a border below.
+	StyleHeader
+	// StyleDecimal shows two decimals.
+	StyleDecimal
+	// StyleBold is for totals.
+	StyleBold
+)
+
+// Cell is a value to write: a number when Number is set, text otherwise.
+type Cell struct {
+	Text   string
+	Number *float64
+	Style  Style
+}
+
+func Text(value string) Cell {
+	return Cell{Text: value}
+}
+
+func Number(value float64) Cell {
+	return Cell{Number: &value}
+}
+
+func Decimal(value float64) Cell {
+	return Cell{Number: &value, Style: StyleDecimal}
+}
+
+func Header(value string) Cell {
+	return Cell{Text: value, Style: StyleHeader}
+}
+
+// String returns the cell as text, numbers written the way Rows reads them
+// back.
+func (c Cell) String() string {
+	if c.Number == nil {
+		return c.Text
+	}
+	if c.Style == StyleDecimal {
+		return strconv.FormatFloat(*c.Number, 'f', 2, 64)
+	}
+	return formatNumber(*c.Number)
+}
+
+// File builds a workbook to write.
+type File struct {
+	sheets []*Worksheet
+}
+
+// Worksheet is a sheet being written. Column widths follow the longest
+// value unless set.
+type Worksheet struct {
+	name       string
+	rows       [][]Cell
+	freezeRows int
+	freezeCols int
+	widths     map[int]float64
+}
+
+func NewFile() *File {
+	return &File{}
+}
+
+// AddSheet appends a sheet. The name is made valid for Excel (at most 31
+// characters, none of []:*?/\) and unique within the file.
+func (f *File) AddSheet(name string) *Worksheet {
+	sheet := &Worksheet{name: f.uniqueName(name), widths: map[int]float64{}}
+	f.sheets = append(f.sheets, sheet)
+	return sheet
+}
+
+func (ws *Worksheet) AddRow(cells ...Cell) {
+	ws.rows = append(ws.rows, cells)
+}
+
+// Freeze keeps the first rows and cols in view while scrolling.
+func (ws *Worksheet) Freeze(rows, cols int) {
+	ws.freezeRows, ws.freezeCols = rows, cols
+}
+
+// SetWidth sets a column's width in characters.
+func (ws *Worksheet) SetWidth(col int, width float64) {
+	ws.widths[col] = width
+}
+
+func (f *File) uniqueName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Sheet" + strconv.Itoa(len(f.sheets)+1)
+	}
+	base := truncate(name, 31)
+	name = base
+	for n := 2; f.hasSheet(name); n++ {
+		suffix := " (" + strconv.Itoa(n) + ")"
+		name = truncate(base, 31-len(suffix)) + suffix
+	}
+	return name
+}
+
+func (f *File) hasSheet(name string) bool {
+	for _, sheet := range f.sheets {
+		if strings.EqualFold(sheet.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(value string, limit int) string {
+	if utf8.RuneCountInString(value) <= limit {
+		return value
+	}
+	return string([]rune(value)[:limit])
+}
+
+// Write writes the workbook as an .xlsx file.
+func (f *File) Write(w io.Writer) error {
+	sheets := f.sheets
+	if len(sheets) == 0 {
+		sheets = []*Worksheet{{name: "Sheet1", widths: map[int]float64{}}}
+	}
+	zw := zip.NewWriter(w)
+	parts := []struct {
+		name string
+		body []byte
+	}{
+		{"[Content_Types].xml", contentTypes(len(sheets))},
+		{"_rels/.rels", []byte(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`)},
+		{"xl/workbook.xml", workbookXML(sheets)},
+		{"xl/_rels/workbook.xml.rels", workbookRels(len(sheets))},
+		{"xl/styles.xml", []byte(stylesXML)},
+	}
+	for i, sheet := range sheets {
+		parts = append(parts, struct {
+			name string
+			body []byte
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+	for _, part := range parts {
+		pw, err := zw.CreateHeader(&zip.FileHeader{Name: part.name, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if _, err := pw.Write(part.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func contentTypes(sheets int) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.Bytes()
+}
+
+func workbookXML(sheets []*Worksheet) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheet.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.Bytes()
+}
+
+func workbookRels(sheets int) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheets; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1)
+	b.WriteString(`</Relationships>`)
+	return b.Bytes()
+}
+
+// stylesXML holds the cell formats in Style order. Number format 2 is the
+// built-in "0.00".
+const stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="2"><border><left/><right/><top/><bottom/><diagonal/></border>` +
+	`<border><left/><right/><top/><bottom style="thin"><color auto="1"/></bottom><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="1" xfId="0" applyFont="1" applyFill="1" applyBorder="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func (ws *Worksheet) xml() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if ws.freezeRows > 0 || ws.freezeCols > 0 {
+		pane := "bottomRight"
+		switch {
+		case ws.freezeCols == 0:
+			pane = "bottomLeft"
+		case ws.freezeRows == 0:
+			pane = "topRight"
+		}
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane`)
+		if ws.freezeCols > 0 {
+			fmt.Fprintf(&b, ` xSplit="%d"`, ws.freezeCols)
+		}
+		if ws.freezeRows > 0 {
+			fmt.Fprintf(&b, ` ySplit="%d"`, ws.freezeRows)
+		}
+		fmt.Fprintf(&b, ` topLeftCell="%s%d" activePane="%s" state="frozen"/><selection pane="%s"/></sheetView></sheetViews>`,
+			columnName(ws.freezeCols), ws.freezeRows+1, pane, pane)
+	}
+
+	widths := ws.columnWidths()
+	if len(widths) > 0 {
+		b.WriteString(`<cols>`)
+		for col, width := range widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%.1f" customWidth="1"/>`, col+1, col+1, width)
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	for r, row := range ws.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			if cell.Number == nil && cell.Text == "" && cell.Style == StyleDefault {
+				continue
+			}
+			ref := columnName(c) + strconv.Itoa(r+1)
+			style := ""
+			if cell.Style != StyleDefault {
+				style = fmt.Sprintf(` s="%d"`, cell.Style)
+			}
+			if cell.Number != nil {
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(*cell.Number, 'f', -1, 64))
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(cell.Text))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.Bytes()
+}
+
+// columnWidths sizes each column to its longest value, within limits, with
+// SetWidth taking precedence.
+func (ws *Worksheet) columnWidths() []float64 {
+	var widths []float64
+	for _, row := range ws.rows {
+		for c, cell := range row {
+			for len(widths) <= c {
+				widths = append(widths, 8)
+			}
+			width := float64(utf8.RuneCountInString(cell.String())) + 2
+			if width > widths[c] {
+				widths[c] = min(width, 50)
+			}
+		}
+	}
+	for col, width := range ws.widths {
+		for len(widths) <= col {
+			widths = append(widths, 8)
+		}
+		widths[col] = width
+	}
+	return widths
+}
+
+// columnName turns a zero-based column into letters: 0 is A, 26 is AA.
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+func escape(value string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
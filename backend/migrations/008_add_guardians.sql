@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS guardians (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+  full_name text NOT NULL DEFAULT '',
+  relationship text NOT NULL,
+  phone text NOT NULL DEFAULT '',
+  email text NOT NULL DEFAULT '',
+  is_primary boolean NOT NULL DEFAULT false,
+  can_pick_up boolean NOT NULL DEFAULT false,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_guardians_student_primary
+  ON guardians (student_id)
+  WHERE is_primary;
+
+CREATE INDEX IF NOT EXISTS idx_guardians_phone_digits
+  ON guardians (right(regexp_replace(phone, '\D', '', 'g'), 10));
+
+-- Carry every existing parent_phone over as the primary guardian.
+INSERT INTO guardians (student_id, full_name, relationship, phone, is_primary, can_pick_up)
+SELECT s.id, '', 'parent', s.parent_phone, TRUE, TRUE
+FROM students s
+WHERE s.parent_phone <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM guardians g WHERE g.student_id = s.id
+  );
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS student_documents (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  school_id uuid NOT NULL REFERENCES schools(id),
+  student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+  doc_type text NOT NULL,
+  status text NOT NULL DEFAULT 'submitted',
+  storage_key text NOT NULL,
+  file_name text NOT NULL DEFAULT '',
+  content_type text NOT NULL,
+  size_bytes bigint NOT NULL,
+  sha256 text NOT NULL DEFAULT '',
+  uploaded_by uuid NOT NULL REFERENCES users(id),
+  verified_by uuid NULL REFERENCES users(id),
+  verified_at timestamptz NULL,
+  rejection_reason text NOT NULL DEFAULT '',
+  expires_on date NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_student_documents_student
+  ON student_documents (student_id, doc_type, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS document_requirements (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  school_id uuid NOT NULL REFERENCES schools(id),
+  class text NOT NULL,
+  doc_type text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (school_id, class, doc_type)
+);
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS student_health_profiles (
+  student_id uuid PRIMARY KEY REFERENCES students(id) ON DELETE CASCADE,
+  school_id uuid NOT NULL REFERENCES schools(id),
+  blood_group text NOT NULL DEFAULT '',
+  allergies text NOT NULL DEFAULT '',
+  chronic_conditions text NOT NULL DEFAULT '',
+  medications text NOT NULL DEFAULT '',
+  notes text NOT NULL DEFAULT '',
+  updated_by uuid NULL REFERENCES users(id),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS infirmary_visits (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  school_id uuid NOT NULL REFERENCES schools(id),
+  student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+  visited_at timestamptz NOT NULL DEFAULT now(),
+  complaint text NOT NULL,
+  diagnosis text NOT NULL DEFAULT '',
+  treatment text NOT NULL DEFAULT '',
+  status text NOT NULL DEFAULT 'treated',
+  discharged_at timestamptz NULL,
+  discharge_notes text NOT NULL DEFAULT '',
+  recorded_by uuid NOT NULL REFERENCES users(id),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_infirmary_visits_student
+  ON infirmary_visits (student_id, visited_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_infirmary_visits_admitted
+  ON infirmary_visits (school_id)
+  WHERE status = 'admitted';
@@ -0,0 +1,27 @@
+ALTER TABLE students
+  ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'active',
+  ADD COLUMN IF NOT EXISTS graduation_year int NULL,
+  ADD COLUMN IF NOT EXISTS archived_at timestamptz NULL;
+
+-- Roll numbers are reused by every new cohort, so only current students need
+-- to be unique per class; alumni keep their final class and roll.
+ALTER TABLE students DROP CONSTRAINT IF EXISTS students_school_id_class_label_roll_number_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_active_class_roll
+  ON students (school_id, class_label, roll_number)
+  WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_students_alumni
+  ON students (school_id, graduation_year)
+  WHERE status = 'alumni';
+
+CREATE TABLE IF NOT EXISTS alumni_contacts (
+  student_id uuid PRIMARY KEY REFERENCES students(id) ON DELETE CASCADE,
+  phone text NOT NULL DEFAULT '',
+  email text NOT NULL DEFAULT '',
+  city text NOT NULL DEFAULT '',
+  occupation text NOT NULL DEFAULT '',
+  share_contact boolean NOT NULL DEFAULT false,
+  updated_by uuid NULL REFERENCES users(id),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
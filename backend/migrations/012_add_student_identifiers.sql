@@ -0,0 +1,18 @@
+ALTER TABLE students
+  ADD COLUMN IF NOT EXISTS admission_no text NULL,
+  ADD COLUMN IF NOT EXISTS apaar_id text NULL,
+  ADD COLUMN IF NOT EXISTS pen text NULL;
+
+-- Admission numbers are issued by each school; APAAR IDs and UDISE+ PENs are
+-- national, so they are unique across schools.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_admission_no
+  ON students (school_id, admission_no)
+  WHERE admission_no IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_apaar_id
+  ON students (apaar_id)
+  WHERE apaar_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_pen
+  ON students (pen)
+  WHERE pen IS NOT NULL;
@@ -0,0 +1,24 @@
+-- Siblings at the same school are grouped into a family once an admin
+-- confirms them. A suggestion the admin rejects is kept as a 'dismissed' row
+-- for its phone so it is not offered again.
+CREATE TABLE IF NOT EXISTS families (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  school_id uuid NOT NULL REFERENCES schools(id),
+  phone_key text NOT NULL DEFAULT '',
+  status text NOT NULL DEFAULT 'confirmed',
+  created_by uuid NULL REFERENCES users(id),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_families_school_phone
+  ON families (school_id, phone_key)
+  WHERE phone_key <> '';
+
+CREATE TABLE IF NOT EXISTS family_members (
+  family_id uuid NOT NULL REFERENCES families(id) ON DELETE CASCADE,
+  student_id uuid NOT NULL UNIQUE REFERENCES students(id) ON DELETE CASCADE,
+  added_by uuid NULL REFERENCES users(id),
+  added_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (family_id, student_id)
+);
@@ -0,0 +1,6 @@
+-- How link requests are approved for a school:
+--   off           every request waits for an admin
+--   parent_phone  approve when the verified phone is students.parent_phone
+--   guardians     approve when it is parent_phone or any guardian's phone
+ALTER TABLE schools
+  ADD COLUMN IF NOT EXISTS parent_link_auto_approve text NOT NULL DEFAULT 'off';
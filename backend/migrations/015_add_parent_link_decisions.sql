@@ -0,0 +1,9 @@
+-- Parent link statuses: pending, approved, rejected, revoked. Rejections and
+-- revocations carry the admin's reason, shown to the parent.
+ALTER TABLE parent_links
+  ADD COLUMN IF NOT EXISTS reason text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS decided_by uuid NULL REFERENCES users(id),
+  ADD COLUMN IF NOT EXISTS decided_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS idx_parent_links_parent_student
+  ON parent_links (parent_id, student_id, created_at DESC);
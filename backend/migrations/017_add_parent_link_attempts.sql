@@ -0,0 +1,17 @@
+-- Every identity challenge a parent answers when requesting a link by
+-- class/roll or identifier, used to lock out guessing.
+CREATE TABLE IF NOT EXISTS parent_link_attempts (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  parent_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  student_id uuid NULL REFERENCES students(id) ON DELETE CASCADE,
+  succeeded boolean NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_parent_link_attempts_parent
+  ON parent_link_attempts (parent_id, created_at DESC)
+  WHERE NOT succeeded;
+
+CREATE INDEX IF NOT EXISTS idx_parent_link_attempts_student
+  ON parent_link_attempts (student_id, created_at DESC)
+  WHERE NOT succeeded;
@@ -0,0 +1,17 @@
+-- One-time codes that link a parent to a student without admin review. Only
+-- the SHA-256 of each code is kept; a student has at most one open code.
+CREATE TABLE IF NOT EXISTS parent_link_codes (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  school_id uuid NOT NULL REFERENCES schools(id),
+  student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+  code_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_by uuid NOT NULL REFERENCES users(id),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  redeemed_by uuid NULL REFERENCES users(id),
+  redeemed_at timestamptz NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_parent_link_codes_student
+  ON parent_link_codes (student_id)
+  WHERE redeemed_at IS NULL;
@@ -0,0 +1,9 @@
+-- Track which pending link requests have already triggered an admin reminder
+-- or a super_admin escalation, so the background job notifies only once.
+ALTER TABLE parent_links
+  ADD COLUMN IF NOT EXISTS reminded_at timestamptz NULL,
+  ADD COLUMN IF NOT EXISTS escalated_at timestamptz NULL;
+
+CREATE INDEX IF NOT EXISTS idx_parent_links_pending_created
+  ON parent_links (created_at)
+  WHERE status = 'pending';
@@ -0,0 +1,20 @@
+-- Exam lifecycle: draft -> scores_entered -> results_published -> locked.
+-- Parents only see scores of published or locked exams. Exams that already
+-- have scores were visible to parents before, so they start out published.
+ALTER TABLE exams
+  ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'draft'
+    CHECK (status IN ('draft', 'scores_entered', 'results_published', 'locked')),
+  ADD COLUMN IF NOT EXISTS published_at timestamptz NULL,
+  ADD COLUMN IF NOT EXISTS locked_at timestamptz NULL,
+  ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+
+UPDATE exams e
+SET status = 'results_published', published_at = now()
+WHERE e.status = 'draft'
+  AND EXISTS (SELECT 1 FROM scores sc WHERE sc.exam_id = e.id);
+
+CREATE INDEX IF NOT EXISTS idx_exams_school_class
+  ON exams (school_id, class, exam_date DESC);
+
+CREATE INDEX IF NOT EXISTS idx_scores_exam
+  ON scores (exam_id);
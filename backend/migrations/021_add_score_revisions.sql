@@ -0,0 +1,48 @@
+-- Scores are keyed by exam, student and subject (case-insensitively) and
+-- written with upserts.
+-- Every change is kept in score_revisions with the previous value, the actor
+-- and a reason.
+CREATE TABLE IF NOT EXISTS score_revisions (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  exam_id uuid NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+  student_id uuid NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+  subject text NOT NULL,
+  action text NOT NULL CHECK (action IN ('created', 'updated', 'deleted')),
+  previous_score numeric NULL,
+  previous_max_score numeric NULL,
+  previous_grade text NULL,
+  score numeric NULL,
+  max_score numeric NULL,
+  grade text NULL,
+  reason text NOT NULL DEFAULT '',
+  changed_by uuid NULL REFERENCES users(id),
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_score_revisions_exam_student
+  ON score_revisions (exam_id, student_id, created_at DESC);
+
+ALTER TABLE scores
+  ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS updated_by uuid NULL REFERENCES users(id);
+
+UPDATE scores SET subject = trim(subject) WHERE subject <> trim(subject);
+
+-- Re-uploads used to duplicate marks. Keep the newest row of each key and
+-- record the removed ones.
+INSERT INTO score_revisions (exam_id, student_id, subject, action, previous_score, previous_max_score, previous_grade, reason, created_at)
+SELECT a.exam_id, a.student_id, a.subject, 'deleted', a.score, a.max_score, a.grade, 'duplicate removed by migration', now()
+FROM scores a
+WHERE EXISTS (
+  SELECT 1 FROM scores b
+  WHERE b.exam_id = a.exam_id AND b.student_id = a.student_id AND lower(b.subject) = lower(a.subject)
+    AND (b.created_at, b.id) > (a.created_at, a.id)
+);
+
+DELETE FROM scores a
+USING scores b
+WHERE b.exam_id = a.exam_id AND b.student_id = a.student_id AND lower(b.subject) = lower(a.subject)
+  AND (b.created_at, b.id) > (a.created_at, a.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scores_exam_student_subject
+  ON scores (exam_id, student_id, lower(subject));
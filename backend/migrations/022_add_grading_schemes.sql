@@ -0,0 +1,14 @@
+-- Grading schemes map a percentage to a grade. A scheme with an empty class
+-- is the school default; a class scheme overrides it for that class. Bands
+-- are [{"grade": "A1", "min_percent": 91}, ...], highest first.
+CREATE TABLE IF NOT EXISTS grading_schemes (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  school_id uuid NOT NULL REFERENCES schools(id),
+  class text NOT NULL DEFAULT '',
+  name text NOT NULL,
+  bands jsonb NOT NULL,
+  updated_by uuid NULL REFERENCES users(id),
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  UNIQUE (school_id, class)
+);
@@ -0,0 +1,28 @@
+-- The subjects table becomes the per-class catalogue that score uploads are
+-- checked against. Aliases are other spellings that map to the subject, e.g.
+-- ["Maths", "Math"] for Mathematics.
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS aliases jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+
+CREATE UNIQUE INDEX IF NOT EXISTS subjects_school_class_name_lower_idx
+  ON subjects (school_id, class, lower(name));
+
+-- The subjects an exam contains, each with its own max score.
+CREATE TABLE IF NOT EXISTS exam_subjects (
+  exam_id uuid NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+  subject_id uuid NOT NULL REFERENCES subjects(id),
+  max_score numeric NOT NULL CHECK (max_score > 0),
+  position int NOT NULL DEFAULT 0,
+  PRIMARY KEY (exam_id, subject_id)
+);
+
+CREATE INDEX IF NOT EXISTS exam_subjects_subject_idx ON exam_subjects (subject_id);
+
+-- Seed each class's catalogue with the subjects its scores already use, so
+-- existing uploads keep working once the catalogue is enforced.
+INSERT INTO subjects (school_id, class, name)
+SELECT DISTINCT ON (e.school_id, e.class, lower(sc.subject)) e.school_id, e.class, sc.subject
+FROM scores sc
+JOIN exams e ON e.id = sc.exam_id
+ORDER BY e.school_id, e.class, lower(sc.subject), sc.created_at
+ON CONFLICT DO NOTHING;
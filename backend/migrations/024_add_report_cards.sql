@@ -0,0 +1,21 @@
+-- Per-school report card layout; settings hold models.ReportCardTemplate.
+CREATE TABLE IF NOT EXISTS report_card_templates (
+  school_id uuid PRIMARY KEY REFERENCES schools(id),
+  settings jsonb NOT NULL,
+  updated_by uuid NULL REFERENCES users(id),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Teacher remarks and attendance printed on a student's report card for a
+-- term. Attendance is optional and left off the card when not entered.
+CREATE TABLE IF NOT EXISTS report_card_remarks (
+  student_id uuid NOT NULL REFERENCES students(id),
+  term text NOT NULL,
+  remarks text NOT NULL DEFAULT '',
+  days_present int NULL CHECK (days_present >= 0),
+  working_days int NULL CHECK (working_days > 0),
+  updated_by uuid NULL REFERENCES users(id),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (student_id, term),
+  CHECK (days_present IS NULL OR working_days IS NULL OR days_present <= working_days)
+);
@@ -0,0 +1,16 @@
+-- Bind the seeded "gpa" and "rank" widgets to computed metrics so parents
+-- see their own child's values instead of the demo strings.
+UPDATE app_configs c
+SET dashboard_widgets = (
+  SELECT jsonb_agg(
+    CASE
+      WHEN w ? 'metric' THEN w
+      WHEN w->>'key' = 'gpa' THEN w || '{"metric": "percentage", "label": "Score", "value": "", "hint": ""}'::jsonb
+      WHEN w->>'key' = 'rank' THEN w || '{"metric": "rank", "value": "", "hint": ""}'::jsonb
+      ELSE w
+    END ORDER BY t.ord)
+  FROM jsonb_array_elements(c.dashboard_widgets) WITH ORDINALITY AS t(w, ord)
+),
+updated_at = now()
+WHERE jsonb_typeof(c.dashboard_widgets) = 'array'
+  AND jsonb_array_length(c.dashboard_widgets) > 0;
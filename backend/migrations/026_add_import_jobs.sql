@@ -0,0 +1,33 @@
+-- Uploaded sheets are imported in the background. A job row is the queue
+-- entry: workers claim queued jobs with FOR UPDATE SKIP LOCKED and hold a
+-- lease while they run, so a job whose worker died is picked up again once
+-- its lease runs out.
+CREATE TABLE IF NOT EXISTS import_jobs (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  school_id uuid NOT NULL REFERENCES schools(id),
+  kind text NOT NULL,
+  status text NOT NULL DEFAULT 'queued'
+    CHECK (status IN ('queued', 'running', 'succeeded', 'rejected', 'failed', 'cancelled')),
+  params jsonb NOT NULL DEFAULT '{}',
+  file_name text NOT NULL DEFAULT '',
+  upload_key text NOT NULL,
+  created_by uuid NOT NULL REFERENCES users(id),
+  progress_done int NOT NULL DEFAULT 0,
+  progress_total int NOT NULL DEFAULT 0,
+  result jsonb NULL,
+  error text NOT NULL DEFAULT '',
+  attempts int NOT NULL DEFAULT 0,
+  cancel_requested boolean NOT NULL DEFAULT false,
+  lease_until timestamptz NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  started_at timestamptz NULL,
+  finished_at timestamptz NULL,
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_pending
+  ON import_jobs (created_at)
+  WHERE status IN ('queued', 'running');
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_school
+  ON import_jobs (school_id, created_at DESC);
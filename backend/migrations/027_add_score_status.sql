@@ -0,0 +1,10 @@
+-- A score can record that the student did not sit the paper instead of a
+-- mark. Such rows keep score 0 and an empty grade; absent papers count as
+-- zero in totals, medical leave and exemptions are left out of them.
+ALTER TABLE scores
+  ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'present'
+    CHECK (status IN ('present', 'absent', 'medical_leave', 'exempt'));
+
+ALTER TABLE score_revisions
+  ADD COLUMN IF NOT EXISTS previous_status text NULL,
+  ADD COLUMN IF NOT EXISTS status text NULL;
@@ -0,0 +1,9 @@
+-- The infirmary summary goes to nurses and admins once a day. A row is the
+-- claim on a school's summary for a day, so API instances running the job
+-- side by side send it once.
+CREATE TABLE IF NOT EXISTS infirmary_summaries (
+  school_id uuid NOT NULL REFERENCES schools(id) ON DELETE CASCADE,
+  day date NOT NULL,
+  sent_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (school_id, day)
+);
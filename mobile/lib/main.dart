@@ -475,6 +475,7 @@ class ParentScore {
   final double score;
   final double maxScore;
   final String grade;
+  final String status;
   final DateTime? createdAt;
 
   const ParentScore({
@@ -482,15 +483,32 @@ class ParentScore {
     required this.score,
     required this.maxScore,
     required this.grade,
+    required this.status,
     required this.createdAt,
   });
 
+  bool get hasMarks => status.isEmpty || status == 'present';
+
+  // Code shown instead of marks for a paper the student did not sit.
+  String get statusCode {
+    switch (status) {
+      case 'absent':
+        return 'AB';
+      case 'medical_leave':
+        return 'ML';
+      case 'exempt':
+        return 'EX';
+    }
+    return '';
+  }
+
   factory ParentScore.fromJson(Map<String, dynamic> json) {
     return ParentScore(
       subject: (json['subject'] ?? '').toString(),
       score: double.tryParse((json['score'] ?? '').toString()) ?? 0,
       maxScore: double.tryParse((json['max_score'] ?? '').toString()) ?? 100,
       grade: (json['grade'] ?? '').toString(),
+      status: (json['status'] ?? '').toString(),
       createdAt: DateTime.tryParse((json['created_at'] ?? '').toString()),
     );
   }
@@ -671,6 +689,7 @@ class BackendClient {
     String district,
     String classLabel,
     int rollNumber,
+    String dateOfBirth,
   ) async {
     final response = await http.post(
       Uri.parse('$baseUrl/api/v1/parent-links/request'),
@@ -682,6 +701,7 @@ class BackendClient {
         'district': district,
         'class_label': classLabel,
         'roll_number': rollNumber,
+        'date_of_birth': dateOfBirth,
       }),
     );
 
@@ -1163,6 +1183,7 @@ class LinkChildScreen extends StatefulWidget {
 class _LinkChildScreenState extends State<LinkChildScreen> {
   final TextEditingController _classController = TextEditingController();
   final TextEditingController _rollController = TextEditingController();
+  final TextEditingController _dobController = TextEditingController();
   String _status = '';
   bool _loading = false;
   bool _loadingDistricts = false;
@@ -1204,6 +1225,7 @@ class _LinkChildScreenState extends State<LinkChildScreen> {
   void dispose() {
     _classController.dispose();
     _rollController.dispose();
+    _dobController.dispose();
     super.dispose();
   }
 
@@ -1211,10 +1233,15 @@ class _LinkChildScreenState extends State<LinkChildScreen> {
     final district = _selectedDistrict ?? '';
     final classLabel = _classController.text.trim();
     final roll = int.tryParse(_rollController.text.trim());
+    final dob = _dobController.text.trim();
     if (district.isEmpty || classLabel.isEmpty || roll == null || roll <= 0) {
       setState(() => _status = 'Enter valid district, class and roll number.');
       return;
     }
+    if (!RegExp(r'^\d{4}-\d{2}-\d{2}$').hasMatch(dob)) {
+      setState(() => _status = "Enter your child's date of birth as YYYY-MM-DD.");
+      return;
+    }
 
     setState(() {
       _loading = true;
@@ -1223,7 +1250,7 @@ class _LinkChildScreenState extends State<LinkChildScreen> {
     try {
       final client = BackendClient(widget.apiBase);
       await client.requestParentLinkByClassRoll(
-          widget.authToken, district, classLabel, roll);
+          widget.authToken, district, classLabel, roll, dob);
       widget.onRequested();
     } catch (err) {
       setState(() {
@@ -1263,7 +1290,7 @@ class _LinkChildScreenState extends State<LinkChildScreen> {
                             fontSize: 24, fontWeight: FontWeight.w800)),
                     const SizedBox(height: 6),
                     const Text(
-                      'Enter district, class and roll number to request student access.',
+                      "Enter district, class, roll number and your child's date of birth to request student access.",
                       style: TextStyle(color: Color(0xFF64748B)),
                     ),
                     const SizedBox(height: 16),
@@ -1296,6 +1323,13 @@ class _LinkChildScreenState extends State<LinkChildScreen> {
                       decoration:
                           const InputDecoration(labelText: 'Roll Number'),
                     ),
+                    const SizedBox(height: 10),
+                    TextField(
+                      controller: _dobController,
+                      keyboardType: TextInputType.datetime,
+                      decoration: const InputDecoration(
+                          labelText: 'Date of Birth (YYYY-MM-DD)'),
+                    ),
                     const SizedBox(height: 16),
                     SizedBox(
                       width: double.infinity,
@@ -2068,7 +2102,9 @@ class RecentScoresCard extends StatelessWidget {
                           ),
                           const SizedBox(width: 12),
                           Text(
-                              '${score.toStringAsFixed(0)}/${maxScore.toStringAsFixed(0)}',
+                              item.hasMarks
+                                  ? '${score.toStringAsFixed(0)}/${maxScore.toStringAsFixed(0)}'
+                                  : item.statusCode,
                               style:
                                   const TextStyle(fontWeight: FontWeight.w700)),
                         ],
@@ -3194,7 +3230,7 @@ class ProfileScreen extends StatelessWidget {
   Widget build(BuildContext context) {
     final subjectMap = <String, List<double>>{};
     for (final score in scores) {
-      if (score.subject.isEmpty) continue;
+      if (score.subject.isEmpty || !score.hasMarks) continue;
       subjectMap.putIfAbsent(score.subject, () => []);
       subjectMap[score.subject]!
           .add(score.maxScore > 0 ? (score.score / score.maxScore) * 100 : 0);
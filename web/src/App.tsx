@@ -20,6 +20,8 @@ type ParentLink = {
   class_label: string;
   roll_number: number;
   status: string;
+  age_days?: number;
+  escalated_at?: string;
   created_at: string;
 };
 
@@ -74,8 +76,11 @@ type DashboardWidgetRecord = {
   value: string;
   hint: string;
   icon: string;
+  metric?: string;
 };
 
+const widgetMetrics = ['', 'percentage', 'rank', 'percentile', 'grade', 'trend'];
+
 type AppConfigRecord = {
   feature_flags: Record<string, boolean>;
   dashboard_widgets: DashboardWidgetRecord[];
@@ -164,9 +169,9 @@ export default function App() {
     show_academic_tab: true,
   });
   const [dashboardWidgets, setDashboardWidgets] = useState<DashboardWidgetRecord[]>([
-    { key: 'gpa', label: 'GPA', value: '9.2', hint: 'This term', icon: 'school' },
+    { key: 'gpa', label: 'Score', value: '', hint: '', icon: 'school', metric: 'percentage' },
     { key: 'attendance', label: 'Attend', value: '94.5%', hint: 'Monthly avg', icon: 'check_circle' },
-    { key: 'rank', label: 'Rank', value: '#3', hint: 'Class standing', icon: 'emoji_events' },
+    { key: 'rank', label: 'Rank', value: '', hint: '', icon: 'emoji_events', metric: 'rank' },
   ]);
   const [minSupportedVersion, setMinSupportedVersion] = useState('');
   const [forceUpdateMessage, setForceUpdateMessage] = useState('');
@@ -346,6 +351,34 @@ export default function App() {
     return res.text();
   };
 
+  // Sheet uploads are queued as import jobs (202 with the job). Follow the
+  // job until it finishes and return its result, which has the same shape as
+  // an inline upload response.
+  const uploadResult = async (res: Response, label: string) => {
+    const body = await res.json();
+    if (res.status !== 202) {
+      if (!res.ok) {
+        throw new Error(Array.isArray(body.errors) ? body.errors.join(', ') : body.error || res.statusText);
+      }
+      return body;
+    }
+    let job = body;
+    while (job.status === 'queued' || job.status === 'running') {
+      const rows = job.progress_total ? ` (${job.progress_done}/${job.progress_total} rows)` : '';
+      setStatus(`${label}: ${job.status}${rows}...`);
+      await new Promise((resolve) => setTimeout(resolve, 1000));
+      job = await getJSON(`/api/v1/jobs/${job.id}`);
+    }
+    const result = job.result ?? {};
+    if (job.status === 'rejected') {
+      throw new Error(Array.isArray(result.errors) ? result.errors.join(', ') : 'upload rejected');
+    }
+    if (job.status !== 'succeeded') {
+      throw new Error(job.error || `import ${job.status}`);
+    }
+    return result;
+  };
+
   const handlePublishAnnouncement = async () => {
     try {
       setStatus('Publishing announcement...');
@@ -411,6 +444,21 @@ export default function App() {
     }
   };
 
+  const approveAllParentLinks = async () => {
+    try {
+      setStatus('Approving parent links...');
+      const resp = (await postJSON('/api/v1/parent-links/bulk', {
+        action: 'approve',
+        ids: pendingLinks.map((item) => item.id),
+      })) as { succeeded: number; failed: number; results: { id: string; status: string }[] };
+      const approved = new Set(resp.results.filter((item) => item.status === 'approved').map((item) => item.id));
+      setPendingLinks((prev) => prev.filter((item) => !approved.has(item.id)));
+      setStatus(`Approved ${resp.succeeded} parent links, ${resp.failed} failed.`);
+    } catch (err) {
+      setStatus(`Error: ${(err as Error).message}`);
+    }
+  };
+
   const publishAnnouncement = async (id: string) => {
     try {
       setStatus('Publishing announcement...');
@@ -641,11 +689,7 @@ export default function App() {
         headers: authOnlyHeader,
         body: formData,
       });
-      const body = await res.json();
-      if (!res.ok) {
-        const errors = Array.isArray(body.errors) ? body.errors.join(', ') : res.statusText;
-        throw new Error(errors);
-      }
+      const body = await uploadResult(res, 'Importing students');
       const inserted = Number(body.inserted ?? 0);
       const failed = Number(body.failed ?? 0);
       const errors = Array.isArray(body.errors) ? body.errors.slice(0, 5).join(' | ') : '';
@@ -688,12 +732,16 @@ export default function App() {
       for (const row of manualRows) {
         if (!row.roll || !row.score) continue;
         const student = await lookupStudent(manualClass, row.roll);
+        // AB, ML and EX record a paper the student did not sit.
+        const code = row.score.trim().toUpperCase();
+        const noMarks = code === 'AB' || code === 'ML' || code === 'EX';
         scores.push({
           student_id: student.id,
           subject: manualSubject,
-          score: Number(row.score),
+          score: noMarks ? 0 : Number(row.score),
           max_score: Number(manualMaxMarks),
-          grade: row.grade || '',
+          grade: noMarks ? '' : row.grade || '',
+          status: noMarks ? code : '',
         });
       }
       await submitScores(examId, scores);
@@ -722,12 +770,8 @@ export default function App() {
         headers: authOnlyHeader,
         body: formData,
       });
-      const body = await res.json();
-      if (!res.ok) {
-        const errors = Array.isArray(body.errors) ? body.errors.join(', ') : res.statusText;
-        throw new Error(errors);
-      }
-      setStatus(`Uploaded ${body.inserted} scores for approval.`);
+      const body = await uploadResult(res, 'Importing scores');
+      setStatus(`Uploaded ${body.inserted} new and ${body.updated ?? 0} updated scores for approval.`);
     } catch (err) {
       setStatus(`Error: ${(err as Error).message}`);
     }
@@ -1062,7 +1106,9 @@ export default function App() {
                   </td>
                   <td>
                     <input
-                      type="number"
+                      type="text"
+                      inputMode="decimal"
+                      placeholder="marks or AB/ML/EX"
                       value={row.score}
                       onChange={(event) => updateManualRow(index, 'score', event.target.value)}
                     />
@@ -1318,6 +1364,7 @@ export default function App() {
                 <th>Value</th>
                 <th>Hint</th>
                 <th>Icon</th>
+                <th>Metric</th>
               </tr>
             </thead>
             <tbody>
@@ -1328,6 +1375,13 @@ export default function App() {
                   <td><input value={widget.value} onChange={(event) => updateWidget(index, 'value', event.target.value)} /></td>
                   <td><input value={widget.hint} onChange={(event) => updateWidget(index, 'hint', event.target.value)} /></td>
                   <td><input value={widget.icon} onChange={(event) => updateWidget(index, 'icon', event.target.value)} /></td>
+                  <td>
+                    <select value={widget.metric ?? ''} onChange={(event) => updateWidget(index, 'metric', event.target.value)}>
+                      {widgetMetrics.map((metric) => (
+                        <option key={metric} value={metric}>{metric || 'static value'}</option>
+                      ))}
+                    </select>
+                  </td>
                 </tr>
               ))}
             </tbody>
@@ -1406,6 +1460,9 @@ export default function App() {
           <p>Approve parent-link requests directly from UI.</p>
           <div className="section-actions">
             <button className="app__button" onClick={loadPendingLinks}>Load requests</button>
+            {pendingLinks.length > 0 ? (
+              <button className="app__button" onClick={approveAllParentLinks}>Approve all</button>
+            ) : null}
           </div>
           {pendingLinks.length === 0 ? (
             <div className="empty-state">No pending parent-link requests.</div>
@@ -1420,7 +1477,11 @@ export default function App() {
                   <p>
                     Student: {link.student_name || link.student_id} • {link.class_label} • Roll {link.roll_number}
                   </p>
-                  <p>Requested: {formatDate(link.created_at)}</p>
+                  <p>
+                    Requested: {formatDate(link.created_at)}
+                    {link.age_days ? ` • waiting ${link.age_days} day${link.age_days === 1 ? '' : 's'}` : ''}
+                    {link.escalated_at ? ' • escalated' : ''}
+                  </p>
                 </div>
                 <button
                   className="app__button app__button--primary"